@@ -0,0 +1,79 @@
+package clock
+
+import "time"
+
+// Clock 是一个接口，它抽象了 EventEmitter 和内置 Pipeline 使用的时间来源，便于在测试和模拟中替换真实时间。
+// Clock is an interface that abstracts the time source used by the EventEmitter and the built-in pipelines, so that real time can be substituted in tests and simulations.
+type Clock = interface {
+	// Now 返回当前时间。
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc 在等待 d 之后调用 f，并返回一个可以停止或重置的 Timer。
+	// AfterFunc calls f after waiting for d, and returns a Timer that can be stopped or reset.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTimer 创建一个在等待 d 之后向 C() 发送当前时间的 Timer。
+	// NewTimer creates a Timer that sends the current time on C() after waiting for d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 是一个接口，它表示 Clock 创建的单次定时器。
+// Timer is an interface that represents a one-shot timer created by a Clock.
+type Timer = interface {
+	// C 返回定时器到期时接收时间的通道，由 AfterFunc 创建的定时器返回 nil。
+	// C returns the channel receiving the time when the timer expires, timers created by AfterFunc return nil.
+	C() <-chan time.Time
+
+	// Stop 停止定时器，如果定时器在到期之前被停止则返回 true。
+	// Stop stops the timer, and returns true if the timer was stopped before it expired.
+	Stop() bool
+
+	// Reset 将定时器改为在等待 d 之后到期，如果定时器在重置之前仍然有效则返回 true。
+	// Reset changes the timer to expire after waiting for d, and returns true if the timer had been active before the reset.
+	Reset(d time.Duration) bool
+}
+
+// realClock 是一个结构体，它使用 time 包实现 Clock。
+// realClock is a structure that implements Clock with the time package.
+type realClock struct{}
+
+// NewRealClock 是一个函数，它返回一个使用真实时间的 Clock。
+// NewRealClock is a function that returns a Clock using real time.
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+// Now 是 realClock 的一个方法，它返回 time.Now()。
+// Now is a method of realClock that returns time.Now().
+func (realClock) Now() time.Time { return time.Now() }
+
+// AfterFunc 是 realClock 的一个方法，它使用 time.AfterFunc 在单独的协程中调用 f。
+// AfterFunc is a method of realClock that calls f in its own goroutine with time.AfterFunc.
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{timer: time.AfterFunc(d, f)}
+}
+
+// NewTimer 是 realClock 的一个方法，它使用 time.NewTimer 创建定时器。
+// NewTimer is a method of realClock that creates the timer with time.NewTimer.
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+// realTimer 是一个结构体，它使用 *time.Timer 实现 Timer。
+// realTimer is a structure that implements Timer with *time.Timer.
+type realTimer struct {
+	timer *time.Timer
+}
+
+// C 是 realTimer 的一个方法，它返回 *time.Timer 的通道。
+// C is a method of realTimer that returns the channel of *time.Timer.
+func (t *realTimer) C() <-chan time.Time { return t.timer.C }
+
+// Stop 是 realTimer 的一个方法，它停止 *time.Timer。
+// Stop is a method of realTimer that stops *time.Timer.
+func (t *realTimer) Stop() bool { return t.timer.Stop() }
+
+// Reset 是 realTimer 的一个方法，它重置 *time.Timer。
+// Reset is a method of realTimer that resets *time.Timer.
+func (t *realTimer) Reset(d time.Duration) bool { return t.timer.Reset(d) }
@@ -0,0 +1,239 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// FakeClock 是一个结构体，它实现了一个只有在调用 Advance 或 Set 时才会前进的虚拟时钟。
+// 与 time.AfterFunc 不同，到期的 AfterFunc 回调在调用 Advance 的协程上按到期顺序同步执行，因此 Advance 返回时它们已经执行完毕。
+// FakeClock is a structure that implements a virtual clock which only moves forward when Advance or Set is called.
+// Unlike time.AfterFunc, due AfterFunc callbacks are executed synchronously in due order on the goroutine calling Advance, so they have completed when Advance returns.
+type FakeClock struct {
+	// lock 是 sync.Mutex 类型，用于保护 now、timers 和 seq 的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to now, timers, and seq.
+	lock sync.Mutex
+
+	// now 是虚拟时钟的当前时间。
+	// now is the current time of the virtual clock.
+	now time.Time
+
+	// timers 是尚未到期的定时器。
+	// timers is the timers that are not yet due.
+	timers fakeTimerHeap
+
+	// seq 是定时器的设置序号，到期时间相同的定时器按设置顺序触发。
+	// seq is the setting sequence number of timers, timers with the same due time fire in setting order.
+	seq uint64
+}
+
+// NewFakeClock 是一个函数，它返回一个从 start 开始的 FakeClock。
+// NewFakeClock is a function that returns a FakeClock starting at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now 是 FakeClock 的一个方法，它返回虚拟时钟的当前时间。
+// Now is a method of FakeClock that returns the current time of the virtual clock.
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// AfterFunc 是 FakeClock 的一个方法，它在虚拟时钟走过 d 之后调用 f。d 不大于 0 时，f 在下一次调用 Advance 时执行。
+// AfterFunc is a method of FakeClock that calls f after the virtual clock has moved past d. When d is not greater than 0, f is executed on the next call to Advance.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{clock: c, fn: f, index: -1}
+	t.Reset(d)
+	return t
+}
+
+// NewTimer 是 FakeClock 的一个方法，它创建一个在虚拟时钟走过 d 之后向 C() 发送虚拟时间的 Timer。
+// NewTimer is a method of FakeClock that creates a Timer sending the virtual time on C() after the virtual clock has moved past d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), index: -1}
+	t.Reset(d)
+	return t
+}
+
+// Advance 是 FakeClock 的一个方法，它将虚拟时钟向前推进 d，并按到期顺序触发期间到期的所有定时器。
+// 触发定时器时虚拟时钟位于定时器的到期时间，回调中设置的、在推进范围内到期的定时器也会被触发。
+// Advance is a method of FakeClock that moves the virtual clock forward by d and fires all timers due in the meantime in due order.
+// While a timer fires, the virtual clock is at the due time of the timer, and timers set by callbacks that are due within the range also fire.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	target := c.now.Add(d)
+	c.lock.Unlock()
+	c.Set(target)
+}
+
+// Set 是 FakeClock 的一个方法，它将虚拟时钟移动到 t，并按到期顺序触发在 t 之前到期的所有定时器。虚拟时钟不会后退。
+// Set is a method of FakeClock that moves the virtual clock to t and fires all timers due before t in due order. The virtual clock never moves backward.
+func (c *FakeClock) Set(t time.Time) {
+	c.lock.Lock()
+	for {
+		// 取出下一个在目标时间之前到期的定时器，没有则结束。
+		// Take the next timer due before the target time, and stop if there is none.
+		next := c.timers.peek()
+		if next == nil || next.due.After(t) {
+			break
+		}
+		heap.Pop(&c.timers)
+
+		// 将虚拟时钟移动到定时器的到期时间，并在不持有锁的情况下触发定时器。
+		// Move the virtual clock to the due time of the timer and fire the timer without holding the lock.
+		if next.due.After(c.now) {
+			c.now = next.due
+		}
+		now := c.now
+		c.lock.Unlock()
+		next.fire(now)
+		c.lock.Lock()
+	}
+
+	// 将虚拟时钟移动到目标时间。
+	// Move the virtual clock to the target time.
+	if t.After(c.now) {
+		c.now = t
+	}
+	c.lock.Unlock()
+}
+
+// Len 是 FakeClock 的一个方法，它返回尚未到期的定时器的数量。
+// Len is a method of FakeClock that returns the number of timers that are not yet due.
+func (c *FakeClock) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.timers.Len()
+}
+
+// fakeTimer 是一个结构体，它表示 FakeClock 创建的定时器。
+// fakeTimer is a structure that represents a timer created by FakeClock.
+type fakeTimer struct {
+	// clock 是创建定时器的 FakeClock。
+	// clock is the FakeClock that created the timer.
+	clock *FakeClock
+
+	// fn 是 AfterFunc 的回调，由 NewTimer 创建的定时器为 nil。
+	// fn is the callback of AfterFunc, nil for timers created by NewTimer.
+	fn func()
+
+	// c 是 NewTimer 的通道，由 AfterFunc 创建的定时器为 nil。
+	// c is the channel of NewTimer, nil for timers created by AfterFunc.
+	c chan time.Time
+
+	// due 是定时器的到期时间。
+	// due is the due time of the timer.
+	due time.Time
+
+	// seq 是定时器的设置序号。
+	// seq is the setting sequence number of the timer.
+	seq uint64
+
+	// index 是定时器在堆中的位置，不在堆中时为 -1。
+	// index is the position of the timer in the heap, -1 when it is not in the heap.
+	index int
+}
+
+// C 是 fakeTimer 的一个方法，它返回定时器的通道。
+// C is a method of fakeTimer that returns the channel of the timer.
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+// Stop 是 fakeTimer 的一个方法，它将定时器从 FakeClock 中移除。
+// Stop is a method of fakeTimer that removes the timer from the FakeClock.
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	return t.stop()
+}
+
+// Reset 是 fakeTimer 的一个方法，它将定时器改为在虚拟时钟走过 d 之后到期。
+// Reset is a method of fakeTimer that changes the timer to expire after the virtual clock has moved past d.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	active := t.stop()
+	c.seq++
+	t.due, t.seq = c.now.Add(d), c.seq
+	heap.Push(&c.timers, t)
+	return active
+}
+
+// stop 是 fakeTimer 的一个方法，它在持有锁的情况下将定时器从堆中移除，并返回定时器之前是否有效。
+// stop is a method of fakeTimer that removes the timer from the heap while holding the lock, and returns whether the timer had been active.
+func (t *fakeTimer) stop() bool {
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&t.clock.timers, t.index)
+	return true
+}
+
+// fire 是 fakeTimer 的一个方法，它调用回调或者向通道发送虚拟时间，通道已满时丢弃。
+// fire is a method of fakeTimer that calls the callback or sends the virtual time on the channel, dropped when the channel is full.
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// fakeTimerHeap 是一个按到期时间排序的定时器最小堆，实现了 container/heap 的 Interface。
+// fakeTimerHeap is a min-heap of timers sorted by due time, implementing the Interface of container/heap.
+type fakeTimerHeap []*fakeTimer
+
+// Len 是 fakeTimerHeap 的一个方法，它返回堆中定时器的数量。
+// Len is a method of fakeTimerHeap that returns the number of timers in the heap.
+func (h fakeTimerHeap) Len() int { return len(h) }
+
+// Less 是 fakeTimerHeap 的一个方法，它比较两个定时器的到期时间，到期时间相同时比较设置序号。
+// Less is a method of fakeTimerHeap that compares the due times of two timers, and the setting sequence numbers when the due times are equal.
+func (h fakeTimerHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+
+// Swap 是 fakeTimerHeap 的一个方法，它交换两个定时器的位置。
+// Swap is a method of fakeTimerHeap that swaps the positions of two timers.
+func (h fakeTimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+// Push 是 fakeTimerHeap 的一个方法，它将一个定时器加入堆的末尾。
+// Push is a method of fakeTimerHeap that appends a timer to the end of the heap.
+func (h *fakeTimerHeap) Push(x any) {
+	t := x.(*fakeTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+// Pop 是 fakeTimerHeap 的一个方法，它移除并返回堆的最后一个定时器。
+// Pop is a method of fakeTimerHeap that removes and returns the last timer of the heap.
+func (h *fakeTimerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
+
+// peek 是 fakeTimerHeap 的一个方法，它返回最早到期的定时器，堆为空时返回 nil。
+// peek is a method of fakeTimerHeap that returns the earliest due timer, or nil when the heap is empty.
+func (h fakeTimerHeap) peek() *fakeTimer {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
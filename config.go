@@ -0,0 +1,218 @@
+package events
+
+import (
+	"time"
+
+	"github.com/shengyanli1982/events/clock"
+	"github.com/shengyanli1982/events/wal"
+)
+
+// Config 是一个结构体，用于配置 EventEmitter 的参数。
+// Config is a structure used to configure the parameters of EventEmitter.
+type Config struct {
+	// separator 是主题级别之间的分隔符。
+	// separator is the separator between topic levels.
+	separator string
+
+	// bubbling 表示事件是否会冒泡到父级主题。
+	// bubbling indicates whether events bubble up to parent topics.
+	bubbling bool
+
+	// shutdownPolicy 决定 Shutdown 如何处理尚未到期的延迟事件。
+	// shutdownPolicy decides how Shutdown handles the delayed events that are not yet due.
+	shutdownPolicy ShutdownPolicy
+
+	// deadLetterTopic 表示处理失败的事件是否在 DeadLetterTopic 上重新发出。
+	// deadLetterTopic indicates whether failed events are re-emitted on DeadLetterTopic.
+	deadLetterTopic bool
+
+	// deadLetterSink 是接收处理失败的事件的 DeadLetterSink。
+	// deadLetterSink is the DeadLetterSink receiving failed events.
+	deadLetterSink DeadLetterSink
+
+	// clock 是为事件生成时间戳和计划时间的时钟。
+	// clock is the clock generating the timestamps and scheduled times of events.
+	clock clock.Clock
+
+	// metrics 是记录发出和处理指标的 MetricsRecorder。
+	// metrics is the MetricsRecorder recording emission and handling metrics.
+	metrics MetricsRecorder
+
+	// headerInjector 是在事件发出时写入头部的 HeaderInjector。
+	// headerInjector is the HeaderInjector writing headers when an event is emitted.
+	headerInjector HeaderInjector
+
+	// wal 是在提交之前写入事件的预写日志。
+	// wal is the write-ahead log events are written into before they are submitted.
+	wal *wal.Log
+
+	// replay 是一个映射，键是主题，值是这个主题上保留的历史事件的限制。
+	// replay is a map with topics as keys and the limits of the history events kept on the topic as values.
+	replay map[string]replayLimit
+}
+
+// NewConfig 是一个函数，用于创建并返回一个新的 Config 结构体的指针。
+// NewConfig is a function that creates and returns a pointer to a new Config structure.
+func NewConfig() *Config {
+	return &Config{
+		// separator 是主题级别之间的分隔符，默认为 DefaultTopicSeparator。
+		// separator is the separator between topic levels, default is DefaultTopicSeparator.
+		separator: DefaultTopicSeparator,
+
+		// clock 默认为真实时间的时钟。
+		// clock defaults to a clock using real time.
+		clock: clock.NewRealClock(),
+
+		// metrics 默认为一个什么也不做的 MetricsRecorder。
+		// metrics defaults to a MetricsRecorder that does nothing.
+		metrics: NewEmptyMetricsRecorder(),
+	}
+}
+
+// DefaultConfig 是一个函数，用于创建一个默认的配置。
+// DefaultConfig is a function that creates a default configuration.
+func DefaultConfig() *Config {
+	return NewConfig()
+}
+
+// WithTopicSeparator 是一个方法，用于设置 Config 结构体中的 separator 变量。
+// WithTopicSeparator is a method used to set the separator variable in the Config structure.
+func (c *Config) WithTopicSeparator(separator string) *Config {
+	c.separator = separator
+	return c
+}
+
+// WithBubbling 是一个方法，用于开启事件冒泡：在子主题上发出的事件处理完成后，会依次发送给父级主题上注册的处理函数，
+// 直到某个处理函数返回 ErrorStopPropagation。
+// WithBubbling is a method used to enable event bubbling: after an event emitted on a child topic has been handled, it is delivered to the handling functions registered on the parent topics in turn,
+// until a handling function returns ErrorStopPropagation.
+func (c *Config) WithBubbling() *Config {
+	c.bubbling = true
+	return c
+}
+
+// WithShutdownPolicy 是一个方法，用于设置 Shutdown 处理尚未到期的延迟事件的策略，默认为 ShutdownRunPending。
+// WithShutdownPolicy is a method used to set the policy by which Shutdown handles the delayed events that are not yet due, default is ShutdownRunPending.
+func (c *Config) WithShutdownPolicy(policy ShutdownPolicy) *Config {
+	c.shutdownPolicy = policy
+	return c
+}
+
+// WithDeadLetterTopic 是一个方法，用于开启死信主题：处理函数返回错误时，事件会以 *DeadLetter 的形式在 DeadLetterTopic 上重新发出。
+// WithDeadLetterTopic is a method used to enable the dead-letter topic: when a handling function returns an error, the event is re-emitted on DeadLetterTopic as *DeadLetter.
+func (c *Config) WithDeadLetterTopic() *Config {
+	c.deadLetterTopic = true
+	return c
+}
+
+// WithDeadLetterSink 是一个方法，用于设置接收处理失败的事件的 DeadLetterSink。
+// WithDeadLetterSink is a method used to set the DeadLetterSink receiving failed events.
+func (c *Config) WithDeadLetterSink(sink DeadLetterSink) *Config {
+	c.deadLetterSink = sink
+	return c
+}
+
+// WithClock 是一个方法，用于设置为事件生成时间戳和计划时间的时钟。延迟事件和重试的等待由 Pipeline 完成，
+// 测试时应将同一个 clock.FakeClock 同时传给 EventEmitter 和 Pipeline。
+// WithClock is a method used to set the clock generating the timestamps and scheduled times of events. Delayed events and retries are waited for by the Pipeline,
+// so in tests the same clock.FakeClock should be passed to both the EventEmitter and the Pipeline.
+func (c *Config) WithClock(clock clock.Clock) *Config {
+	c.clock = clock
+	return c
+}
+
+// WithMetricsRecorder 是一个方法，用于设置记录发出和处理指标的 MetricsRecorder，例如 NewMemoryMetrics 返回的内存实现。
+// WithMetricsRecorder is a method used to set the MetricsRecorder recording emission and handling metrics, for example the in-memory implementation returned by NewMemoryMetrics.
+func (c *Config) WithMetricsRecorder(recorder MetricsRecorder) *Config {
+	c.metrics = recorder
+	return c
+}
+
+// WithHeaderInjector 是一个方法，用于设置在事件发出时根据发出方的上下文写入头部的 HeaderInjector。
+// WithHeaderInjector is a method used to set the HeaderInjector writing headers from the emitter side's context when an event is emitted.
+func (c *Config) WithHeaderInjector(injector HeaderInjector) *Config {
+	c.headerInjector = injector
+	return c
+}
+
+// WithWAL 是一个方法，用于设置在提交之前写入事件的预写日志。处理完成的事件会在日志中标记为完成，
+// 进程重启后，EventEmitter 在创建时取出日志中尚未完成的事件，并在它们的主题上注册了处理函数之后自动重新发出。
+// WithWAL is a method used to set the write-ahead log events are written into before they are submitted. Handled events are marked as completed in the log,
+// and after the process restarts, the EventEmitter takes the events not yet completed in the log when it is created, and emits them again automatically once handling functions are registered for their topics.
+func (c *Config) WithWAL(log *wal.Log) *Config {
+	c.wal = log
+	return c
+}
+
+// WithReplayBuffer 是一个方法，用于在主题上保留最近发出的事件，使用 WithReplay 选项注册的处理函数会先收到这些事件。
+// size 是保留的事件数量上限，maxAge 是事件保留的最长时间，小于等于 0 时表示不限制，两者都不限制时不保留事件。每个主题需要单独设置。
+// WithReplayBuffer is a method used to keep the recently emitted events on a topic, handling functions registered with the WithReplay option receive these events first.
+// size is the maximum number of events kept, maxAge is the maximum time an event is kept, a value less than or equal to 0 means no limit, and no events are kept when neither is limited. Each topic needs to be set separately.
+func (c *Config) WithReplayBuffer(topic string, size int, maxAge time.Duration) *Config {
+	limit := replayLimit{size: size, maxAge: maxAge, sticky: c.replay[topic].sticky}
+	return c.withReplayLimit(topic, limit)
+}
+
+// WithStickyTopic 是一个方法，用于把主题设置为粘性主题：主题保留最新发出的事件，新注册的处理函数会立即收到它，EventEmitter 的 Latest 方法会返回它。
+// 如果主题还设置了 WithReplayBuffer，保留的事件数量由 WithReplayBuffer 决定，但新注册的处理函数只会自动收到最新的事件。
+// WithStickyTopic is a method used to make the topics sticky: the topic keeps the latest emitted event, newly registered handling functions receive it immediately, and the Latest method of EventEmitter returns it.
+// If WithReplayBuffer is also set for the topic, the number of events kept is decided by WithReplayBuffer, but newly registered handling functions only receive the latest event automatically.
+func (c *Config) WithStickyTopic(topics ...string) *Config {
+	for _, topic := range topics {
+		limit := c.replay[topic]
+		limit.sticky = true
+		c.withReplayLimit(topic, limit)
+	}
+	return c
+}
+
+// withReplayLimit 是一个方法，用于设置主题保留历史事件的限制。粘性主题至少保留最新的一个事件，其它没有限制的主题不保留事件。
+// withReplayLimit is a method used to set the limits of keeping history events on a topic. Sticky topics keep at least the latest event, other topics without limits keep no events.
+func (c *Config) withReplayLimit(topic string, limit replayLimit) *Config {
+	if limit.size <= 0 && limit.maxAge <= 0 {
+		if !limit.sticky {
+			delete(c.replay, topic)
+			return c
+		}
+		limit.size = 1
+	}
+	if c.replay == nil {
+		c.replay = make(map[string]replayLimit)
+	}
+	c.replay[topic] = limit
+	return c
+}
+
+// isConfigValid 是一个函数，用于检查配置是否有效，如果无效则返回一个默认的配置。
+// isConfigValid is a function that checks whether the configuration is valid, and returns a default configuration if it is not.
+func isConfigValid(conf *Config) *Config {
+	// 如果配置不为 nil
+	// If the configuration is not nil
+	if conf != nil {
+		// 如果分隔符为空，设置为默认的分隔符。
+		// If the separator is empty, set it to the default separator.
+		if conf.separator == "" {
+			conf.separator = DefaultTopicSeparator
+		}
+
+		// 如果时钟为 nil，设置为真实时间的时钟。
+		// If the clock is nil, set it to a clock using real time.
+		if conf.clock == nil {
+			conf.clock = clock.NewRealClock()
+		}
+
+		// 如果 MetricsRecorder 为 nil，设置为一个什么也不做的 MetricsRecorder。
+		// If the MetricsRecorder is nil, set it to a MetricsRecorder that does nothing.
+		if conf.metrics == nil {
+			conf.metrics = NewEmptyMetricsRecorder()
+		}
+	} else {
+		// 如果配置为 nil，创建一个默认的配置。
+		// If the configuration is nil, create a default configuration.
+		conf = DefaultConfig()
+	}
+
+	// 返回配置。
+	// Return the configuration.
+	return conf
+}
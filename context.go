@@ -0,0 +1,108 @@
+package events
+
+import "context"
+
+// withContext 是一个函数，它将 ContextHandleFunc 适配为 EnvelopeHandleFunc。
+// withContext is a function that adapts a ContextHandleFunc to an EnvelopeHandleFunc.
+func withContext(fn ContextHandleFunc) EnvelopeHandleFunc {
+	return func(env *Envelope) (any, error) {
+		return fn(env.Context(), env.Payload)
+	}
+}
+
+// RegisterContextWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个带上下文的消息处理函数，将这个函数注册到指定的主题上。
+// 处理函数收到的上下文携带发出方上下文中的值，并在发出方的上下文结束或者 EventEmitter 停止时被取消。
+// RegisterContextWithTopic is a method of EventEmitter that takes a topic and a context-aware message handling function and registers this function to the specified topic.
+// The context received by the handling function carries the values of the emitter side's context, and is cancelled when the emitter side's context is done or the EventEmitter is stopped.
+func (ee *EventEmitter) RegisterContextWithTopic(topic string, fn ContextHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 注册带上下文的消息处理函数，原始处理函数使用 context.Background() 调用它。
+	// Register the context-aware message handling function, the original handling function calls it with context.Background().
+	return ee.registerHandleFunc(topic, func(msg any) (any, error) { return fn(context.Background(), msg) }, withContext(fn), false, opts)
+}
+
+// RegisterContext 是 EventEmitter 的一个方法，它接受一个带上下文的消息处理函数，将这个函数注册到默认的主题上。
+// RegisterContext is a method of EventEmitter that takes a context-aware message handling function and registers this function to the default topic.
+func (ee *EventEmitter) RegisterContext(fn ContextHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	return ee.RegisterContextWithTopic(DefaultTopicName, fn, opts...)
+}
+
+// EmitWithContext 是 EventEmitter 的一个方法，它接受一个上下文、一个主题和一个消息，然后立即在指定的主题上发出这个消息。
+// ctx 的值会传递给处理函数，ctx 结束后尚未开始的处理函数会被跳过，正在执行的处理函数可以通过上下文观察到取消。
+// EmitWithContext is a method of EventEmitter that takes a context, a topic, and a message, and then immediately emits this message on the specified topic.
+// The values of ctx are passed to the handling functions. After ctx is done, handling functions that have not started are skipped, and running handling functions can observe the cancellation through their context.
+func (ee *EventEmitter) EmitWithContext(ctx context.Context, topic string, msg any) error {
+	return ee.emit(ctx, topic, msg, executeImmediately, nil)
+}
+
+// trackContext 是 EventEmitter 的一个方法，它记录派生自调用方上下文的事件上下文，使 Stop 可以取消它。
+// 如果 EventEmitter 已经停止，上下文会被立即取消。
+// trackContext is a method of EventEmitter that records an event context derived from the caller's context, so that Stop can cancel it.
+// If the EventEmitter has already been stopped, the context is cancelled immediately.
+func (ee *EventEmitter) trackContext(e *emission, cancel context.CancelFunc) {
+	ee.ctxLock.Lock()
+	defer ee.ctxLock.Unlock()
+
+	// 如果 EventEmitter 已经停止，立即取消上下文。
+	// If the EventEmitter has already been stopped, cancel the context immediately.
+	if ee.contexts == nil {
+		cancel()
+		return
+	}
+
+	// 记录上下文的取消函数。
+	// Record the cancel function of the context.
+	ee.contexts[e] = cancel
+}
+
+// untrackContext 是 EventEmitter 的一个方法，它在事件分发结束后移除事件上下文的记录。
+// untrackContext is a method of EventEmitter that removes the record of an event context after the dispatch of the event ends.
+func (ee *EventEmitter) untrackContext(e *emission) {
+	ee.ctxLock.Lock()
+	defer ee.ctxLock.Unlock()
+
+	// 从 contexts 中移除记录，对 nil 映射执行 delete 是安全的。
+	// Remove the record from contexts, it is safe to delete from a nil map.
+	delete(ee.contexts, e)
+}
+
+// cancelContexts 是 EventEmitter 的一个方法，它取消所有记录的事件上下文，之后记录的上下文会被立即取消。
+// cancelContexts is a method of EventEmitter that cancels all recorded event contexts, contexts recorded afterwards are cancelled immediately.
+func (ee *EventEmitter) cancelContexts() {
+	ee.ctxLock.Lock()
+	defer ee.ctxLock.Unlock()
+
+	// 取消所有记录的上下文。
+	// Cancel all recorded contexts.
+	for _, cancel := range ee.contexts {
+		cancel()
+	}
+
+	// 将 contexts 置为 nil，标记 EventEmitter 已经停止。
+	// Set contexts to nil to mark that the EventEmitter has been stopped.
+	ee.contexts = nil
+}
+
+// valueContext 是一个结构体，它的取消信号来自内嵌的上下文，值优先从 values 中查找。
+// valueContext is a structure whose cancellation signal comes from the embedded context, and whose values are looked up in values first.
+type valueContext struct {
+	context.Context
+
+	// values 是提供值的上下文。
+	// values is the context that provides values.
+	values context.Context
+}
+
+// Value 是 valueContext 的一个方法，它先在 values 中查找键对应的值，找不到时再查找内嵌的上下文。
+// Value is a method of valueContext that looks up the value of the key in values first, and then in the embedded context if it is not found.
+func (c valueContext) Value(key any) any {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
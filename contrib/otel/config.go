@@ -0,0 +1,90 @@
+package otel
+
+import (
+	gotel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config 是一个结构体，用于配置链路追踪的参数。
+// Config is a structure used to configure the parameters of tracing.
+type Config struct {
+	// provider 是创建 Tracer 的 TracerProvider。
+	// provider is the TracerProvider creating the Tracer.
+	provider trace.TracerProvider
+
+	// propagator 是在事件头部中注入和提取链路上下文的 TextMapPropagator。
+	// propagator is the TextMapPropagator injecting and extracting the trace context into and from the event headers.
+	propagator propagation.TextMapPropagator
+
+	// link 表示处理函数的 span 是否作为新的根 span，并链接到发出方的 span，而不是作为它的子 span。
+	// link indicates whether the span of the handling function is a new root span linked to the span of the emitter side, instead of its child span.
+	link bool
+}
+
+// NewConfig 是一个函数，用于创建并返回一个新的 Config 结构体的指针。
+// NewConfig is a function that creates and returns a pointer to a new Config structure.
+func NewConfig() *Config {
+	return &Config{
+		// provider 默认为全局的 TracerProvider。
+		// provider defaults to the global TracerProvider.
+		provider: gotel.GetTracerProvider(),
+
+		// propagator 默认为 W3C Trace Context。
+		// propagator defaults to W3C Trace Context.
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// DefaultConfig 是一个函数，用于创建一个默认的配置。
+// DefaultConfig is a function that creates a default configuration.
+func DefaultConfig() *Config {
+	return NewConfig()
+}
+
+// WithTracerProvider 是一个方法，用于设置创建 Tracer 的 TracerProvider。
+// WithTracerProvider is a method used to set the TracerProvider creating the Tracer.
+func (c *Config) WithTracerProvider(provider trace.TracerProvider) *Config {
+	c.provider = provider
+	return c
+}
+
+// WithPropagator 是一个方法，用于设置在事件头部中注入和提取链路上下文的 TextMapPropagator。
+// WithPropagator is a method used to set the TextMapPropagator injecting and extracting the trace context into and from the event headers.
+func (c *Config) WithPropagator(propagator propagation.TextMapPropagator) *Config {
+	c.propagator = propagator
+	return c
+}
+
+// WithLinks 是一个方法，用于让处理函数的 span 作为新的根 span 并链接到发出方的 span，适合发出方不等待处理结果的场景。
+// WithLinks is a method used to make the span of the handling function a new root span linked to the span of the emitter side, suitable when the emitter side does not wait for the result.
+func (c *Config) WithLinks() *Config {
+	c.link = true
+	return c
+}
+
+// isConfigValid 是一个函数，用于检查配置是否有效，如果无效则返回一个默认的配置。
+// isConfigValid is a function that checks whether the configuration is valid, and returns a default configuration if it is not.
+func isConfigValid(conf *Config) *Config {
+	// 如果配置为 nil，创建一个默认的配置。
+	// If the configuration is nil, create a default configuration.
+	if conf == nil {
+		return DefaultConfig()
+	}
+
+	// 如果 TracerProvider 为 nil，设置为全局的 TracerProvider。
+	// If the TracerProvider is nil, set it to the global TracerProvider.
+	if conf.provider == nil {
+		conf.provider = gotel.GetTracerProvider()
+	}
+
+	// 如果 TextMapPropagator 为 nil，设置为 W3C Trace Context。
+	// If the TextMapPropagator is nil, set it to W3C Trace Context.
+	if conf.propagator == nil {
+		conf.propagator = propagation.TraceContext{}
+	}
+
+	// 返回配置。
+	// Return the configuration.
+	return conf
+}
@@ -0,0 +1,17 @@
+module github.com/shengyanli1982/events/contrib/otel
+
+go 1.19
+
+replace github.com/shengyanli1982/events => ../../
+
+require (
+	github.com/shengyanli1982/events v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.16.0
+	go.opentelemetry.io/otel/trace v1.16.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.16.0 // indirect
+)
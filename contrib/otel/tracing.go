@@ -0,0 +1,127 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	ev "github.com/shengyanli1982/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName 是创建 Tracer 时使用的插桩范围名称。
+// ScopeName is the instrumentation scope name used when creating the Tracer.
+const ScopeName = "github.com/shengyanli1982/events/contrib/otel"
+
+// span 的属性键。
+// The attribute keys of spans.
+const (
+	// AttributeSystem 是消息系统的属性键，值为 "events"。
+	// AttributeSystem is the attribute key of the messaging system, its value is "events".
+	AttributeSystem = attribute.Key("messaging.system")
+
+	// AttributeTopic 是事件主题的属性键。
+	// AttributeTopic is the attribute key of the event topic.
+	AttributeTopic = attribute.Key("messaging.destination.name")
+
+	// AttributeOperation 是消息操作的属性键，值为 "process"。
+	// AttributeOperation is the attribute key of the messaging operation, its value is "process".
+	AttributeOperation = attribute.Key("messaging.operation")
+
+	// AttributeEventID 是事件 ID 的属性键。
+	// AttributeEventID is the attribute key of the event ID.
+	AttributeEventID = attribute.Key("messaging.message.id")
+
+	// AttributeAttempt 是处理函数第几次执行的属性键。
+	// AttributeAttempt is the attribute key of the number of the execution of the handling function.
+	AttributeAttempt = attribute.Key("events.attempt")
+)
+
+// NewHeaderInjector 是一个函数，它返回一个 events.HeaderInjector，在事件发出时将发出方上下文中的 span 上下文注入到事件的头部中。
+// 通过 events.Config 的 WithHeaderInjector 设置它，使事件在 pipeline 的工作协程中执行时仍然能找回发出方的链路。
+// NewHeaderInjector is a function that returns an events.HeaderInjector injecting the span context in the emitter side's context into the event headers when an event is emitted.
+// Set it through WithHeaderInjector of events.Config, so that the trace of the emitter side can be recovered when the event is executed on a worker of the pipeline.
+func NewHeaderInjector(conf *Config) ev.HeaderInjector {
+	conf = isConfigValid(conf)
+	return func(ctx context.Context, headers map[string]string) {
+		conf.propagator.Inject(ctx, propagation.MapCarrier(headers))
+	}
+}
+
+// NewMiddleware 是一个函数，它返回一个 events.Middleware，从事件的头部中提取 span 上下文，并在处理函数外层创建一个 span。
+// span 默认是发出方 span 的子 span，使用 WithLinks 时是链接到它的新根 span；处理函数可以从 Envelope.Context() 或者带上下文的处理函数的上下文中获取这个 span。
+// NewMiddleware is a function that returns an events.Middleware extracting the span context from the event headers and creating a span around the handling function.
+// The span is a child of the emitter side's span by default, or a new root span linked to it with WithLinks; handling functions can get this span from Envelope.Context() or from the context of context-aware handling functions.
+func NewMiddleware(conf *Config) ev.Middleware {
+	conf = isConfigValid(conf)
+	tracer := conf.provider.Tracer(ScopeName)
+
+	return func(next ev.MessageHandleFunc) ev.MessageHandleFunc {
+		return func(msg any) (result any, err error) {
+			// 如果消息不是 Envelope，无法获取元数据，直接调用下一个处理函数。
+			// If the message is not an Envelope, the metadata is not available, call the next handling function directly.
+			env, ok := msg.(*ev.Envelope)
+			if !ok {
+				return next(msg)
+			}
+
+			// 从事件的头部中提取发出方的 span 上下文。
+			// Extract the span context of the emitter side from the event headers.
+			ctx := env.Context()
+			parent := conf.propagator.Extract(ctx, propagation.MapCarrier(env.Headers))
+
+			// 设置 span 的类型和属性。
+			// Set the kind and attributes of the span.
+			opts := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					AttributeSystem.String("events"),
+					AttributeTopic.String(env.Topic),
+					AttributeOperation.String("process"),
+					AttributeEventID.String(env.ID),
+					AttributeAttempt.Int(env.Attempt),
+				),
+			}
+
+			// 使用链接时，span 作为新的根 span，并链接到发出方的 span。
+			// When using links, the span is a new root span linked to the span of the emitter side.
+			if conf.link {
+				if sc := trace.SpanContextFromContext(parent); sc.IsValid() {
+					opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+				}
+				opts = append(opts, trace.WithNewRoot())
+			}
+
+			// 创建 span，并在处理函数结束时结束它。
+			// Create the span, and end it when the handling function finishes.
+			spanCtx, span := tracer.Start(parent, env.Topic+" process", opts...)
+			defer func() {
+				// 如果处理函数发生 panic，记录后继续传播。
+				// If the handling function panics, record it and keep propagating.
+				if r := recover(); r != nil {
+					span.SetStatus(codes.Error, fmt.Sprint(r))
+					span.End()
+					panic(r)
+				}
+				span.End()
+			}()
+
+			// 使用带有 span 的上下文调用下一个处理函数。
+			// Call the next handling function with the context carrying the span.
+			result, err = next(env.WithContext(spanCtx))
+
+			// 如果处理失败，在 span 上记录错误。使用与指标相同的分类，停止传播等不属于失败。
+			// If the handling fails, record the error on the span. The same classification as the metrics is used, stopping propagation and the like are not failures.
+			if ev.OutcomeOf(err) != ev.OutcomeSucceeded {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			// 返回结果和错误。
+			// Return the result and error.
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package prometheus
+
+import prom "github.com/prometheus/client_golang/prometheus"
+
+// DefaultNamespace 是指标名称默认使用的命名空间。
+// DefaultNamespace is the namespace used by metric names by default.
+const DefaultNamespace = "events"
+
+// Config 是一个结构体，用于配置 Recorder 的参数。
+// Config is a structure used to configure the parameters of Recorder.
+type Config struct {
+	// namespace 是指标名称的命名空间。
+	// namespace is the namespace of metric names.
+	namespace string
+
+	// subsystem 是指标名称的子系统。
+	// subsystem is the subsystem of metric names.
+	subsystem string
+
+	// buckets 是延迟直方图的桶上界，单位为秒。
+	// buckets is the upper bounds of the buckets of the latency histograms, in seconds.
+	buckets []float64
+
+	// constLabels 是所有指标都带有的固定标签。
+	// constLabels is the constant labels carried by all metrics.
+	constLabels prom.Labels
+}
+
+// NewConfig 是一个函数，用于创建并返回一个新的 Config 结构体的指针。
+// NewConfig is a function that creates and returns a pointer to a new Config structure.
+func NewConfig() *Config {
+	return &Config{
+		// namespace 默认为 DefaultNamespace。
+		// namespace defaults to DefaultNamespace.
+		namespace: DefaultNamespace,
+
+		// buckets 默认为 prometheus 的默认桶。
+		// buckets defaults to the default buckets of prometheus.
+		buckets: prom.DefBuckets,
+	}
+}
+
+// DefaultConfig 是一个函数，用于创建一个默认的配置。
+// DefaultConfig is a function that creates a default configuration.
+func DefaultConfig() *Config {
+	return NewConfig()
+}
+
+// WithNamespace 是一个方法，用于设置指标名称的命名空间。
+// WithNamespace is a method used to set the namespace of metric names.
+func (c *Config) WithNamespace(namespace string) *Config {
+	c.namespace = namespace
+	return c
+}
+
+// WithSubsystem 是一个方法，用于设置指标名称的子系统。
+// WithSubsystem is a method used to set the subsystem of metric names.
+func (c *Config) WithSubsystem(subsystem string) *Config {
+	c.subsystem = subsystem
+	return c
+}
+
+// WithBuckets 是一个方法，用于设置延迟直方图的桶上界，单位为秒。
+// WithBuckets is a method used to set the upper bounds of the buckets of the latency histograms, in seconds.
+func (c *Config) WithBuckets(buckets []float64) *Config {
+	c.buckets = buckets
+	return c
+}
+
+// WithConstLabels 是一个方法，用于设置所有指标都带有的固定标签，例如服务名称。
+// WithConstLabels is a method used to set the constant labels carried by all metrics, for example the service name.
+func (c *Config) WithConstLabels(labels prom.Labels) *Config {
+	c.constLabels = labels
+	return c
+}
+
+// isConfigValid 是一个函数，用于检查配置是否有效，如果无效则返回一个默认的配置。
+// isConfigValid is a function that checks whether the configuration is valid, and returns a default configuration if it is not.
+func isConfigValid(conf *Config) *Config {
+	// 如果配置为 nil，创建一个默认的配置。
+	// If the configuration is nil, create a default configuration.
+	if conf == nil {
+		return DefaultConfig()
+	}
+
+	// 如果桶为空，设置为 prometheus 的默认桶。
+	// If the buckets are empty, set them to the default buckets of prometheus.
+	if len(conf.buckets) == 0 {
+		conf.buckets = prom.DefBuckets
+	}
+
+	// 返回配置。
+	// Return the configuration.
+	return conf
+}
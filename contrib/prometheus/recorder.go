@@ -0,0 +1,158 @@
+package prometheus
+
+import (
+	"net/http"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ev "github.com/shengyanli1982/events"
+)
+
+// 指标的标签名称。
+// The label names of the metrics.
+const (
+	// LabelTopic 是事件主题的标签名称。
+	// LabelTopic is the label name of the event topic.
+	LabelTopic = "topic"
+
+	// LabelOutcome 是处理函数执行结果的标签名称，取值为 events.Outcome 的 String()。
+	// LabelOutcome is the label name of the result of a handling function, its values are the String() of events.Outcome.
+	LabelOutcome = "outcome"
+)
+
+// Recorder 是一个结构体，它实现了 events.MetricsRecorder，将 EventEmitter 的指标记录为 prometheus 的计数器和直方图。
+// 它同时实现了 prometheus.Collector，可以注册到任意的 prometheus.Registerer 上；Handler 返回只包含这些指标的抓取接口。
+// 指标按主题打标签，主题的数量应该是有限的。
+// Recorder is a structure that implements events.MetricsRecorder, recording the metrics of the EventEmitter as prometheus counters and histograms.
+// It also implements prometheus.Collector and can be registered to any prometheus.Registerer; Handler returns a scrape endpoint containing only these metrics.
+// The metrics are labelled by topic, so the number of topics should be bounded.
+type Recorder struct {
+	// emitted 是被接受的发出次数。
+	// emitted is the number of accepted emissions.
+	emitted *prom.CounterVec
+
+	// rejected 是被拒绝的发出次数。
+	// rejected is the number of rejected emissions.
+	rejected *prom.CounterVec
+
+	// submitted 是提交给 pipeline 的处理函数任务数量。
+	// submitted is the number of handling function jobs submitted to the pipeline.
+	submitted *prom.CounterVec
+
+	// handled 是按结果区分的处理函数执行次数。
+	// handled is the number of handling function executions by result.
+	handled *prom.CounterVec
+
+	// queueWait 是任务排队等待时间的直方图。
+	// queueWait is the histogram of the queue wait times of jobs.
+	queueWait *prom.HistogramVec
+
+	// handlerDuration 是按结果区分的处理函数执行时间的直方图。
+	// handlerDuration is the histogram of the execution times of handling functions by result.
+	handlerDuration *prom.HistogramVec
+
+	// registry 是 Handler 使用的只包含 Recorder 的注册表。
+	// registry is the registry containing only the Recorder, used by Handler.
+	registry *prom.Registry
+}
+
+// NewRecorder 是一个函数，它使用配置创建一个新的 Recorder。
+// NewRecorder is a function that creates a new Recorder with the configuration.
+func NewRecorder(conf *Config) *Recorder {
+	// 检查配置是否有效。
+	// Check whether the configuration is valid.
+	conf = isConfigValid(conf)
+
+	// counter 和 histogram 使用配置中的命名空间、子系统和固定标签创建指标。
+	// counter and histogram create the metrics with the namespace, subsystem and constant labels of the configuration.
+	counter := func(name, help string, labels ...string) *prom.CounterVec {
+		return prom.NewCounterVec(prom.CounterOpts{
+			Namespace: conf.namespace, Subsystem: conf.subsystem, Name: name, Help: help, ConstLabels: conf.constLabels,
+		}, labels)
+	}
+	histogram := func(name, help string, labels ...string) *prom.HistogramVec {
+		return prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: conf.namespace, Subsystem: conf.subsystem, Name: name, Help: help, ConstLabels: conf.constLabels, Buckets: conf.buckets,
+		}, labels)
+	}
+
+	// 创建 Recorder 的所有指标。
+	// Create all metrics of the Recorder.
+	r := &Recorder{
+		emitted:         counter("emitted_total", "Number of accepted emissions.", LabelTopic),
+		rejected:        counter("rejected_total", "Number of emissions that returned an error.", LabelTopic),
+		submitted:       counter("submitted_total", "Number of handler jobs submitted to the pipeline.", LabelTopic),
+		handled:         counter("handled_total", "Number of handler executions by outcome.", LabelTopic, LabelOutcome),
+		queueWait:       histogram("queue_wait_seconds", "Time handler jobs waited in the pipeline queue.", LabelTopic),
+		handlerDuration: histogram("handler_duration_seconds", "Time handlers took by outcome.", LabelTopic, LabelOutcome),
+		registry:        prom.NewRegistry(),
+	}
+
+	// 将 Recorder 注册到它自己的注册表上。
+	// Register the Recorder to its own registry.
+	r.registry.MustRegister(r)
+
+	// 返回 Recorder。
+	// Return the Recorder.
+	return r
+}
+
+// OnEmitted 是 Recorder 的一个方法，它增加主题被接受的发出次数。
+// OnEmitted is a method of Recorder that increases the number of accepted emissions of the topic.
+func (r *Recorder) OnEmitted(topic string) {
+	r.emitted.WithLabelValues(topic).Inc()
+}
+
+// OnRejected 是 Recorder 的一个方法，它增加主题被拒绝的发出次数。
+// OnRejected is a method of Recorder that increases the number of rejected emissions of the topic.
+func (r *Recorder) OnRejected(topic string, _ error) {
+	r.rejected.WithLabelValues(topic).Inc()
+}
+
+// OnSubmitted 是 Recorder 的一个方法，它增加主题提交的任务数量。
+// OnSubmitted is a method of Recorder that increases the number of jobs submitted for the topic.
+func (r *Recorder) OnSubmitted(topic string) {
+	r.submitted.WithLabelValues(topic).Inc()
+}
+
+// OnStarted 是 Recorder 的一个方法，它记录任务排队等待的时间。
+// OnStarted is a method of Recorder that records the queue wait time of a job.
+func (r *Recorder) OnStarted(topic string, wait time.Duration) {
+	r.queueWait.WithLabelValues(topic).Observe(wait.Seconds())
+}
+
+// OnHandled 是 Recorder 的一个方法，它记录处理函数的执行结果和执行时间。
+// OnHandled is a method of Recorder that records the result and execution time of a handling function.
+func (r *Recorder) OnHandled(topic string, outcome ev.Outcome, duration time.Duration) {
+	r.handled.WithLabelValues(topic, outcome.String()).Inc()
+	r.handlerDuration.WithLabelValues(topic, outcome.String()).Observe(duration.Seconds())
+}
+
+// Describe 是 Recorder 的一个方法，它实现了 prometheus.Collector，发送所有指标的描述。
+// Describe is a method of Recorder that implements prometheus.Collector, sending the descriptions of all metrics.
+func (r *Recorder) Describe(ch chan<- *prom.Desc) {
+	for _, c := range r.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect 是 Recorder 的一个方法，它实现了 prometheus.Collector，发送所有指标的当前值。
+// Collect is a method of Recorder that implements prometheus.Collector, sending the current values of all metrics.
+func (r *Recorder) Collect(ch chan<- prom.Metric) {
+	for _, c := range r.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// Handler 是 Recorder 的一个方法，它返回一个以 prometheus 文本格式输出 Recorder 指标的 http.Handler。
+// Handler is a method of Recorder that returns an http.Handler exposing the metrics of the Recorder in the prometheus text format.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+// collectors 是 Recorder 的一个方法，它返回 Recorder 的所有指标。
+// collectors is a method of Recorder that returns all metrics of the Recorder.
+func (r *Recorder) collectors() []prom.Collector {
+	return []prom.Collector{r.emitted, r.rejected, r.submitted, r.handled, r.queueWait, r.handlerDuration}
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// DeadLetterTopic 是一个常量，它的值为 "$dead-letter"，开启死信主题后，处理失败的事件会以 *DeadLetter 的形式在这个主题上重新发出。
+// DeadLetterTopic is a constant, its value is "$dead-letter". When the dead-letter topic is enabled, failed events are re-emitted on this topic as *DeadLetter.
+const DeadLetterTopic = "$dead-letter"
+
+// DeadLetter 是一个结构体，它描述一次处理失败的事件。
+// DeadLetter is a structure that describes an event whose handling failed.
+type DeadLetter struct {
+	// Envelope 是处理失败的事件的 Envelope。
+	// Envelope is the Envelope of the event whose handling failed.
+	Envelope *Envelope
+
+	// Err 是处理函数返回的错误。
+	// Err is the error returned by the handling function.
+	Err error
+
+	// Attempts 是处理函数对这个事件的执行次数。
+	// Attempts is the number of executions of the handling function for this event.
+	Attempts int
+}
+
+// DeadLetterSink 是一个接口，它接收处理失败的事件，例如用于记录或者持久化，之后可以通过 Requeue 重新发出。
+// DeadLetterSink is an interface that receives events whose handling failed, for example for logging or persistence, and they can be re-emitted later through Requeue.
+type DeadLetterSink = interface {
+	// HandleDeadLetter 方法接收一个处理失败的事件，它在执行处理函数的工作协程中被调用，不应长时间阻塞。
+	// The HandleDeadLetter method receives an event whose handling failed. It is called on the worker executing the handling function and should not block for a long time.
+	HandleDeadLetter(dl *DeadLetter)
+}
+
+// isHandlingFailure 是一个函数，它判断处理函数返回的错误是否表示处理失败。
+// 停止传播、只执行一次的处理函数已经执行过，以及执行被中断，都不属于处理失败。
+// isHandlingFailure is a function that determines whether the error returned by the handling function indicates a handling failure.
+// Stopping propagation, a run-once handling function that has already been executed, and an interrupted execution are not handling failures.
+func isHandlingFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrorStopPropagation) && !errors.Is(err, ErrorTopicExecutedOnce) && !isInterrupted(err)
+}
+
+// isInterrupted 是一个函数，它判断处理函数返回的错误是否表示执行因为上下文结束或者 EventEmitter 关闭而被中断。
+// isInterrupted is a function that determines whether the error returned by the handling function indicates that the execution was interrupted because the context is done or the EventEmitter is closed.
+func isInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrEmitterClosed)
+}
+
+// deadLetter 是 EventEmitter 的一个方法，它将处理失败的事件交给配置的死信接收者，并在死信主题上重新发出。
+// 死信主题上的处理函数失败时不会再次发出到死信主题，以免形成循环。
+// deadLetter is a method of EventEmitter that hands the failed event to the configured dead-letter sink and re-emits it on the dead-letter topic.
+// Failures of handling functions on the dead-letter topic are not emitted to the dead-letter topic again, to avoid loops.
+func (ee *EventEmitter) deadLetter(env *Envelope, err error) {
+	// 如果没有配置死信的去向，或者错误不表示处理失败，直接返回。
+	// If no dead-letter destination is configured, or the error does not indicate a handling failure, return directly.
+	sink, topic := ee.config.deadLetterSink, ee.config.deadLetterTopic
+	if (sink == nil && !topic) || !isHandlingFailure(err) {
+		return
+	}
+
+	// 创建描述处理失败的 DeadLetter。
+	// Create the DeadLetter describing the handling failure.
+	dl := &DeadLetter{Envelope: env, Err: err, Attempts: env.Attempt}
+
+	// 交给死信接收者。
+	// Hand it to the dead-letter sink.
+	if sink != nil {
+		sink.HandleDeadLetter(dl)
+	}
+
+	// 在死信主题上重新发出，没有处理函数或者 EventEmitter 已经关闭时，死信会被丢弃。
+	// Re-emit it on the dead-letter topic. The dead letter is dropped when there are no handling functions or the EventEmitter is closed.
+	if topic && env.Topic != DeadLetterTopic {
+		_ = ee.emit(context.Background(), DeadLetterTopic, dl, executeImmediately, nil)
+	}
+}
+
+// Requeue 是 EventEmitter 的一个方法，它将死信中的事件使用原来的主题、数据和头部立即重新发出，主题上的所有处理函数都会再次收到它。
+// Requeue is a method of EventEmitter that immediately re-emits the event in the dead letter with the original topic, data, and headers. All handling functions on the topic receive it again.
+func (ee *EventEmitter) Requeue(dl *DeadLetter) error {
+	ctx := ContextWithHeaders(context.Background(), dl.Envelope.Headers)
+	return ee.emit(ctx, dl.Envelope.Topic, dl.Envelope.Payload, executeImmediately, nil)
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrorEventNotScheduled 是一个变量，它的值为一个新的错误，表示延迟事件已经到期、被取消或者随 EventEmitter 关闭，不能再重新安排。
+// ErrorEventNotScheduled is a variable, its value is a new error, indicating that the delayed event is already due, cancelled or closed along with the EventEmitter, and can no longer be rescheduled.
+var ErrorEventNotScheduled = errors.New("event is not scheduled")
+
+// delayedEvent 是一个结构体，它记录一个尚未到期的延迟事件，并实现了 ScheduledEvent 接口。
+// 延迟事件只向 pipeline 提交一个到期任务，到期后才把事件分发给所有处理函数，因此在到期之前它仍然由 EventEmitter 管理。
+// 重新安排会增加代数，使已经提交的旧任务失效。
+// delayedEvent is a structure that records a delayed event that is not yet due, and implements the ScheduledEvent interface.
+// A delayed event submits only one due job to the pipeline and dispatches the event to all handling functions after it is due, so it is still managed by the EventEmitter before it is due.
+// Rescheduling increases the generation so that the old job already submitted becomes invalid.
+type delayedEvent struct {
+	// emission 是延迟事件的分发状态。
+	// emission is the dispatch state of the delayed event.
+	emission *emission
+
+	// generation 是当前的代数，只有代数相同的到期任务才会分发事件，由 scheduleLock 保护。
+	// generation is the current generation, only the due job of the same generation dispatches the event, protected by scheduleLock.
+	generation uint64
+}
+
+// delayedTick 是一个结构体，它是提交给 pipeline 的延迟事件到期任务的消息。
+// delayedTick is a structure that is the message of the due job of a delayed event submitted to the pipeline.
+type delayedTick struct {
+	// event 是到期的延迟事件。
+	// event is the delayed event that is due.
+	event *delayedEvent
+
+	// generation 是提交任务时延迟事件的代数。
+	// generation is the generation of the delayed event when the job was submitted.
+	generation uint64
+}
+
+// ID 是 delayedEvent 的一个方法，它返回事件的 ID。
+// ID is a method of delayedEvent that returns the ID of the event.
+func (d *delayedEvent) ID() string {
+	return d.emission.id
+}
+
+// Topic 是 delayedEvent 的一个方法，它返回事件的主题。
+// Topic is a method of delayedEvent that returns the topic of the event.
+func (d *delayedEvent) Topic() string {
+	return d.emission.topic
+}
+
+// When 是 delayedEvent 的一个方法，它返回事件到期的时间。
+// When is a method of delayedEvent that returns the time the event is due.
+func (d *delayedEvent) When() time.Time {
+	ee := d.emission.emitter
+	ee.scheduleLock.Lock()
+	defer ee.scheduleLock.Unlock()
+	return d.emission.scheduledAt
+}
+
+// Cancel 是 delayedEvent 的一个方法，它在事件到期之前取消事件，事件不会被分发，并在预写日志中标记为完成。
+// 只有事件确实被取消时才返回 true，已经到期、已经取消或者随 EventEmitter 关闭的事件返回 false。
+// Cancel is a method of delayedEvent that cancels the event before it is due, the event is not dispatched and is marked as completed in the write-ahead log.
+// It returns true only when the event is actually cancelled, and false for events already due, already cancelled or closed along with the EventEmitter.
+func (d *delayedEvent) Cancel() bool {
+	ee := d.emission.emitter
+
+	// 从集合中移除延迟事件，如果它已经被取走，返回 false。
+	// Remove the delayed event from the set, and return false if it has already been taken.
+	ee.scheduleLock.Lock()
+	if _, ok := ee.scheduled[d]; !ok {
+		ee.scheduleLock.Unlock()
+		return false
+	}
+	delete(ee.scheduled, d)
+	d.generation++
+	ee.scheduleLock.Unlock()
+
+	// 事件不会进入主题的历史，结束它的分发状态。
+	// The event does not enter the history of the topic, end its dispatch state.
+	d.emission.complete()
+	return true
+}
+
+// Reschedule 是 delayedEvent 的一个方法，它把事件的到期时间改为从现在开始的 delay 之后，delay 小于等于 0 时事件立即到期。
+// 已经到期、已经取消或者随 EventEmitter 关闭的事件返回 ErrorEventNotScheduled。
+// 如果新的到期时间无法写入预写日志或者到期任务提交失败，事件会被取消，并返回错误。
+// Reschedule is a method of delayedEvent that changes the due time of the event to delay after now, the event is due immediately when delay is less than or equal to 0.
+// Events already due, already cancelled or closed along with the EventEmitter return ErrorEventNotScheduled.
+// If the new due time cannot be written into the write-ahead log or the due job fails to be submitted, the event is cancelled and the error is returned.
+func (d *delayedEvent) Reschedule(delay time.Duration) error {
+	e := d.emission
+	ee := e.emitter
+
+	// 锁定延迟事件的集合，如果事件已经被取走，返回 ErrorEventNotScheduled 错误。
+	// Lock the set of delayed events, and return the ErrorEventNotScheduled error if the event has already been taken.
+	ee.scheduleLock.Lock()
+	if _, ok := ee.scheduled[d]; !ok {
+		ee.scheduleLock.Unlock()
+		return ErrorEventNotScheduled
+	}
+
+	// 更新到期时间并增加代数。在锁内重写日志，使到期任务不会在日志更新之前完成事件。
+	// Update the due time and increase the generation. The log is rewritten within the lock so that the due job cannot complete the event before the log is updated.
+	if delay < 0 {
+		delay = 0
+	}
+	d.generation++
+	tick := &delayedTick{event: d, generation: d.generation}
+	e.scheduledAt = ee.config.clock.Now().Add(delay)
+	if err := ee.relog(e); err != nil {
+		delete(ee.scheduled, d)
+		ee.scheduleLock.Unlock()
+		e.complete()
+		return err
+	}
+	ee.scheduleLock.Unlock()
+
+	// 提交新的到期任务，如果提交失败，取消事件，并返回错误。
+	// Submit the new due job, and cancel the event and return the error if the submission fails.
+	if err := ee.submitDelayed(tick, delay); err != nil {
+		if ee.takeDelayed(d, tick.generation) {
+			ee.inflight.Done()
+			e.complete()
+		}
+		return err
+	}
+
+	// 如果没有发生错误，返回 nil。
+	// If no error occurs, return nil.
+	return nil
+}
+
+// envelope 是 delayedEvent 的一个方法，它返回描述延迟事件的 Envelope，用于交还给调用方。
+// envelope is a method of delayedEvent that returns the Envelope describing the delayed event, used to hand it back to the caller.
+func (d *delayedEvent) envelope() *Envelope {
+	e := d.emission
+	return &Envelope{
+		ID:          e.id,
+		Topic:       e.topic,
+		Payload:     e.msg,
+		Timestamp:   e.timestamp,
+		ScheduledAt: e.scheduledAt,
+		Attempt:     1,
+		Headers:     cloneHeaders(e.headers),
+	}
+}
+
+// schedule 是 EventEmitter 的一个方法，它记录延迟事件，并向 pipeline 提交一个在延迟后执行的到期任务。
+// schedule is a method of EventEmitter that records the delayed event and submits a due job executed after the delay to the pipeline.
+func (ee *EventEmitter) schedule(e *emission, delay time.Duration) error {
+	d := &delayedEvent{emission: e}
+	e.delayed = d
+
+	// 锁定延迟事件的集合，如果 EventEmitter 已经关闭，返回 ErrEmitterClosed 错误。
+	// Lock the set of delayed events, and return the ErrEmitterClosed error if the EventEmitter is closed.
+	ee.scheduleLock.Lock()
+	if ee.closed.Load() {
+		ee.scheduleLock.Unlock()
+		return ErrEmitterClosed
+	}
+	ee.scheduled[d] = struct{}{}
+	ee.scheduleLock.Unlock()
+
+	// 提交到期任务，如果提交失败，移除延迟事件的记录，并返回错误。
+	// Submit the due job, and remove the record of the delayed event and return the error if the submission fails.
+	if err := ee.submitDelayed(&delayedTick{event: d}, delay); err != nil {
+		if ee.takeDelayed(d, 0) {
+			ee.inflight.Done()
+			e.complete()
+		}
+		return err
+	}
+
+	// 如果没有发生错误，返回 nil。
+	// If no error occurs, return nil.
+	return nil
+}
+
+// submitDelayed 是 EventEmitter 的一个方法，它向 pipeline 提交延迟事件的到期任务。
+// submitDelayed is a method of EventEmitter that submits the due job of the delayed event to the pipeline.
+func (ee *EventEmitter) submitDelayed(tick *delayedTick, delay time.Duration) error {
+	return ee.pipeline.SubmitAfterWithFunc(ee.fireDelayed, tick, delay)
+}
+
+// takeDelayed 是 EventEmitter 的一个方法，它从集合中取走代数为 generation 的延迟事件，只有第一个取走它的调用方会得到 true。
+// takeDelayed is a method of EventEmitter that takes the delayed event of the given generation out of the set, only the first caller taking it gets true.
+func (ee *EventEmitter) takeDelayed(d *delayedEvent, generation uint64) bool {
+	ee.scheduleLock.Lock()
+	defer ee.scheduleLock.Unlock()
+
+	// 如果延迟事件已经被取走，或者已经被重新安排，返回 false。
+	// If the delayed event has already been taken or has been rescheduled, return false.
+	if _, ok := ee.scheduled[d]; !ok || d.generation != generation {
+		return false
+	}
+
+	// 移除延迟事件，并在锁内将它标记为执行中，使 Shutdown 不会错过正在分发的事件。
+	// Remove the delayed event and mark it as in flight within the lock, so that Shutdown does not miss events being dispatched.
+	delete(ee.scheduled, d)
+	ee.inflight.Add(1)
+	return true
+}
+
+// fireDelayed 是 EventEmitter 的一个方法，它是延迟事件的到期任务，将尚未被取走的延迟事件分发给所有处理函数。
+// fireDelayed is a method of EventEmitter that is the due job of delayed events, dispatching the delayed event that has not been taken to all handling functions.
+func (ee *EventEmitter) fireDelayed(msg any) (any, error) {
+	tick := msg.(*delayedTick)
+	d := tick.event
+
+	// 如果延迟事件已经被 Shutdown、Stop 或者 Cancel 取走，或者已经被重新安排，什么也不做。
+	// If the delayed event has already been taken by Shutdown, Stop or Cancel, or has been rescheduled, do nothing.
+	if !ee.takeDelayed(d, tick.generation) {
+		return nil, nil
+	}
+	defer ee.inflight.Done()
+
+	// 延迟事件到期时进入主题的历史，然后立即分发。
+	// The delayed event enters the history of the topic when it is due, and is then dispatched immediately.
+	ee.recordDue(d.emission)
+	return nil, d.emission.dispatch()
+}
+
+// close 是 EventEmitter 的一个方法，它关闭 EventEmitter，并取走所有尚未到期的延迟事件，按到期时间排序后返回。
+// close is a method of EventEmitter that closes the EventEmitter, takes all delayed events that are not yet due, and returns them sorted by due time.
+func (ee *EventEmitter) close() []*delayedEvent {
+	ee.scheduleLock.Lock()
+	defer ee.scheduleLock.Unlock()
+
+	// 关闭 EventEmitter，之后发出事件会返回 ErrEmitterClosed。
+	// Close the EventEmitter, emitting events afterwards returns ErrEmitterClosed.
+	ee.closed.Store(true)
+
+	// 取走所有尚未到期的延迟事件。
+	// Take all delayed events that are not yet due.
+	pending := make([]*delayedEvent, 0, len(ee.scheduled))
+	for d := range ee.scheduled {
+		pending = append(pending, d)
+		delete(ee.scheduled, d)
+	}
+
+	// 按到期时间排序后返回取走的延迟事件。
+	// Return the taken delayed events sorted by due time.
+	sortDelayed(pending)
+	return pending
+}
+
+// sortDelayed 是一个函数，它按到期时间排序延迟事件，到期时间相同时按发出时间排序，调用方需要持有 scheduleLock。
+// sortDelayed is a function that sorts the delayed events by due time, and by emit time when the due times are equal, the caller needs to hold scheduleLock.
+func sortDelayed(pending []*delayedEvent) {
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := pending[i].emission, pending[j].emission
+		if !a.scheduledAt.Equal(b.scheduledAt) {
+			return a.scheduledAt.Before(b.scheduledAt)
+		}
+		return a.timestamp.Before(b.timestamp)
+	})
+}
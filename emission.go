@@ -0,0 +1,296 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// emission 是一个结构体，它记录一次事件发出的分发状态。
+// 开启事件冒泡时，每一级主题上的处理函数全部完成后，事件才会分发给下一级父主题上的处理函数。
+// emission is a structure that records the dispatch state of one emitted event.
+// When event bubbling is enabled, the event is dispatched to the handling functions of the next parent topic only after all handling functions of the current level have completed.
+type emission struct {
+	// emitter 是发出事件的 EventEmitter。
+	// emitter is the EventEmitter that emitted the event.
+	emitter *EventEmitter
+
+	// topic 是事件发出时的主题。
+	// topic is the topic the event was emitted on.
+	topic string
+
+	// msg 是事件的数据。
+	// msg is the data of the event.
+	msg any
+
+	// id 是事件的唯一标识。
+	// id is the unique identifier of the event.
+	id string
+
+	// timestamp 是事件发出的时间。
+	// timestamp is the time the event was emitted.
+	timestamp time.Time
+
+	// scheduledAt 是事件计划被处理的时间。
+	// scheduledAt is the time the event is scheduled to be handled.
+	scheduledAt time.Time
+
+	// headers 是发出时从上下文中取出的事件头部。
+	// headers is the event headers taken from the context at emission.
+	headers map[string]string
+
+	// levels 是按分发顺序排列的各级主题上的注册，levels[0] 是当前正在分发的一级。
+	// levels is the registrations of each topic level in dispatch order, levels[0] is the level currently being dispatched.
+	levels [][]*subscription
+
+	// pending 是当前一级中尚未完成的处理函数数量。
+	// pending is the number of handling functions in the current level that have not yet completed.
+	pending atomic.Int64
+
+	// stopped 表示是否有处理函数停止了事件的传播。
+	// stopped indicates whether a handling function has stopped the propagation of the event.
+	stopped atomic.Bool
+
+	// offset 是当前一级第一个处理函数在整个分发顺序中的位置。
+	// offset is the position of the first handling function of the current level in the whole dispatch order.
+	offset int
+
+	// future 用于收集处理函数的结果，只有需要等待结果时才不为 nil。
+	// future is used to collect the results of handling functions, it is not nil only when the results need to be waited for.
+	future *future
+
+	// ctx 是传递给处理函数的上下文。
+	// ctx is the context passed to the handling functions.
+	ctx context.Context
+
+	// cancel 是取消 ctx 的函数，只有 ctx 是为这次发出单独派生的上下文时才不为 nil。
+	// cancel is the function that cancels ctx, it is not nil only when ctx is a context derived separately for this emission.
+	cancel context.CancelFunc
+
+	// tracked 表示 ctx 是否派生自调用方的上下文，并记录在 EventEmitter 中。
+	// tracked indicates whether ctx is derived from the caller's context and recorded in the EventEmitter.
+	tracked bool
+
+	// logged 表示事件是否已经写入预写日志。
+	// logged indicates whether the event has been written into the write-ahead log.
+	logged bool
+
+	// incomplete 表示事件是否没有被完整处理：有处理函数失败、提交失败，或者延迟事件被 Stop 丢弃。这样的事件不会在预写日志中标记为完成。
+	// incomplete indicates whether the event has not been fully handled: a handling function failed, a submission failed, or the delayed event was dropped by Stop. Such events are not marked as completed in the write-ahead log.
+	incomplete atomic.Bool
+
+	// record 是事件在主题历史中的记录，主题没有设置 WithReplayBuffer 时为 nil。
+	// record is the record of the event in the history of the topic, it is nil when WithReplayBuffer is not set for the topic.
+	record *replayRecord
+
+	// replayed 表示事件是否是重放给新处理函数的历史事件。
+	// replayed indicates whether the event is a history event replayed to a new handling function.
+	replayed bool
+
+	// delayed 是延迟事件在到期之前的记录，立即发出的事件为 nil。
+	// delayed is the record of the delayed event before it is due, it is nil for events emitted immediately.
+	delayed *delayedEvent
+}
+
+// newEmission 是一个函数，它返回一个新的 emission 实例，并生成事件的元数据。处理函数确定之后需要调用 bind。
+// newEmission is a function that returns a new instance of emission and generates the metadata of the event. bind needs to be called after the handling functions are determined.
+func newEmission(ctx context.Context, ee *EventEmitter, topic string, msg any, delay time.Duration, f *future) *emission {
+	// 记录事件发出的时间。
+	// Record the time the event is emitted.
+	now := ee.config.clock.Now()
+
+	// 创建并返回一个新的 emission 实例。
+	// Create and return a new instance of emission.
+	return &emission{
+		emitter:     ee,
+		topic:       topic,
+		msg:         msg,
+		id:          internal.NewEventID(),
+		timestamp:   now,
+		scheduledAt: now.Add(delay),
+		headers:     ee.emitHeaders(ctx),
+		future:      f,
+	}
+}
+
+// bind 是 emission 的一个方法，它设置需要分发的各级主题上的注册，并绑定传递给处理函数的上下文。
+// bind is a method of emission that sets the registrations of each topic level to dispatch, and binds the context passed to the handling functions.
+func (e *emission) bind(ctx context.Context, levels [][]*subscription) {
+	e.levels = levels
+
+	// 绑定传递给处理函数的上下文。
+	// Bind the context passed to the handling functions.
+	e.bindContext(ctx)
+
+	// 如果需要等待结果，按所有级别的处理函数总数分配结果的存储空间，Future 被取消时停止传播并取消上下文。
+	// If the results need to be waited for, allocate the storage of results according to the total number of handling functions of all levels, and stop the propagation and cancel the context when the Future is cancelled.
+	if e.future != nil {
+		n := 0
+		for _, level := range levels {
+			n += len(level)
+		}
+		e.future.init(n, func() {
+			e.stopped.Store(true)
+			e.cancel()
+		})
+	}
+}
+
+// bindContext 是 emission 的一个方法，它根据调用方的上下文确定传递给处理函数的上下文。
+// 调用方没有可取消的上下文并且不需要等待结果时，直接使用 EventEmitter 的基础上下文；否则派生一个新的上下文，使它在调用方的上下文结束、Future 被取消或者 EventEmitter 停止时都会被取消。
+// bindContext is a method of emission that determines the context passed to the handling functions according to the caller's context.
+// When the caller has no cancellable context and the results do not need to be waited for, the base context of the EventEmitter is used directly; otherwise a new context is derived, which is cancelled when the caller's context is done, the Future is cancelled, or the EventEmitter is stopped.
+func (e *emission) bindContext(ctx context.Context) {
+	ee := e.emitter
+
+	// 调用方的上下文不可取消时，使用 EventEmitter 的基础上下文，并保留调用方上下文中的值，Stop 会通过基础上下文取消它。
+	// When the caller's context cannot be cancelled, use the base context of the EventEmitter and keep the values of the caller's context, and Stop cancels it through the base context.
+	if ctx.Done() == nil {
+		parent := ee.ctx
+		if ctx != context.Background() {
+			parent = valueContext{Context: ee.ctx, values: ctx}
+		}
+		if e.future == nil {
+			e.ctx = parent
+			return
+		}
+		e.ctx, e.cancel = context.WithCancel(parent)
+		return
+	}
+
+	// 否则从调用方的上下文派生，并记录在 EventEmitter 中，使 Stop 可以取消它。
+	// Otherwise derive from the caller's context and record it in the EventEmitter, so that Stop can cancel it.
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	e.tracked = true
+	ee.trackContext(e, e.cancel)
+}
+
+// dispatch 是 emission 的一个方法，它将事件提交给当前一级主题上的所有处理函数。
+// dispatch is a method of emission that submits the event to all handling functions of the current topic level.
+func (e *emission) dispatch() error {
+	// 如果没有需要分发的处理函数，例如事件只保留在主题的历史中，直接结束分发。
+	// If there are no handling functions to dispatch to, for example the event is only kept in the history of the topic, end the dispatch directly.
+	if len(e.levels) == 0 {
+		e.complete()
+		return nil
+	}
+	level := e.levels[0]
+
+	// 在提交之前设置未完成的数量，因为处理函数可能在提交全部完成之前就已经执行完毕。
+	// Set the pending count before submitting, because handling functions may finish before all submissions are done.
+	e.pending.Store(int64(len(level)))
+
+	// 按顺序为每一个处理函数提交一个任务。
+	// Submit one job for each handling function in order.
+	for i, sub := range level {
+		// 如果处理函数正在重放历史事件，新事件排队等待重放结束。
+		// If the handling function is replaying history events, the new event is queued until the replay ends.
+		if sub.gate != nil && !e.replayed && sub.gate.hold(e, e.offset+i) {
+			e.emitter.inflight.Add(1)
+			continue
+		}
+		if err := e.emitter.submit(sub, e, e.offset+i); err != nil {
+			// 提交失败时使用错误结束 Future，停止传播，并扣除尚未提交的数量。
+			// Resolve the Future with the error when the submission fails, stop the propagation, and deduct the number of jobs not yet submitted.
+			if e.future != nil {
+				e.future.fail(err)
+			}
+			e.stopped.Store(true)
+			e.incomplete.Store(true)
+			e.finish(int64(len(level) - i))
+			return err
+		}
+	}
+
+	// 如果没有发生错误，返回 nil。
+	// If no error occurs, return nil.
+	return nil
+}
+
+// doneFunc 是 emission 的一个方法，它返回分发顺序中第 i 个处理函数执行完成后调用的函数。
+// doneFunc is a method of emission that returns the function called after the i-th handling function in dispatch order has been executed.
+func (e *emission) doneFunc(i int) func(result any, err error) {
+	// 不需要等待结果时，所有处理函数共用同一个函数。
+	// When the results do not need to be waited for, all handling functions share the same function.
+	if e.future == nil {
+		return e.done
+	}
+
+	// 否则在完成前先记录这个处理函数的结果。
+	// Otherwise record the result of this handling function before completing.
+	return func(result any, err error) {
+		e.future.set(i, result, err)
+		e.done(result, err)
+	}
+}
+
+// done 是 emission 的一个方法，它在一个处理函数执行完成后被调用。
+// done is a method of emission that is called after a handling function has been executed.
+func (e *emission) done(_ any, err error) {
+	// 如果处理函数返回 ErrorStopPropagation，停止事件的传播。
+	// If the handling function returns ErrorStopPropagation, stop the propagation of the event.
+	if errors.Is(err, ErrorStopPropagation) {
+		e.stopped.Store(true)
+	}
+
+	// 如果处理失败或者执行被中断，事件没有被完整处理。
+	// If the handling fails or the execution is interrupted, the event has not been fully handled.
+	if isHandlingFailure(err) || isInterrupted(err) {
+		e.incomplete.Store(true)
+	}
+
+	// 标记一个处理函数已完成。
+	// Mark one handling function as completed.
+	e.finish(1)
+}
+
+// finish 是 emission 的一个方法，它减少当前一级中未完成的数量，当这一级全部完成后，分发给下一级。
+// finish is a method of emission that decreases the pending count of the current level, and dispatches to the next level when this level is all completed.
+func (e *emission) finish(n int64) {
+	// 如果当前一级还有未完成的处理函数，直接返回。
+	// If there are still pending handling functions in the current level, return directly.
+	if e.pending.Add(-n) > 0 {
+		return
+	}
+
+	// 如果传播已经停止，或者没有更多的父级主题，结束分发。
+	// If the propagation has stopped, or there are no more parent topics, end the dispatch.
+	if e.stopped.Load() || len(e.levels) <= 1 {
+		e.complete()
+		return
+	}
+
+	// 立即分发给下一级父主题。此时已经没有调用方可以接收错误，提交失败时剩余的各级不再分发，错误记录到 MetricsRecorder 的 OnRejected。
+	// Dispatch to the next parent topic immediately. There is no caller left to receive the error at this point, so when the submission fails the remaining levels are not dispatched, and the error is recorded by OnRejected of the MetricsRecorder.
+	e.offset += len(e.levels[0])
+	e.levels = e.levels[1:]
+	if err := e.dispatch(); err != nil {
+		e.emitter.config.metrics.OnRejected(e.topic, err)
+	}
+}
+
+// complete 是 emission 的一个方法，它在分发结束后被调用，通知等待结果的调用方。
+// complete is a method of emission that is called after the dispatch ends to notify the caller waiting for the results.
+func (e *emission) complete() {
+	// 如果事件已经写入预写日志并且被完整处理，将它标记为完成。
+	// If the event has been written into the write-ahead log and fully handled, mark it as completed.
+	if e.logged && !e.incomplete.Load() {
+		_ = e.emitter.config.wal.Complete(e.id)
+	}
+
+	if e.future != nil {
+		e.future.resolve()
+	}
+
+	// 释放为这次发出单独派生的上下文。
+	// Release the context derived separately for this emission.
+	if e.tracked {
+		e.emitter.untrackContext(e)
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
@@ -1,11 +1,16 @@
 package events
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shengyanli1982/events/internal"
+	"github.com/shengyanli1982/events/wal"
 )
 
 // executeImmediately 是一个常量，它的值为 time.Duration 类型的 0，表示立即执行。
@@ -16,6 +21,10 @@ const executeImmediately = time.Duration(0)
 // DefaultTopicName is a constant, its value is "default", indicating the default topic name.
 const DefaultTopicName = "default"
 
+// DefaultTopicSeparator 是一个常量，它的值为 "."，表示主题级别之间的默认分隔符。
+// DefaultTopicSeparator is a constant, its value is ".", indicating the default separator between topic levels.
+const DefaultTopicSeparator = "."
+
 // ErrorTopicNotExists 是一个变量，它的值为一个新的错误，表示主题不存在。
 // ErrorTopicNotExists is a variable, its value is a new error, indicating that the topic does not exist.
 var ErrorTopicNotExists = errors.New("topic does not exist")
@@ -24,14 +33,37 @@ var ErrorTopicNotExists = errors.New("topic does not exist")
 // ErrorTopicExecutedOnce is a variable, its value is a new error, indicating that the topic has been executed once.
 var ErrorTopicExecutedOnce = errors.New("topic has been executed once")
 
-// EventEmitter 是一个结构体，它包含五个字段：pipeline，once，eventPool，lock 和 registerFuncs。
-// EventEmitter is a structure that contains five fields: pipeline, once, eventPool, lock, and registerFuncs.
+// ErrorHandleFuncIsNil 是一个变量，它的值为一个新的错误，表示消息处理函数为 nil。
+// ErrorHandleFuncIsNil is a variable, its value is a new error, indicating that the message handling function is nil.
+var ErrorHandleFuncIsNil = errors.New("message handle function is nil")
+
+// ErrorTopicPatternInvalid 是一个变量，它的值为一个新的错误，表示主题模式无效。
+// ErrorTopicPatternInvalid is a variable, its value is a new error, indicating that the topic pattern is invalid.
+var ErrorTopicPatternInvalid = errors.New("topic pattern is invalid")
+
+// ErrorTopicInvalid 是一个变量，它的值为一个新的错误，表示主题名称无效。
+// ErrorTopicInvalid is a variable, its value is a new error, indicating that the topic name is invalid.
+var ErrorTopicInvalid = errors.New("topic is invalid")
+
+// ErrEmitterClosed 是一个变量，它的值为一个新的错误，表示 EventEmitter 已经关闭，不再接受新的事件。
+// ErrEmitterClosed is a variable, its value is a new error, indicating that the EventEmitter is closed and no longer accepts new events.
+var ErrEmitterClosed = errors.New("event emitter is closed")
 
+// ErrorStopPropagation 是一个变量，它的值为一个新的错误。处理函数返回它时，事件不会再冒泡到父级主题。
+// ErrorStopPropagation is a variable, its value is a new error. When a handling function returns it, the event no longer bubbles up to parent topics.
+var ErrorStopPropagation = errors.New("event propagation stopped")
+
+// EventEmitter 是一个结构体，它管理主题上注册的消息处理函数，并通过 pipeline 分发事件。
+// EventEmitter is a structure that manages the message handling functions registered on topics and dispatches events through the pipeline.
 type EventEmitter struct {
 	// pipeline 是 Pipeline 类型，用于处理事件。
 	// pipeline is of type Pipeline, used for handling events.
 	pipeline Pipeline
 
+	// config 是 EventEmitter 的配置。
+	// config is the configuration of EventEmitter.
+	config *Config
+
 	// once 是 sync.Once 类型，确保某些操作只执行一次。
 	// once is of type sync.Once, ensuring that certain operations are performed only once.
 	once sync.Once
@@ -44,20 +76,100 @@ type EventEmitter struct {
 	// lock is of type sync.RWMutex, used to protect concurrent access to registerFuncs.
 	lock sync.RWMutex
 
-	// registerFuncs 是一个映射，键是字符串，值是 handleFuncs 类型的指针，用于存储注册的事件处理函数。
-	// registerFuncs is a map with keys of type string and values of type pointer to handleFuncs, used to store registered event handling functions.
-	registerFuncs map[string]*handleFuncs
+	// registerFuncs 是一个映射，键是字符串，值是按注册顺序排列的 subscription 指针列表，用于存储注册的事件处理函数。
+	// registerFuncs is a map with keys of type string and values of ordered lists of pointers to subscription, used to store registered event handling functions.
+	registerFuncs map[string][]*subscription
+
+	// patterns 是一个主题前缀树，索引了 registerFuncs 中所有包含通配符的主题模式。
+	// patterns is a topic trie that indexes all topic patterns containing wildcards in registerFuncs.
+	patterns *internal.TopicTrie
+
+	// topicTypes 是一个映射，键是主题，值是通过 NewTopic 绑定到主题上的消息类型。
+	// topicTypes is a map with topics as keys and the message types bound to the topics through NewTopic as values.
+	topicTypes map[string]reflect.Type
+
+	// nextID 是一个原子计数器，用于生成注册的唯一标识。
+	// nextID is an atomic counter used to generate unique identifiers for registrations.
+	nextID atomic.Uint64
+
+	// middlewares 是一个原子指针，指向作用于所有处理函数的中间件列表。
+	// middlewares is an atomic pointer to the list of middlewares applied to all handling functions.
+	middlewares atomic.Pointer[[]Middleware]
+
+	// closed 表示 EventEmitter 是否已经关闭，关闭后发出事件会返回 ErrEmitterClosed。
+	// closed indicates whether the EventEmitter is closed, emitting events after it is closed returns ErrEmitterClosed.
+	closed atomic.Bool
+
+	// inflight 记录正在发出和正在执行的事件数量，Shutdown 会等待它降为 0。
+	// inflight records the number of events being emitted and being executed, Shutdown waits for it to drop to 0.
+	inflight *internal.Tracker
+
+	// scheduleLock 是 sync.Mutex 类型，用于保护 scheduled 的并发访问以及 closed 的修改。
+	// scheduleLock is of type sync.Mutex, used to protect concurrent access to scheduled and modifications of closed.
+	scheduleLock sync.Mutex
+
+	// scheduled 是一个集合，记录所有尚未到期的延迟事件。
+	// scheduled is a set that records all delayed events that are not yet due.
+	scheduled map[*delayedEvent]struct{}
+
+	// recurring 是一个集合，记录所有尚未取消的周期发出的计划，同样由 scheduleLock 保护。
+	// recurring is a set that records all recurring schedules not yet cancelled, also protected by scheduleLock.
+	recurring map[*recurringSchedule]struct{}
+
+	// ctx 是 EventEmitter 的基础上下文，调用 Stop 时被取消。
+	// ctx is the base context of EventEmitter, which is cancelled when Stop is called.
+	ctx context.Context
+
+	// cancel 是取消基础上下文的函数。
+	// cancel is the function that cancels the base context.
+	cancel context.CancelFunc
+
+	// ctxLock 是 sync.Mutex 类型，用于保护 contexts 的并发访问。
+	// ctxLock is of type sync.Mutex, used to protect concurrent access to contexts.
+	ctxLock sync.Mutex
+
+	// contexts 是一个映射，记录派生自调用方上下文、尚未完成分发的事件，调用 Stop 时它们的上下文会被取消。
+	// contexts is a map that records the events whose contexts are derived from the caller's context and whose dispatch has not completed. Their contexts are cancelled when Stop is called.
+	contexts map[*emission]context.CancelFunc
+
+	// historyLock 是 sync.Mutex 类型，用于保护 history 和 historySeq 的并发访问。
+	// historyLock is of type sync.Mutex, used to protect concurrent access to history and historySeq.
+	historyLock sync.Mutex
+
+	// history 是一个映射，键是主题，值是这个主题上保留的最近的事件，只有设置了 WithReplayBuffer 的主题才会保留。
+	// history is a map with topics as keys and the recent events kept on the topic as values, only topics with WithReplayBuffer set keep events.
+	history map[string]*replayBuffer
+
+	// historySeq 是最后一个进入历史的事件的顺序。
+	// historySeq is the order of the last event entering the history.
+	historySeq uint64
+
+	// recovered 是从预写日志中恢复、还没有重新发出的事件，在主题上注册了处理函数之后重新发出。
+	// recovered is the events recovered from the write-ahead log and not yet emitted again, they are emitted again after a handling function is registered for the topic.
+	recovered []*wal.Record
 }
 
 // NewEventEmitter 是一个函数，它接受一个 Pipeline 类型的参数，并返回一个 EventEmitter 类型的指针。
 // NewEventEmitter is a function that takes a parameter of type Pipeline and returns a pointer of type EventEmitter.
 func NewEventEmitter(pl Pipeline) *EventEmitter {
+	// 使用默认的配置创建 EventEmitter。
+	// Create the EventEmitter with the default configuration.
+	return NewEventEmitterWithConfig(pl, DefaultConfig())
+}
+
+// NewEventEmitterWithConfig 是一个函数，它接受一个 Pipeline 类型的参数和一个配置，并返回一个 EventEmitter 类型的指针。
+// NewEventEmitterWithConfig is a function that takes a parameter of type Pipeline and a configuration, and returns a pointer of type EventEmitter.
+func NewEventEmitterWithConfig(pl Pipeline, conf *Config) *EventEmitter {
 	// 如果传入的 pipeline 为 nil，则返回 nil。
 	// If the incoming pipeline is nil, return nil.
 	if pl == nil {
 		return nil
 	}
 
+	// 检查配置是否有效。
+	// Check whether the configuration is valid.
+	conf = isConfigValid(conf)
+
 	// 创建一个新的 EventEmitter 实例。
 	// Create a new instance of EventEmitter.
 	ee := EventEmitter{
@@ -65,6 +177,10 @@ func NewEventEmitter(pl Pipeline) *EventEmitter {
 		// Initialize the pipeline field.
 		pipeline: pl,
 
+		// 初始化 config 字段。
+		// Initialize the config field.
+		config: conf,
+
 		// 初始化 once 字段。
 		// Initialize the once field.
 		once: sync.Once{},
@@ -79,7 +195,45 @@ func NewEventEmitter(pl Pipeline) *EventEmitter {
 
 		// 初始化 registerFuncs 字段。
 		// Initialize the registerFuncs field.
-		registerFuncs: make(map[string]*handleFuncs),
+		registerFuncs: make(map[string][]*subscription),
+
+		// 初始化 patterns 字段。
+		// Initialize the patterns field.
+		patterns: internal.NewTopicTrie(conf.separator),
+
+		// 初始化 topicTypes 字段。
+		// Initialize the topicTypes field.
+		topicTypes: make(map[string]reflect.Type),
+
+		// 初始化 contexts 字段。
+		// Initialize the contexts field.
+		contexts: make(map[*emission]context.CancelFunc),
+
+		// 初始化 inflight 字段。
+		// Initialize the inflight field.
+		inflight: internal.NewTracker(),
+
+		// 初始化 scheduled 字段。
+		// Initialize the scheduled field.
+		scheduled: make(map[*delayedEvent]struct{}),
+
+		// 初始化 recurring 字段。
+		// Initialize the recurring field.
+		recurring: make(map[*recurringSchedule]struct{}),
+
+		// 初始化 history 字段。
+		// Initialize the history field.
+		history: make(map[string]*replayBuffer),
+	}
+
+	// 创建基础上下文。
+	// Create the base context.
+	ee.ctx, ee.cancel = context.WithCancel(context.Background())
+
+	// 取出预写日志中尚未完成的事件，等到它们的主题上注册了处理函数时再重新发出。
+	// Take the events not yet completed in the write-ahead log, they are emitted again once a handling function is registered for their topics.
+	if conf.wal != nil {
+		ee.recovered = conf.wal.TakeRecovered()
 	}
 
 	// 返回 EventEmitter 实例的指针。
@@ -87,91 +241,267 @@ func NewEventEmitter(pl Pipeline) *EventEmitter {
 	return &ee
 }
 
-// Stop 是 EventEmitter 的一个方法，它停止 EventEmitter 的 pipeline。
-// Stop is a method of EventEmitter that stops the pipeline of EventEmitter.
+// Stop 是 EventEmitter 的一个方法，它关闭 EventEmitter，取消周期发出的计划，丢弃尚未到期的延迟事件，取消所有处理函数的上下文，并停止 EventEmitter 的 pipeline。
+// 如果需要等待正在执行的处理函数，或者处理尚未到期的延迟事件，请使用 Shutdown。
+// Stop is a method of EventEmitter that closes the EventEmitter, cancels the recurring schedules, discards the delayed events that are not yet due, cancels the contexts of all handling functions, and stops the pipeline of EventEmitter.
+// Use Shutdown to wait for the running handling functions or to handle the delayed events that are not yet due.
 func (ee *EventEmitter) Stop() {
 	// 使用 once 确保 pipeline 的 Stop 方法只被调用一次。
 	// Use once to ensure that the Stop method of pipeline is called only once.
 	ee.once.Do(func() {
+		// 关闭 EventEmitter，并丢弃尚未到期的延迟事件，它们不会在预写日志中标记为完成。
+		// Close the EventEmitter and discard the delayed events that are not yet due, they are not marked as completed in the write-ahead log.
+		for _, d := range ee.close() {
+			d.emission.incomplete.Store(true)
+			d.emission.complete()
+		}
+
+		// 取消所有周期发出的计划。
+		// Cancel all recurring schedules.
+		ee.cancelRecurring()
+
+		// 取消基础上下文，以及所有派生自调用方上下文的事件上下文。
+		// Cancel the base context, and the contexts of all events derived from the caller's context.
+		ee.cancel()
+		ee.cancelContexts()
+
 		// 停止 pipeline。
 		// Stop the pipeline.
 		ee.pipeline.Stop()
 	})
 }
 
-// RegisterWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上。
-// RegisterWithTopic is a method of EventEmitter that takes a topic and a message handling function and registers this function to the specified topic.
-func (ee *EventEmitter) RegisterWithTopic(topic string, fn MessageHandleFunc) {
+// register 是 EventEmitter 的一个方法，它将 handleFuncs 实例注册到指定的主题上，并返回这次注册的 Subscription。
+// register is a method of EventEmitter that registers an instance of handleFuncs to the specified topic and returns the Subscription of this registration.
+func (ee *EventEmitter) register(topic string, fns *handleFuncs, o *registerOptions) (Subscription, error) {
+	// 检查主题中是否包含通配符。
+	// Check whether the topic contains wildcards.
+	wildcard := internal.IsWildcardPattern(topic, ee.config.separator)
+
+	// 通配符模式，以及开启事件冒泡时的所有主题，都需要是有效的主题模式，否则返回错误 ErrorTopicPatternInvalid。
+	// Wildcard patterns, and all topics when event bubbling is enabled, need to be valid topic patterns, otherwise return the error ErrorTopicPatternInvalid.
+	if (wildcard || ee.config.bubbling) && !internal.IsValidPattern(topic, ee.config.separator) {
+		return nil, ErrorTopicPatternInvalid
+	}
+
+	// 创建一个新的 subscription 实例。
+	// Create a new instance of subscription.
+	sub := newSubscription(ee, ee.nextID.Add(1), topic, fns)
+
 	// 锁定 EventEmitter，以防止并发修改。
 	// Lock the EventEmitter to prevent concurrent modifications.
 	ee.lock.Lock()
-	defer ee.lock.Unlock()
 
-	// 创建一个新的 handleFuncs 实例。
-	// Create a new instance of handleFuncs.
-	fns := newHandleFuncs()
+	// 如果是通配符模式，将它加入主题前缀树中。
+	// If it is a wildcard pattern, add it to the topic trie.
+	if wildcard {
+		ee.patterns.Insert(topic)
+	}
 
-	// 设置 origFunc 字段的值。
-	// Set the value of the origFunc field.
-	fns.SetOrigMsgHandleFunc(fn)
-
-	// 设置 wrapFunc 字段的值，这个函数在执行完毕后会将事件对象放回到池中。
-	// Set the value of the wrapFunc field. This function will put the event object back into the pool after it is executed.
-	fns.SetWrapMsgHandleFunc(func(msg any) (any, error) {
-		// 使用 defer 语句在函数结束时将事件对象放回到池中。
-		// Use the defer statement to put the event object back into the pool when the function ends.
-		defer ee.eventPool.Put(msg.(*internal.Event))
-
-		// 调用原始的消息处理函数，并返回结果。
-		// Call the original message handling function and return the result.
-		return fn(msg.(*internal.Event).GetData())
-	})
+	// 在锁内取得需要重放的历史事件，使它们与之后分发给这个处理函数的事件衔接：使用 WithReplay 时是保留的所有事件，否则是粘性主题上最新的事件。
+	// Take the history events to replay within the lock so that they connect with the events dispatched to this handling function afterwards: all kept events with WithReplay, otherwise the latest events of sticky topics.
+	var records []*replayRecord
+	if o.replay {
+		records = ee.historyOf(topic)
+	} else {
+		records = ee.latestOf(topic)
+	}
+	if len(records) > 0 {
+		sub.gate = &replayGate{records: records}
+	}
 
-	// 将新的 handleFuncs 实例注册到指定的主题上。
-	// Register the new instance of handleFuncs to the specified topic.
-	ee.registerFuncs[topic] = fns
-}
+	if o.replace {
+		// 如果是替换模式，用新的 subscription 实例替换主题上已注册的所有处理函数，并使被替换的注册失效。
+		// If in replace mode, replace all handling functions registered on the topic with the new instance of subscription and deactivate the replaced registrations.
+		deactivateSubscriptions(ee.registerFuncs[topic])
+		ee.registerFuncs[topic] = []*subscription{sub}
+	} else {
+		// 否则将新的 subscription 实例追加到主题的处理函数列表末尾。
+		// Otherwise, append the new instance of subscription to the end of the handling function list of the topic.
+		ee.registerFuncs[topic] = append(ee.registerFuncs[topic], sub)
+	}
 
-// Register 是 EventEmitter 的一个方法，它接受一个消息处理函数，将这个函数注册到默认的主题上。
-// Register is a method of EventEmitter that takes a message handling function and registers this function to the default topic.
-func (ee *EventEmitter) Register(fn MessageHandleFunc) {
-	// 调用 OnWithTopic 方法，将消息处理函数注册到默认的主题上。
-	// Call the OnWithTopic method to register the message handling function to the default topic.
-	ee.RegisterWithTopic(DefaultTopicName, fn)
+	// 取出这次注册之后可以分发的恢复事件。
+	// Take the recovered events that can be dispatched after this registration.
+	recovered := ee.takeRecovered(false)
+
+	// 解锁 EventEmitter。
+	// Unlock the EventEmitter.
+	ee.lock.Unlock()
+
+	// 在锁外重放历史事件，处理函数可能在提交时就被执行。
+	// Replay the history events outside the lock, the handling function may be executed while submitting.
+	if sub.gate != nil {
+		ee.replay(sub)
+	}
+
+	// 在锁外重新发出恢复的事件。
+	// Emit the recovered events again outside the lock.
+	_, _ = ee.recover(recovered)
+
+	// 返回这次注册的 Subscription。
+	// Return the Subscription of this registration.
+	return sub, nil
 }
 
-// UnregisterWithTopic 是 EventEmitter 的一个方法，它接受一个主题，将这个主题上注册的消息处理函数移除。
-// UnregisterWithTopic is a method of EventEmitter that takes a topic and removes the message handling function registered on this topic.
-func (ee *EventEmitter) UnregisterWithTopic(topic string) {
+// unsubscribe 是 EventEmitter 的一个方法，它从主题的处理函数列表中只移除指定的 subscription 实例。
+// unsubscribe is a method of EventEmitter that removes only the specified instance of subscription from the handling function list of the topic.
+func (ee *EventEmitter) unsubscribe(sub *subscription) {
 	// 锁定 EventEmitter，以防止并发修改。
 	// Lock the EventEmitter to prevent concurrent modifications.
 	ee.lock.Lock()
 	defer ee.lock.Unlock()
 
-	// 从 registerFuncs 中移除指定的主题。
-	// Remove the specified topic from registerFuncs.
+	// 如果这次注册已经失效，直接返回。
+	// If this registration is already inactive, return directly.
+	if !sub.active.Swap(false) {
+		return
+	}
+
+	// 从主题的处理函数列表中查找并移除这次注册。
+	// Find and remove this registration from the handling function list of the topic.
+	list := ee.registerFuncs[sub.topic]
+	for i, s := range list {
+		if s == sub {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+
+	// 如果主题上已经没有处理函数，移除这个主题。
+	// If there are no handling functions left on the topic, remove the topic.
+	if len(list) == 0 {
+		ee.removeTopic(sub.topic)
+		return
+	}
+
+	// 保存更新后的处理函数列表。
+	// Save the updated handling function list.
+	ee.registerFuncs[sub.topic] = list
+}
+
+// removeTopic 是 EventEmitter 的一个方法，它从 registerFuncs 和主题前缀树中移除指定的主题，调用方需要持有写锁。
+// removeTopic is a method of EventEmitter that removes the specified topic from registerFuncs and the topic trie, the caller needs to hold the write lock.
+func (ee *EventEmitter) removeTopic(topic string) {
 	delete(ee.registerFuncs, topic)
+	if internal.IsWildcardPattern(topic, ee.config.separator) {
+		ee.patterns.Remove(topic)
+	}
 }
 
-// Unregister 是 EventEmitter 的一个方法，它将默认主题上注册的消息处理函数移除。
-// Unregister is a method of EventEmitter that removes the message handling function registered on the default topic.
-func (ee *EventEmitter) Unregister() {
-	// 调用 OffWithTopic 方法，将默认主题上注册的消息处理函数移除。
-	// Call the OffWithTopic method to remove the message handling function registered on the default topic.
-	ee.UnregisterWithTopic(DefaultTopicName)
+// deactivateSubscriptions 是一个函数，它使列表中的所有注册失效。
+// deactivateSubscriptions is a function that deactivates all registrations in the list.
+func deactivateSubscriptions(list []*subscription) {
+	for _, sub := range list {
+		sub.active.Store(false)
+	}
 }
 
-// RegisterOnceWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上，并确保这个函数只执行一次。
-// RegisterOnceWithTopic is a method of EventEmitter that takes a topic and a message handling function, registers this function to the specified topic, and ensures that this function is executed only once.
-func (ee *EventEmitter) RegisterOnceWithTopic(topic string, fn MessageHandleFunc) {
-	// 锁定 EventEmitter，以防止并发修改。
-	// Lock the EventEmitter to prevent concurrent modifications.
-	ee.lock.Lock()
-	defer ee.lock.Unlock()
+// wrapMsgHandleFunc 是 EventEmitter 的一个方法，它将一个处理事件对象的函数包装成提交给 pipeline 的消息处理函数。
+// 如果设置了重试策略，失败的事件会使用 pipeline 的 SubmitAfterWithFunc 在退避时间之后重新执行；最终失败的事件会交给死信的去向。
+// 包装后的函数在事件最终执行完毕后会通知事件的分发状态，并将事件对象放回到池中。
+// wrapMsgHandleFunc is a method of EventEmitter that wraps a function handling event objects into the message handling function submitted to the pipeline.
+// If a retry policy is set, failed events are executed again after the backoff time through the SubmitAfterWithFunc method of pipeline; events that finally fail are handed to the dead-letter destination.
+// The wrapped function notifies the dispatch state of the event after the event has finally been executed, and puts the event object back into the pool.
+func (ee *EventEmitter) wrapMsgHandleFunc(fn func(event *internal.Event) (any, error), retry *RetryPolicy) MessageHandleFunc {
+	var wrapped MessageHandleFunc
+	wrapped = func(msg any) (data any, err error) {
+		event := msg.(*internal.Event)
+
+		// 记录任务排队等待的时间，并开始计算处理函数的执行时间。
+		// Record the queue wait time of the job, and start timing the handling function.
+		topic, start := event.GetTopic(), ee.config.clock.Now()
+		ee.config.metrics.OnStarted(topic, start.Sub(event.GetEnqueuedAt()))
+
+		// settle 决定失败的事件是在退避时间之后重新提交，还是交给死信的去向，返回事件是否已经重新提交。
+		// settle decides whether the failed event is resubmitted after the backoff time or handed to the dead-letter destination, and returns whether the event has been resubmitted.
+		settle := func(err error) bool {
+			// 如果需要重试，增加执行次数，并在退避时间之后重新提交事件。
+			// If a retry is needed, increase the number of attempts and resubmit the event after the backoff time.
+			attempt := event.GetAttempt()
+			if retry != nil && retry.shouldRetry(attempt, err) && event.GetContext().Err() == nil {
+				delay := retry.backoff(attempt)
+				event.SetAttempt(attempt + 1)
+				event.SetScheduledAt(ee.config.clock.Now().Add(delay))
+				event.SetEnqueuedAt(event.GetScheduledAt())
+				if ee.pipeline.SubmitAfterWithFunc(wrapped, event, delay) == nil {
+					return true
+				}
+			}
+
+			// 事件最终失败，将它交给死信的去向。
+			// The event finally fails, hand it to the dead-letter destination.
+			ee.deadLetter(newEnvelope(event), err)
+			return false
+		}
+
+		// 使用 defer 语句在事件最终执行完毕时通知分发状态，将事件对象放回到池中，并标记事件不再处于执行中。
+		// 如果处理函数发生 panic，把它记录为包装了 ErrorHandlerPanicked 的错误，按失败的事件重试或者交给死信，然后继续 panic，交给 pipeline 处理。
+		// Use the defer statement to notify the dispatch state, put the event object back into the pool, and mark the event as no longer in flight when the event has finally been executed.
+		// If the handling function panics, record it as an error wrapping ErrorHandlerPanicked, retry it or hand it to the dead-letter destination like a failed event, and then keep panicking for the pipeline to handle.
+		rescheduled := false
+		handled := false
+		defer func() {
+			if !handled {
+				if r := recover(); r != nil {
+					data, err = nil, fmt.Errorf("%w: %v", ErrorHandlerPanicked, r)
+					defer panic(r)
+				} else {
+					data, err = nil, fmt.Errorf("%w: handler did not return", ErrorHandlerPanicked)
+				}
+				rescheduled = settle(err)
+			}
+			if !rescheduled {
+				event.Done(data, err)
+				ee.eventPool.Put(event)
+				ee.inflight.Done()
+			}
+		}()
+
+		// 使用 defer 语句在通知分发状态之前记录这次执行的结果；如果处理函数发生 panic，handled 不会被设置，结果记录为 OutcomePanicked。
+		// Use the defer statement to record the result of this execution before notifying the dispatch state; if the handling function panics, handled is not set and the result is recorded as OutcomePanicked.
+		defer func() {
+			outcome := OutcomePanicked
+			if handled {
+				outcome = OutcomeOf(err)
+			}
+			ee.config.metrics.OnHandled(topic, outcome, ee.config.clock.Now().Sub(start))
+		}()
+
+		// 如果事件的上下文在处理函数开始之前就已经结束，跳过处理函数并返回上下文的错误。
+		// If the context of the event is done before the handling function starts, skip the handling function and return the error of the context.
+		if err := event.GetContext().Err(); err != nil {
+			handled = true
+			return nil, err
+		}
+
+		// 调用处理事件对象的函数，如果执行成功，直接返回结果。
+		// Call the function handling the event object, and return the result directly if it succeeds.
+		data, err = fn(event)
+		handled = true
+		if err == nil {
+			return data, nil
+		}
+
+		// 失败的事件按重试策略重试，或者交给死信的去向。
+		// Failed events are retried according to the retry policy, or handed to the dead-letter destination.
+		rescheduled = settle(err)
+
+		// 返回结果和错误。
+		// Return the result and error.
+		return data, err
+	}
+	return wrapped
+}
 
-	// 创建一个新的 sync.Once 实例。
-	// Create a new instance of sync.Once.
-	once := &sync.Once{}
+// registerHandleFunc 是 EventEmitter 的一个方法，它为处理函数创建 handleFuncs 实例，并将它注册到指定的主题上。
+// origFunc 是 GetMessageHandleFunc 返回的原始处理函数，fn 是实际执行的接收 Envelope 的处理函数，once 表示处理函数是否只执行一次。
+// registerHandleFunc is a method of EventEmitter that creates an instance of handleFuncs for the handling function and registers it to the specified topic.
+// origFunc is the original handling function returned by GetMessageHandleFunc, fn is the handling function receiving the Envelope actually executed, and once indicates whether the handling function is executed only once.
+func (ee *EventEmitter) registerHandleFunc(topic string, origFunc MessageHandleFunc, fn EnvelopeHandleFunc, once bool, opts []RegisterOption) (Subscription, error) {
+	// 应用注册选项。
+	// Apply the register options.
+	o := newRegisterOptions(opts)
 
 	// 创建一个新的 handleFuncs 实例。
 	// Create a new instance of handleFuncs.
@@ -179,60 +509,196 @@ func (ee *EventEmitter) RegisterOnceWithTopic(topic string, fn MessageHandleFunc
 
 	// 设置 origFunc 字段的值。
 	// Set the value of the origFunc field.
-	fns.SetOrigMsgHandleFunc(fn)
+	fns.SetOrigMsgHandleFunc(origFunc)
 
-	// 设置 wrapFunc 字段的值，这个函数在执行完毕后会将事件对象放回到池中，并确保原始的消息处理函数只执行一次。
-	// Set the value of the wrapFunc field. This function will put the event object back into the pool after it is executed and ensure that the original message handling function is executed only once.
-	fns.SetWrapMsgHandleFunc(func(msg any) (data any, err error) {
+	// 如果处理函数只执行一次，设置 once 字段的值，它记录这个处理函数是否已经执行过。
+	// If the handling function is executed only once, set the value of the once field, which records whether this handling function has been executed.
+	if once {
+		fns.SetOnce(&sync.Once{})
+	}
 
-		// 使用 defer 语句在函数结束时将事件对象放回到池中。
-		// Use the defer statement to put the event object back into the pool when the function ends.
-		defer ee.eventPool.Put(msg.(*internal.Event))
+	// 创建中间件链最内层的消息处理函数，它使用 Envelope 调用处理函数。
+	// Create the innermost message handling function of the middleware chain, which calls the handling function with the Envelope.
+	handler := func(msg any) (data any, err error) {
+		// 获取 Envelope，如果中间件传入的不是 Envelope，把它当作事件的数据。
+		// Get the Envelope. If a middleware passed something other than an Envelope, treat it as the data of the event.
+		env, ok := msg.(*Envelope)
+		if !ok {
+			env = &Envelope{Topic: topic, Payload: msg}
+		}
+
+		// 如果处理函数不限制执行次数，直接调用它，并返回结果。
+		// If the handling function is not limited in the number of executions, call it directly and return the result.
+		if !once {
+			return fn(env)
+		}
 
 		// 设置错误为 ErrorTopicExecutedOnce。
 		// Set the error to ErrorTopicExecutedOnce.
 		err = ErrorTopicExecutedOnce
 
-		// 使用 once 确保原始的消息处理函数只执行一次，并返回结果。
-		// Use once to ensure that the original message handling function is executed only once and return the result.
-		once.Do(func() {
-			data, err = fn(msg.(*internal.Event).GetData())
+		// 使用 once 确保处理函数只执行一次，并返回结果。
+		// Use once to ensure that the handling function is executed only once and return the result.
+		fns.GetOnce().Do(func() {
+			data, err = fn(env)
 		})
 
 		// 返回结果和错误。
 		// Return the result and error.
 		return data, err
-	})
+	}
+
+	// 组合这次注册的中间件。
+	// Compose the middlewares of this registration.
+	handler = chainMiddlewares(handler, o.middlewares)
+
+	// 只执行一次的处理函数不会重试。
+	// Run-once handling functions are not retried.
+	retry := o.retry
+	if once {
+		retry = nil
+	}
+
+	// 设置 wrapFunc 字段的值，这个函数在执行时使用 Envelope 执行组合了 EventEmitter 中间件的处理链，失败时按重试策略重试，在执行完毕后会将事件对象放回到池中。
+	// Set the value of the wrapFunc field. This function executes the handling chain composed with the middlewares of EventEmitter with the Envelope, retries on failure according to the retry policy, and puts the event object back into the pool after it is executed.
+	// 处理链在注册时组合一次，之后只有在 Use 改变了中间件列表时才重新组合。
+	// The chain is composed once at registration, and afterwards only again when Use has changed the middleware list.
+	var chain atomic.Pointer[middlewareChain]
+	ee.applyMiddlewares(&chain, handler)
+	fns.SetWrapMsgHandleFunc(ee.wrapMsgHandleFunc(func(event *internal.Event) (any, error) {
+		return ee.applyMiddlewares(&chain, handler)(newEnvelope(event))
+	}, retry))
+
+	// 将新的 handleFuncs 实例注册到指定的主题上，并返回这次注册的 Subscription。
+	// Register the new instance of handleFuncs to the specified topic and return the Subscription of this registration.
+	return ee.register(topic, fns, o)
+}
+
+// SubscribeWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上，并返回这次注册的 Subscription。
+// 同一个主题上可以注册多个处理函数，每个事件都会按注册顺序分发给所有处理函数。使用 WithReplace 选项可以替换主题上已有的处理函数。
+// 主题也可以是通配符模式：+ 或 * 匹配恰好一级，# 匹配零级或多级且只能出现在最后一级，例如 orders.*.created 或 orders.#。
+// SubscribeWithTopic is a method of EventEmitter that takes a topic and a message handling function, registers this function to the specified topic, and returns the Subscription of this registration.
+// Multiple handling functions can be registered on the same topic, and each event is dispatched to all of them in registration order. Use the WithReplace option to replace the existing handling functions on the topic.
+// The topic can also be a wildcard pattern: + or * matches exactly one level, # matches zero or more levels and can only appear at the last level, for example orders.*.created or orders.#.
+func (ee *EventEmitter) SubscribeWithTopic(topic string, fn MessageHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将消息处理函数适配为只接收数据的 EnvelopeHandleFunc 并注册。
+	// Adapt the message handling function to an EnvelopeHandleFunc that only receives the data and register it.
+	return ee.registerHandleFunc(topic, fn, payloadOnly(fn), false, opts)
+}
+
+// Subscribe 是 EventEmitter 的一个方法，它接受一个消息处理函数，将这个函数注册到默认的主题上，并返回这次注册的 Subscription。
+// Subscribe is a method of EventEmitter that takes a message handling function, registers this function to the default topic, and returns the Subscription of this registration.
+func (ee *EventEmitter) Subscribe(fn MessageHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 调用 SubscribeWithTopic 方法，将消息处理函数注册到默认的主题上。
+	// Call the SubscribeWithTopic method to register the message handling function to the default topic.
+	return ee.SubscribeWithTopic(DefaultTopicName, fn, opts...)
+}
+
+// RegisterWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上。
+// 它与 SubscribeWithTopic 相同，但是不返回 Subscription 和错误；需要 Subscription 或注册选项时使用 SubscribeWithTopic。
+// RegisterWithTopic is a method of EventEmitter that takes a topic and a message handling function and registers this function to the specified topic.
+// It is the same as SubscribeWithTopic but returns neither the Subscription nor the error; use SubscribeWithTopic when the Subscription or registration options are needed.
+func (ee *EventEmitter) RegisterWithTopic(topic string, fn MessageHandleFunc) {
+	_, _ = ee.SubscribeWithTopic(topic, fn)
+}
+
+// Register 是 EventEmitter 的一个方法，它接受一个消息处理函数，将这个函数注册到默认的主题上。
+// Register is a method of EventEmitter that takes a message handling function and registers this function to the default topic.
+func (ee *EventEmitter) Register(fn MessageHandleFunc) {
+	// 调用 RegisterWithTopic 方法，将消息处理函数注册到默认的主题上。
+	// Call the RegisterWithTopic method to register the message handling function to the default topic.
+	ee.RegisterWithTopic(DefaultTopicName, fn)
+}
+
+// UnregisterWithTopic 是 EventEmitter 的一个方法，它接受一个主题，将这个主题上注册的所有消息处理函数移除。
+// UnregisterWithTopic is a method of EventEmitter that takes a topic and removes all message handling functions registered on this topic.
+func (ee *EventEmitter) UnregisterWithTopic(topic string) {
+	// 锁定 EventEmitter，以防止并发修改。
+	// Lock the EventEmitter to prevent concurrent modifications.
+	ee.lock.Lock()
+	defer ee.lock.Unlock()
 
-	// 将新的 handleFuncs 实例注册到指定的主题上。
-	// Register the new instance of handleFuncs to the specified topic.
-	ee.registerFuncs[topic] = fns
+	// 使主题上的所有注册失效，并从 registerFuncs 中移除指定的主题。
+	// Deactivate all registrations on the topic and remove the specified topic from registerFuncs.
+	deactivateSubscriptions(ee.registerFuncs[topic])
+	ee.removeTopic(topic)
+}
+
+// Unregister 是 EventEmitter 的一个方法，它将默认主题上注册的消息处理函数移除。
+// Unregister is a method of EventEmitter that removes the message handling function registered on the default topic.
+func (ee *EventEmitter) Unregister() {
+	// 调用 OffWithTopic 方法，将默认主题上注册的消息处理函数移除。
+	// Call the OffWithTopic method to remove the message handling function registered on the default topic.
+	ee.UnregisterWithTopic(DefaultTopicName)
+}
+
+// SubscribeOnceWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上，确保这个函数只执行一次，并返回这次注册的 Subscription。
+// SubscribeOnceWithTopic is a method of EventEmitter that takes a topic and a message handling function, registers this function to the specified topic, ensures that this function is executed only once, and returns the Subscription of this registration.
+func (ee *EventEmitter) SubscribeOnceWithTopic(topic string, fn MessageHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将消息处理函数适配为只接收数据的 EnvelopeHandleFunc，并以只执行一次的方式注册。
+	// Adapt the message handling function to an EnvelopeHandleFunc that only receives the data and register it to be executed only once.
+	return ee.registerHandleFunc(topic, fn, payloadOnly(fn), true, opts)
+}
+
+// SubscribeOnce 是 EventEmitter 的一个方法，它接受一个消息处理函数，将这个函数注册到默认的主题上，确保这个函数只执行一次，并返回这次注册的 Subscription。
+// SubscribeOnce is a method of EventEmitter that takes a message handling function, registers this function to the default topic, ensures that this function is executed only once, and returns the Subscription of this registration.
+func (ee *EventEmitter) SubscribeOnce(fn MessageHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 调用 SubscribeOnceWithTopic 方法，将消息处理函数注册到默认的主题上，并确保这个函数只执行一次。
+	// Call the SubscribeOnceWithTopic method to register the message handling function to the default topic and ensure that this function is executed only once.
+	return ee.SubscribeOnceWithTopic(DefaultTopicName, fn, opts...)
+}
+
+// RegisterOnceWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息处理函数，将这个函数注册到指定的主题上，并确保这个函数只执行一次。
+// 它与 SubscribeOnceWithTopic 相同，但是不返回 Subscription 和错误。
+// RegisterOnceWithTopic is a method of EventEmitter that takes a topic and a message handling function, registers this function to the specified topic, and ensures that this function is executed only once.
+// It is the same as SubscribeOnceWithTopic but returns neither the Subscription nor the error.
+func (ee *EventEmitter) RegisterOnceWithTopic(topic string, fn MessageHandleFunc) {
+	_, _ = ee.SubscribeOnceWithTopic(topic, fn)
 }
 
 // RegisterOnce 是 EventEmitter 的一个方法，它接受一个消息处理函数，将这个函数注册到默认的主题上，并确保这个函数只执行一次。
 // RegisterOnce is a method of EventEmitter that takes a message handling function, registers this function to the default topic, and ensures that this function is executed only once.
 func (ee *EventEmitter) RegisterOnce(fn MessageHandleFunc) {
-	// 调用 OnceWithTopic 方法，将消息处理函数注册到默认的主题上，并确保这个函数只执行一次。
-	// Call the OnceWithTopic method to register the message handling function to the default topic and ensure that this function is executed only once.
+	// 调用 RegisterOnceWithTopic 方法，将消息处理函数注册到默认的主题上，并确保这个函数只执行一次。
+	// Call the RegisterOnceWithTopic method to register the message handling function to the default topic and ensure that this function is executed only once.
 	ee.RegisterOnceWithTopic(DefaultTopicName, fn)
 }
 
-// ResetOnceWithTopic 是 EventEmitter 的一个方法，它接受一个主题，将这个主题上注册的消息处理函数重置，以便可以再次执行。
-// ResetOnceWithTopic is a method of EventEmitter that takes a topic and resets the message handling function registered on this topic so that it can be executed again.
+// ResetOnceWithTopic 是 EventEmitter 的一个方法，它接受一个主题，将这个主题上注册的只执行一次的消息处理函数重置，以便可以再次执行。
+// ResetOnceWithTopic is a method of EventEmitter that takes a topic and resets the run-once message handling functions registered on this topic so that they can be executed again.
 func (ee *EventEmitter) ResetOnceWithTopic(topic string) error {
-	// 获取指定主题上注册的消息处理函数。
-	// Get the message handling function registered on the specified topic.
-	origHandleFunc, err := ee.GetMessageHandleFunc(topic)
+	// 锁定 EventEmitter，以防止并发读取。
+	// Lock the EventEmitter to prevent concurrent reads.
+	ee.lock.RLock()
+	defer ee.lock.RUnlock()
 
-	// 如果获取消息处理函数时出错，返回错误。
-	// If an error occurs when getting the message handling function, return the error.
-	if err != nil {
-		return err
+	// 从 registerFuncs 中获取指定主题的处理函数列表。
+	// Get the handling function list of the specified topic from registerFuncs.
+	list, ok := ee.registerFuncs[topic]
+
+	// 如果主题不存在，返回错误 ErrorTopicNotExists。
+	// If the topic does not exist, return the error ErrorTopicNotExists.
+	if !ok {
+		return ErrorTopicNotExists
 	}
 
-	// 使用 OnceWithTopic 方法，将消息处理函数重新注册到指定的主题上，并确保这个函数只执行一次。
-	// Use the OnceWithTopic method to re-register the message handling function to the specified topic and ensure that this function is executed only once.
-	ee.RegisterOnceWithTopic(topic, origHandleFunc)
+	// 重置主题上每一个只执行一次的处理函数。
+	// Reset every run-once handling function on the topic.
+	for _, sub := range list {
+		sub.fns.ResetOnce()
+	}
 
 	// 返回 nil，表示没有错误。
 	// Return nil to indicate that there is no error.
@@ -247,63 +713,245 @@ func (ee *EventEmitter) ResetOnce() error {
 	return ee.ResetOnceWithTopic(DefaultTopicName)
 }
 
-// emit 是 EventEmitter 的一个方法，它接受一个主题、一个消息和一个延迟时间，将消息发送到指定的主题上。
-// emit is a method of EventEmitter that takes a topic, a message, and a delay time, and sends the message to the specified topic.
-func (ee *EventEmitter) emit(topic string, msg any, delay time.Duration) error {
+// emit 是 EventEmitter 的一个方法，它接受一个主题、一个消息、一个延迟时间和一个可选的 future，将消息发送到指定的主题上。
+// emit is a method of EventEmitter that takes a topic, a message, a delay time, and an optional future, and sends the message to the specified topic.
+func (ee *EventEmitter) emit(ctx context.Context, topic string, msg any, delay time.Duration, f *future) error {
+	_, err := ee.emitEvent(ctx, topic, msg, delay, f, nil)
+	return err
+}
+
+// emitEvent 是 EventEmitter 的一个方法，它实现了 emit。restored 是从预写日志中恢复的事件，不为 nil 时事件沿用它的元数据，并且不会再次写入日志。
+// emitEvent is a method of EventEmitter that implements emit. restored is the event recovered from the write-ahead log, when it is not nil the event keeps its metadata and is not written into the log again.
+func (ee *EventEmitter) emitEvent(ctx context.Context, topic string, msg any, delay time.Duration, f *future, restored *wal.Record) (e *emission, err error) {
+	// 在发出期间将事件标记为执行中，使 Shutdown 等待已经通过关闭检查的发出完成提交。
+	// Mark the event as in flight during the emission, so that Shutdown waits for emissions that have passed the closed check to finish submitting.
+	ee.inflight.Add(1)
+	defer ee.inflight.Done()
+
+	// 发出结束时记录它被接受还是被拒绝。
+	// Record whether the emission was accepted or rejected when it ends.
+	defer func() {
+		if err != nil {
+			ee.config.metrics.OnRejected(topic, err)
+		} else {
+			ee.config.metrics.OnEmitted(topic)
+		}
+	}()
+
+	// 如果 EventEmitter 已经关闭，返回 ErrEmitterClosed 错误。
+	// If the EventEmitter is closed, return the ErrEmitterClosed error.
+	if ee.closed.Load() {
+		return nil, ErrEmitterClosed
+	}
+
+	// 如果发出方的上下文已经结束，返回上下文的错误。
+	// If the context of the emitter side is already done, return the error of the context.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 创建这次发出的分发状态，恢复的事件沿用日志中的元数据。
+	// Create the dispatch state of this emission, recovered events keep the metadata in the log.
+	e = newEmission(ctx, ee, topic, msg, delay, f)
+	if restored != nil {
+		e.restore(restored)
+	}
+
 	// 锁定 EventEmitter，以防止并发读取。
 	// Lock the EventEmitter to prevent concurrent reads.
 	ee.lock.RLock()
 
-	// 从 registerFuncs 中获取指定主题的 handleFuncs 实例。
-	// Get the handleFuncs instance of the specified topic from registerFuncs.
-	fns, ok := ee.registerFuncs[topic]
+	// 如果主题名称无效，返回 ErrorTopicInvalid 错误。
+	// If the topic name is invalid, return the ErrorTopicInvalid error.
+	if !ee.isValidTopic(topic) {
+		ee.lock.RUnlock()
+		return nil, ErrorTopicInvalid
+	}
+
+	// 如果主题绑定了消息类型，检查消息的类型是否匹配。
+	// If the topic is bound to a message type, check whether the type of the message matches.
+	if typ, ok := ee.topicTypes[topic]; ok {
+		if err := checkMessageType(topic, msg, typ); err != nil {
+			ee.lock.RUnlock()
+			return nil, err
+		}
+	}
+
+	// 获取各级主题上需要分发的处理函数。
+	// Get the handling functions to dispatch on each topic level.
+	levels := ee.resolveLevels(topic)
 
-	// 如果没有找到指定的主题，解锁 EventEmitter，并返回 ErrorTopicNotExists 错误。
-	// If the specified topic is not found, unlock the EventEmitter and return the ErrorTopicNotExists error.
-	if !ok {
+	// 如果没有找到与主题匹配的处理函数，并且主题不保留历史，返回 ErrorTopicNotExists 错误。
+	// If no handling function matching the topic is found and the topic does not keep a history, return the ErrorTopicNotExists error.
+	if _, ok := ee.config.replay[topic]; len(levels) == 0 && !ok {
 		ee.lock.RUnlock()
-		return ErrorTopicNotExists
+		return nil, ErrorTopicNotExists
+	}
+
+	// 立即分发的事件在读锁内进入主题的历史，延迟事件在到期时进入。
+	// Events dispatched immediately enter the history of the topic within the read lock, delayed events enter it when they are due.
+	if delay <= 0 {
+		ee.record(e)
 	}
 
 	// 解锁 EventEmitter。
 	// Unlock the EventEmitter.
 	ee.lock.RUnlock()
 
+	// 设置需要分发的处理函数。
+	// Set the handling functions to dispatch.
+	e.bind(ctx, levels)
+
+	// 新发出的事件在分发之前写入预写日志，写入失败时结束这次发出。
+	// Newly emitted events are written into the write-ahead log before dispatching, and the emission ends when writing fails.
+	if restored == nil {
+		if err := ee.appendLog(e); err != nil {
+			ee.drop(e)
+			if e.future != nil {
+				e.future.fail(err)
+			}
+			e.complete()
+			return nil, err
+		}
+	}
+
+	// 如果需要延迟，记录延迟事件，到期后再分发。
+	// If a delay is needed, record the delayed event and dispatch it when it is due.
+	if delay > 0 {
+		return e, ee.schedule(e, delay)
+	}
+
+	// 按顺序将事件分发给第一级主题上的处理函数。
+	// Dispatch the event to the handling functions of the first topic level in order.
+	return e, e.dispatch()
+}
+
+// resolveLevels 是 EventEmitter 的一个方法，它返回按分发顺序排列的各级主题上的注册，没有注册的级别会被跳过，调用方需要持有读锁。
+// 第一级是与主题匹配的所有注册；开启事件冒泡时，后面依次是各级父主题上精确注册的处理函数。
+// resolveLevels is a method of EventEmitter that returns the registrations of each topic level in dispatch order, levels without registrations are skipped, the caller needs to hold the read lock.
+// The first level is all registrations matching the topic; when event bubbling is enabled, it is followed by the handling functions registered exactly on each parent topic.
+func (ee *EventEmitter) resolveLevels(topic string) [][]*subscription {
+	var levels [][]*subscription
+
+	// 获取与主题匹配的所有处理函数。
+	// Get all handling functions that match the topic.
+	if list := ee.matchSubscriptions(topic); len(list) > 0 {
+		levels = append(levels, list)
+	}
+
+	// 如果开启了事件冒泡，依次追加各级父主题上的处理函数。
+	// If event bubbling is enabled, append the handling functions of each parent topic in turn.
+	if ee.config.bubbling {
+		for _, parent := range internal.ParentTopics(topic, ee.config.separator) {
+			if list := ee.registerFuncs[parent]; len(list) > 0 {
+				levels = append(levels, append([]*subscription(nil), list...))
+			}
+		}
+	}
+
+	// 返回各级主题上的注册。
+	// Return the registrations of each topic level.
+	return levels
+}
+
+// isValidTopic 是 EventEmitter 的一个方法，它判断发出事件的主题名称是否有效，调用方需要持有读锁。
+// 只有开启了事件冒泡或者注册了通配符模式时，主题才不能为空、不能包含空的级别和通配符；否则任何主题名称都有效。
+// isValidTopic is a method of EventEmitter that determines whether the topic name of an emitted event is valid, the caller needs to hold the read lock.
+// Only when event bubbling is enabled or wildcard patterns are registered, the topic cannot be empty or contain empty levels and wildcards; otherwise any topic name is valid.
+func (ee *EventEmitter) isValidTopic(topic string) bool {
+	if !ee.config.bubbling && ee.patterns.Empty() {
+		return true
+	}
+	return internal.IsValidTopic(topic, ee.config.separator)
+}
+
+// matchSubscriptions 是 EventEmitter 的一个方法，它返回与主题匹配的所有注册，调用方需要持有读锁。
+// 精确匹配的注册排在最前面，然后是按具体程度从高到低排序的通配符模式的注册，同一模式内保持注册顺序。
+// matchSubscriptions is a method of EventEmitter that returns all registrations matching the topic, the caller needs to hold the read lock.
+// Registrations with an exact match come first, followed by registrations of wildcard patterns sorted from the most specific to the least specific, keeping registration order within the same pattern.
+func (ee *EventEmitter) matchSubscriptions(topic string) []*subscription {
+	// 复制精确匹配的处理函数列表，避免在提交事件时持有锁。
+	// Copy the handling function list of the exact match to avoid holding the lock while submitting events.
+	list := append([]*subscription(nil), ee.registerFuncs[topic]...)
+
+	// 追加与主题匹配的通配符模式的处理函数。
+	// Append the handling functions of the wildcard patterns that match the topic.
+	for _, pattern := range ee.patterns.Match(topic) {
+		list = append(list, ee.registerFuncs[pattern]...)
+	}
+
+	// 返回处理函数列表。
+	// Return the handling function list.
+	return list
+}
+
+// MatchingPatterns 是 EventEmitter 的一个方法，它接受一个主题，然后按优先级返回与这个主题匹配的所有通配符模式。
+// MatchingPatterns is a method of EventEmitter that takes a topic, and then returns all wildcard patterns matching this topic in order of precedence.
+func (ee *EventEmitter) MatchingPatterns(topic string) []string {
+	// 锁定 EventEmitter，以防止并发读取。
+	// Lock the EventEmitter to prevent concurrent reads.
+	ee.lock.RLock()
+	defer ee.lock.RUnlock()
+
+	// 从主题前缀树中查找匹配的通配符模式。
+	// Find the matching wildcard patterns from the topic trie.
+	return ee.patterns.Match(topic)
+}
+
+// submit 是 EventEmitter 的一个方法，它为一个注册创建事件对象，并将其提交到 pipeline 中。
+// submit is a method of EventEmitter that creates an event object for a registration and submits it to the pipeline.
+func (ee *EventEmitter) submit(sub *subscription, e *emission, i int) error {
+	// 获取注册的消息处理函数。
+	// Get the message handling functions of the registration.
+	fns := sub.fns
+
 	// 从 eventPool 中获取一个事件对象。
 	// Get an event object from the eventPool.
 	event := ee.eventPool.Get()
 
 	// 设置事件对象的主题。
 	// Set the topic of the event object.
-	event.SetTopic(topic)
+	event.SetTopic(e.topic)
 
 	// 设置事件对象的数据。
 	// Set the data of the event object.
-	event.SetData(msg)
-
-	// 定义一个错误变量。
-	// Define an error variable.
-	var err error
-
-	// 检查 delay 是否大于 0。
-	// Check if delay is greater than 0.
-	if delay > 0 {
-		// 如果 delay 大于 0，那么使用 pipeline 的 SubmitAfterWithFunc 方法提交事件，该方法会在指定的延迟后执行事件。
-		// If delay is greater than 0, use the SubmitAfterWithFunc method of pipeline to submit the event. This method will execute the event after the specified delay.
-		err = ee.pipeline.SubmitAfterWithFunc(fns.GetWrapMsgHandleFunc(), event, delay)
-	} else {
-		// 如果 delay 不大于 0，那么使用 pipeline 的 SubmitWithFunc 方法立即提交事件。
-		// If delay is not greater than 0, use the SubmitWithFunc method of pipeline to submit the event immediately.
-		err = ee.pipeline.SubmitWithFunc(fns.GetWrapMsgHandleFunc(), event)
-	}
-
-	// 如果提交事件对象时发生错误，将事件对象放回到池中，并返回错误。
-	// If an error occurs when submitting the event object, put the event object back into the pool and return the error.
-	if err != nil {
+	event.SetData(e.msg)
+
+	// 设置事件对象的上下文。
+	// Set the context of the event object.
+	event.SetContext(e.ctx)
+
+	// 设置事件对象的元数据，每个处理函数都从第一次执行开始。
+	// Set the metadata of the event object, every handling function starts from the first attempt.
+	event.SetID(e.id)
+	event.SetTimestamp(e.timestamp)
+	event.SetScheduledAt(e.scheduledAt)
+	event.SetAttempt(1)
+	event.SetHeaders(e.headers)
+	event.SetEnqueuedAt(ee.config.clock.Now())
+
+	// 设置事件对象处理完成后的回调，用于跟踪分发状态。
+	// Set the callback of the event object after it has been handled, used to track the dispatch state.
+	event.SetDoneFunc(e.doneFunc(i))
+
+	// 在提交之前将事件标记为执行中，处理函数执行完毕后会取消标记。
+	// Mark the event as in flight before submitting, the mark is removed after the handling function has been executed.
+	ee.inflight.Add(1)
+
+	// 使用 pipeline 的 SubmitWithFunc 方法立即提交事件，延迟事件在到期之后才会走到这里。
+	// Use the SubmitWithFunc method of pipeline to submit the event immediately, delayed events only get here after they are due.
+	if err := ee.pipeline.SubmitWithFunc(fns.GetWrapMsgHandleFunc(), event); err != nil {
+		// 如果提交事件对象时发生错误，将事件对象放回到池中，取消执行中的标记，并返回错误。
+		// If an error occurs when submitting the event object, put the event object back into the pool, remove the in-flight mark, and return the error.
 		ee.eventPool.Put(event)
+		ee.inflight.Done()
 		return err
 	}
 
+	// 记录提交的任务。
+	// Record the submitted job.
+	ee.config.metrics.OnSubmitted(e.topic)
+
 	// 如果没有发生错误，返回 nil。
 	// If no error occurs, return nil.
 	return nil
@@ -312,7 +960,7 @@ func (ee *EventEmitter) emit(topic string, msg any, delay time.Duration) error {
 // EmitWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个消息，然后立即在指定的主题上发出这个消息。
 // EmitWithTopic is a method of EventEmitter that takes a topic and a message, and then immediately emits this message on the specified topic.
 func (ee *EventEmitter) EmitWithTopic(topic string, msg any) error {
-	return ee.emit(topic, msg, executeImmediately)
+	return ee.emit(context.Background(), topic, msg, executeImmediately, nil)
 }
 
 // Emit 是 EventEmitter 的一个方法，它接受一个消息，然后立即在默认的主题上发出这个消息。
@@ -324,7 +972,7 @@ func (ee *EventEmitter) Emit(msg any) error {
 // EmitAfterWithTopic 是 EventEmitter 的一个方法，它接受一个主题、一个消息和一个延迟，然后在指定的延迟后在指定的主题上发出这个消息。
 // EmitAfterWithTopic is a method of EventEmitter that takes a topic, a message, and a delay, and then emits this message on the specified topic after the specified delay.
 func (ee *EventEmitter) EmitAfterWithTopic(topic string, msg any, delay time.Duration) error {
-	return ee.emit(topic, msg, delay)
+	return ee.emit(context.Background(), topic, msg, delay, nil)
 }
 
 // EmitAfter 是 EventEmitter 的一个方法，它接受一个消息和一个延迟，然后在指定的延迟后在默认的主题上发出这个消息。
@@ -333,9 +981,97 @@ func (ee *EventEmitter) EmitAfter(msg any, delay time.Duration) error {
 	return ee.EmitAfterWithTopic(DefaultTopicName, msg, delay)
 }
 
-// GetMessageHandleFunc 是 EventEmitter 的一个方法，它接受一个主题，然后返回这个主题上注册的消息处理函数。
-// GetMessageHandleFunc is a method of EventEmitter that takes a topic, and then returns the message handling function registered on this topic.
+// ScheduleAfterWithTopic 是 EventEmitter 的一个方法，它与 EmitAfterWithTopic 一样在指定的延迟后在指定的主题上发出消息，并返回可以在事件到期之前取消或者重新安排的 ScheduledEvent。
+// 延迟小于等于 0 时事件立即发出，返回的 ScheduledEvent 已经到期。
+// ScheduleAfterWithTopic is a method of EventEmitter that emits the message on the specified topic after the specified delay like EmitAfterWithTopic, and returns a ScheduledEvent that can be cancelled or rescheduled before the event is due.
+// When the delay is less than or equal to 0 the event is emitted immediately and the returned ScheduledEvent is already due.
+func (ee *EventEmitter) ScheduleAfterWithTopic(topic string, msg any, delay time.Duration) (ScheduledEvent, error) {
+	e, err := ee.emitEvent(context.Background(), topic, msg, delay, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 立即发出的事件没有延迟事件的记录，返回一个已经到期的 ScheduledEvent。
+	// Events emitted immediately have no record of a delayed event, return a ScheduledEvent that is already due.
+	if e.delayed == nil {
+		return &delayedEvent{emission: e}, nil
+	}
+	return e.delayed, nil
+}
+
+// ScheduleAfter 是 EventEmitter 的一个方法，它在指定的延迟后在默认的主题上发出消息，并返回 ScheduledEvent。
+// ScheduleAfter is a method of EventEmitter that emits the message on the default topic after the specified delay, and returns a ScheduledEvent.
+func (ee *EventEmitter) ScheduleAfter(msg any, delay time.Duration) (ScheduledEvent, error) {
+	return ee.ScheduleAfterWithTopic(DefaultTopicName, msg, delay)
+}
+
+// EmitAt 是 EventEmitter 的一个方法，它接受一个主题、一个消息和一个时间，然后在指定的时间在指定的主题上发出这个消息，并返回 ScheduledEvent。时间已经过去时事件立即发出。
+// EmitAt is a method of EventEmitter that takes a topic, a message, and a time, and then emits this message on the specified topic at the specified time, returning a ScheduledEvent. The event is emitted immediately when the time has already passed.
+func (ee *EventEmitter) EmitAt(topic string, msg any, at time.Time) (ScheduledEvent, error) {
+	return ee.ScheduleAfterWithTopic(topic, msg, at.Sub(ee.config.clock.Now()))
+}
+
+// EmitAndWait 是 EventEmitter 的一个方法，它接受一个上下文、一个主题和一个消息，立即在指定的主题上发出这个消息，并阻塞直到所有处理函数执行完毕或者 ctx 结束。
+// 它返回分发顺序中第一个处理函数的结果，以及第一个非 nil 的错误；ctx 先结束时返回 ctx 的错误，处理函数通过它们的上下文观察到取消。
+// EmitAndWait is a method of EventEmitter that takes a context, a topic, and a message, immediately emits this message on the specified topic, and blocks until all handling functions have finished or ctx is done.
+// It returns the result of the first handling function in dispatch order and the first non-nil error; if ctx is done first, the error of ctx is returned, and handling functions observe the cancellation through their context.
+func (ee *EventEmitter) EmitAndWait(ctx context.Context, topic string, msg any) (any, error) {
+	// 创建一个新的 future 实例，用于收集处理结果。
+	// Create a new instance of future to collect the handling results.
+	f := newFuture()
+
+	// 发出消息，如果发出失败，直接返回错误。
+	// Emit the message, and return the error directly if the emission fails.
+	if err := ee.emit(ctx, topic, msg, executeImmediately, f); err != nil {
+		return nil, err
+	}
+
+	// 等待处理结果。
+	// Wait for the handling results.
+	return f.wait(ctx)
+}
+
+// EmitAsync 是 EventEmitter 的一个方法，它接受一个主题和一个消息，立即在指定的主题上发出这个消息，并返回一个 Future。
+// 所有处理函数执行完毕后 Future 完成；如果发出失败，返回的 Future 已经完成，并带有发出时的错误。
+// EmitAsync is a method of EventEmitter that takes a topic and a message, immediately emits this message on the specified topic, and returns a Future.
+// The Future completes after all handling functions have finished; if the emission fails, the returned Future is already completed with the error at emission.
+func (ee *EventEmitter) EmitAsync(topic string, msg any) Future {
+	// 创建一个新的 future 实例，用于收集处理结果。
+	// Create a new instance of future to collect the handling results.
+	f := newFuture()
+
+	// 发出消息，如果发出失败，使用错误结束 Future。
+	// Emit the message, and resolve the Future with the error if the emission fails.
+	if err := ee.emit(context.Background(), topic, msg, executeImmediately, f); err != nil {
+		f.fail(err)
+	}
+
+	// 返回 Future。
+	// Return the Future.
+	return f
+}
+
+// GetMessageHandleFunc 是 EventEmitter 的一个方法，它接受一个主题，然后返回这个主题上最先注册的消息处理函数。
+// GetMessageHandleFunc is a method of EventEmitter that takes a topic, and then returns the first message handling function registered on this topic.
 func (ee *EventEmitter) GetMessageHandleFunc(topic string) (MessageHandleFunc, error) {
+	// 获取主题上注册的所有消息处理函数。
+	// Get all message handling functions registered on the topic.
+	fns, err := ee.GetMessageHandleFuncs(topic)
+
+	// 如果获取消息处理函数时出错，返回错误。
+	// If an error occurs when getting the message handling functions, return the error.
+	if err != nil {
+		return nil, err
+	}
+
+	// 返回主题上最先注册的消息处理函数。
+	// Return the first message handling function registered on the topic.
+	return fns[0], nil
+}
+
+// GetMessageHandleFuncs 是 EventEmitter 的一个方法，它接受一个主题，然后按注册顺序返回这个主题上注册的所有消息处理函数。
+// GetMessageHandleFuncs is a method of EventEmitter that takes a topic, and then returns all message handling functions registered on this topic in registration order.
+func (ee *EventEmitter) GetMessageHandleFuncs(topic string) ([]MessageHandleFunc, error) {
 	// 锁定 EventEmitter，以防止并发读取。
 	// Lock the EventEmitter to prevent concurrent reads.
 	ee.lock.RLock()
@@ -343,15 +1079,22 @@ func (ee *EventEmitter) GetMessageHandleFunc(topic string) (MessageHandleFunc, e
 
 	// 从 registerFuncs 中获取指定的主题。
 	// Get the specified topic from registerFuncs.
-	metadata, ok := ee.registerFuncs[topic]
+	list, ok := ee.registerFuncs[topic]
 
 	// 如果主题不存在，返回错误 ErrorTopicNotExists。
 	// If the topic does not exist, return the error ErrorTopicNotExists.
-	if !ok {
+	if !ok || len(list) == 0 {
 		return nil, ErrorTopicNotExists
 	}
 
+	// 按注册顺序收集主题上注册的消息处理函数。
+	// Collect the message handling functions registered on the topic in registration order.
+	fns := make([]MessageHandleFunc, 0, len(list))
+	for _, sub := range list {
+		fns = append(fns, sub.fns.GetOrigMsgHandleFunc())
+	}
+
 	// 返回主题上注册的消息处理函数。
-	// Return the message handling function registered on the topic.
-	return metadata.GetOrigMsgHandleFunc(), nil
+	// Return the message handling functions registered on the topic.
+	return fns, nil
 }
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// Envelope 是一个结构体，它包装了一次事件分发给处理函数时的数据和元数据，中间件收到的消息就是 *Envelope。
+// Envelope is a structure that wraps the data and metadata of an event when it is dispatched to a handling function. The message received by middlewares is *Envelope.
+type Envelope struct {
+	// ID 是事件的唯一标识，同一次发出的所有处理函数，包括冒泡到父级主题的处理函数，看到的都是同一个标识。
+	// ID is the unique identifier of the event. All handling functions of the same emission, including those on parent topics reached by bubbling, see the same identifier.
+	ID string
+
+	// Topic 是事件发出时的主题。
+	// Topic is the topic the event was emitted on.
+	Topic string
+
+	// Payload 是事件的数据。
+	// Payload is the data of the event.
+	Payload any
+
+	// Timestamp 是事件发出的时间。
+	// Timestamp is the time the event was emitted.
+	Timestamp time.Time
+
+	// ScheduledAt 是事件计划被处理的时间，立即发出的事件与 Timestamp 相同，延迟发出的事件是 Timestamp 加上延迟。
+	// ScheduledAt is the time the event is scheduled to be handled. It equals Timestamp for events emitted immediately, and Timestamp plus the delay for delayed events.
+	ScheduledAt time.Time
+
+	// Attempt 是处理函数对这个事件的第几次执行，从 1 开始。
+	// Attempt is the number of the execution of the handling function for this event, starting from 1.
+	Attempt int
+
+	// Headers 是事件的字符串头部，每个处理函数收到的都是独立的副本。
+	// Headers is the string headers of the event, every handling function receives its own copy.
+	Headers map[string]string
+
+	// ctx 是传递给处理函数的上下文。
+	// ctx is the context passed to the handling function.
+	ctx context.Context
+}
+
+// newEnvelope 是一个函数，它使用事件对象创建一个新的 Envelope 实例。
+// newEnvelope is a function that creates a new instance of Envelope from an event object.
+func newEnvelope(event *internal.Event) *Envelope {
+	return &Envelope{
+		ID:          event.GetID(),
+		Topic:       event.GetTopic(),
+		Payload:     event.GetData(),
+		Timestamp:   event.GetTimestamp(),
+		ScheduledAt: event.GetScheduledAt(),
+		Attempt:     event.GetAttempt(),
+		Headers:     cloneHeaders(event.GetHeaders()),
+		ctx:         event.GetContext(),
+	}
+}
+
+// Context 是 Envelope 的一个方法，它返回传递给处理函数的上下文，如果没有设置则返回 context.Background()。
+// Context is a method of Envelope that returns the context passed to the handling function, or context.Background() if it is not set.
+func (e *Envelope) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// WithContext 是 Envelope 的一个方法，它返回一个使用新上下文的 Envelope 浅拷贝，中间件可以用它向处理函数传递值。
+// WithContext is a method of Envelope that returns a shallow copy of the Envelope with the new context, which middlewares can use to pass values to handling functions.
+func (e *Envelope) WithContext(ctx context.Context) *Envelope {
+	// 复制 Envelope，并替换上下文。
+	// Copy the Envelope and replace the context.
+	c := *e
+	c.ctx = ctx
+
+	// 返回新的 Envelope。
+	// Return the new Envelope.
+	return &c
+}
+
+// Header 是 Envelope 的一个方法，它返回指定键的头部值，如果不存在则返回空字符串。
+// Header is a method of Envelope that returns the header value of the specified key, or an empty string if it does not exist.
+func (e *Envelope) Header(key string) string {
+	return e.Headers[key]
+}
+
+// headersKey 是在上下文中保存事件头部的键类型。
+// headersKey is the key type used to store event headers in a context.
+type headersKey struct{}
+
+// HeaderInjector 是一个函数类型，它在事件发出时根据发出方的上下文向事件的头部中写入内容，例如链路追踪的上下文。
+// HeaderInjector is a function type that writes into the headers of an event from the emitter side's context when the event is emitted, for example the trace context.
+type HeaderInjector = func(ctx context.Context, headers map[string]string)
+
+// emitHeaders 是 EventEmitter 的一个方法，它返回发出事件时使用的头部：上下文中的头部，加上 HeaderInjector 写入的内容。
+// emitHeaders is a method of EventEmitter that returns the headers used when emitting an event: the headers in the context, plus what the HeaderInjector writes.
+func (ee *EventEmitter) emitHeaders(ctx context.Context) map[string]string {
+	headers := cloneHeaders(HeadersFromContext(ctx))
+	if inject := ee.config.headerInjector; inject != nil {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		inject(ctx, headers)
+		if len(headers) == 0 {
+			headers = nil
+		}
+	}
+	return headers
+}
+
+// ContextWithHeaders 是一个函数，它返回一个携带事件头部的新上下文，与上下文中已有的头部合并，同名的键以新值为准。
+// 使用这个上下文调用 EmitWithContext 或 EmitAndWait 时，头部会出现在 Envelope.Headers 中。
+// ContextWithHeaders is a function that returns a new context carrying event headers, merged with the headers already in the context, where keys with the same name take the new values.
+// When EmitWithContext or EmitAndWait is called with this context, the headers appear in Envelope.Headers.
+func ContextWithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	// 复制上下文中已有的头部，并合并新的头部。
+	// Copy the headers already in the context and merge the new headers.
+	merged := cloneHeaders(HeadersFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(headers))
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+
+	// 返回携带头部的新上下文。
+	// Return the new context carrying the headers.
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext 是一个函数，它返回上下文中携带的事件头部，如果没有则返回 nil。返回的映射不应被修改。
+// HeadersFromContext is a function that returns the event headers carried in the context, or nil if there are none. The returned map should not be modified.
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// cloneHeaders 是一个函数，它返回头部的副本，空的头部返回 nil。
+// cloneHeaders is a function that returns a copy of the headers, empty headers return nil.
+func cloneHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(headers))
+	for k, v := range headers {
+		c[k] = v
+	}
+	return c
+}
+
+// payloadOnly 是一个函数，它将消息处理函数适配为只把 Envelope 的数据传给它的 EnvelopeHandleFunc。
+// payloadOnly is a function that adapts a message handling function to an EnvelopeHandleFunc that only passes the data of the Envelope to it.
+func payloadOnly(fn MessageHandleFunc) EnvelopeHandleFunc {
+	return func(env *Envelope) (any, error) {
+		return fn(env.Payload)
+	}
+}
+
+// RegisterEnvelopeWithTopic 是 EventEmitter 的一个方法，它接受一个主题和一个接收 Envelope 的消息处理函数，将这个函数注册到指定的主题上。
+// RegisterEnvelopeWithTopic is a method of EventEmitter that takes a topic and a message handling function receiving the Envelope, and registers this function to the specified topic.
+func (ee *EventEmitter) RegisterEnvelopeWithTopic(topic string, fn EnvelopeHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 注册接收 Envelope 的消息处理函数，原始处理函数使用只包含主题和数据的 Envelope 调用它。
+	// Register the message handling function receiving the Envelope, the original handling function calls it with an Envelope containing only the topic and data.
+	return ee.registerHandleFunc(topic, func(msg any) (any, error) { return fn(&Envelope{Topic: topic, Payload: msg}) }, fn, false, opts)
+}
+
+// RegisterEnvelope 是 EventEmitter 的一个方法，它接受一个接收 Envelope 的消息处理函数，将这个函数注册到默认的主题上。
+// RegisterEnvelope is a method of EventEmitter that takes a message handling function receiving the Envelope and registers this function to the default topic.
+func (ee *EventEmitter) RegisterEnvelope(fn EnvelopeHandleFunc, opts ...RegisterOption) (Subscription, error) {
+	return ee.RegisterEnvelopeWithTopic(DefaultTopicName, fn, opts...)
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrorFutureCancelled 是一个变量，它的值为一个新的错误，表示 Future 已经被取消。
+// ErrorFutureCancelled is a variable, its value is a new error, indicating that the Future has been cancelled.
+var ErrorFutureCancelled = errors.New("future has been cancelled")
+
+// future 是一个结构体，它实现了 Future 接口，收集一次事件发出中所有处理函数的结果，并在分发结束后通知等待方。
+// future is a structure that implements the Future interface, collects the results of all handling functions of one emitted event, and notifies the waiter after the dispatch ends.
+type future struct {
+	// results 是按分发顺序排列的处理结果。
+	// results is the handling results in dispatch order.
+	results []any
+
+	// errs 是按分发顺序排列的处理错误。
+	// errs is the handling errors in dispatch order.
+	errs []error
+
+	// err 是发出事件失败时的错误，它优先于处理函数的错误。
+	// err is the error when emitting the event fails, it takes precedence over the errors of handling functions.
+	err error
+
+	// done 是一个通道，分发结束或者被取消后会被关闭。
+	// done is a channel that is closed after the dispatch ends or is cancelled.
+	done chan struct{}
+
+	// once 确保 done 通道只被关闭一次。
+	// once ensures that the done channel is closed only once.
+	once sync.Once
+
+	// cancelled 表示 Future 是否已经被取消。
+	// cancelled indicates whether the Future has been cancelled.
+	cancelled atomic.Bool
+
+	// onCancel 是 Future 被取消时调用的函数，用于停止后续的分发。
+	// onCancel is the function called when the Future is cancelled, used to stop further dispatch.
+	onCancel func()
+}
+
+// newFuture 是一个函数，它返回一个新的 future 实例。
+// newFuture is a function that returns a new instance of future.
+func newFuture() *future {
+	return &future{done: make(chan struct{})}
+}
+
+// init 是 future 的一个方法，它按处理函数的总数分配结果的存储空间，并设置取消时调用的函数。
+// init is a method of future that allocates the storage of results according to the total number of handling functions and sets the function called on cancellation.
+func (f *future) init(n int, onCancel func()) {
+	f.results = make([]any, n)
+	f.errs = make([]error, n)
+	f.onCancel = onCancel
+}
+
+// set 是 future 的一个方法，它记录分发顺序中第 i 个处理函数的结果和错误。每个位置只会被一个处理函数写入。
+// set is a method of future that records the result and error of the i-th handling function in dispatch order. Each position is written by only one handling function.
+func (f *future) set(i int, result any, err error) {
+	f.results[i] = result
+	f.errs[i] = err
+}
+
+// fail 是 future 的一个方法，它在发出事件失败时使用错误结束 Future。如果 Future 已经结束，它不做任何事情。
+// fail is a method of future that resolves the Future with the error when emitting the event fails. If the Future has already ended, it does nothing.
+func (f *future) fail(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+// resolve 是 future 的一个方法，它通知等待方分发已经结束。
+// resolve is a method of future that notifies the waiter that the dispatch has ended.
+func (f *future) resolve() {
+	f.once.Do(func() { close(f.done) })
+}
+
+// Done 是 future 的一个方法，它返回一个通道，分发结束或者 Future 被取消后这个通道会被关闭。
+// Done is a method of future that returns a channel, which is closed after the dispatch ends or the Future is cancelled.
+func (f *future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result 是 future 的一个方法，它阻塞直到分发结束，然后返回分发顺序中第一个处理函数的结果，以及第一个非 nil 的错误。
+// ErrorStopPropagation 只用于控制冒泡，不会作为错误返回；Future 被取消时返回 ErrorFutureCancelled。
+// Result is a method of future that blocks until the dispatch ends, and then returns the result of the first handling function in dispatch order and the first non-nil error.
+// ErrorStopPropagation is only used to control bubbling and is not returned as an error; ErrorFutureCancelled is returned if the Future has been cancelled.
+func (f *future) Result() (result any, err error) {
+	// 等待分发结束。
+	// Wait for the dispatch to end.
+	<-f.done
+
+	// 如果 Future 已经被取消，返回 ErrorFutureCancelled。
+	// If the Future has been cancelled, return ErrorFutureCancelled.
+	if f.cancelled.Load() {
+		return nil, ErrorFutureCancelled
+	}
+
+	// 如果发出事件失败，返回发出时的错误。
+	// If emitting the event failed, return the error at emission.
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// 取第一个处理函数的结果。
+	// Take the result of the first handling function.
+	if len(f.results) > 0 {
+		result = f.results[0]
+	}
+
+	// 取第一个非 nil 的错误。
+	// Take the first non-nil error.
+	for _, e := range f.errs {
+		if e != nil && !errors.Is(e, ErrorStopPropagation) {
+			return result, e
+		}
+	}
+
+	// 返回结果。
+	// Return the result.
+	return result, nil
+}
+
+// Cancel 是 future 的一个方法，它取消 Future：等待方立即得到 ErrorFutureCancelled，事件不会再冒泡到父级主题。
+// 已经提交到 pipeline 中的事件仍然会被处理。如果分发已经结束，Cancel 不做任何事情。
+// Cancel is a method of future that cancels the Future: the waiter gets ErrorFutureCancelled immediately, and the event no longer bubbles up to parent topics.
+// Events that have already been submitted to the pipeline are still processed. If the dispatch has already ended, Cancel does nothing.
+func (f *future) Cancel() {
+	f.once.Do(func() {
+		f.cancelled.Store(true)
+		if f.onCancel != nil {
+			f.onCancel()
+		}
+		close(f.done)
+	})
+}
+
+// wait 是 future 的一个方法，它阻塞直到分发结束或者 ctx 结束。
+// wait is a method of future that blocks until the dispatch ends or ctx is done.
+func (f *future) wait(ctx context.Context) (any, error) {
+	select {
+	case <-f.done:
+		return f.Result()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
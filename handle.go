@@ -1,7 +1,12 @@
 package events
 
-// handleFuncs 是一个结构体，它包含两个字段：origFunc 和 wrapFunc，都是 MessageHandleFunc 类型的函数。
-// handleFuncs is a structure that contains two fields: origFunc and wrapFunc, both of which are functions of type MessageHandleFunc.
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// handleFuncs 是一个结构体，它包含三个字段：origFunc，wrapFunc 和 once。
+// handleFuncs is a structure that contains three fields: origFunc, wrapFunc, and once.
 type handleFuncs struct {
 	// origFunc 是原始的消息处理函数。
 	// origFunc is the original message handling function.
@@ -10,6 +15,10 @@ type handleFuncs struct {
 	// wrapFunc 是包装后的消息处理函数。
 	// wrapFunc is the wrapped message handling function.
 	wrapFunc MessageHandleFunc
+
+	// once 是一个指向 sync.Once 的原子指针，只有只执行一次的处理函数才会设置它。
+	// once is an atomic pointer to sync.Once, which is only set for handling functions that are executed only once.
+	once atomic.Pointer[sync.Once]
 }
 
 // newHandleFuncs 是一个函数，它返回一个新的 handleFuncs 实例。
@@ -41,3 +50,25 @@ func (h *handleFuncs) SetWrapMsgHandleFunc(fn MessageHandleFunc) {
 func (h *handleFuncs) GetWrapMsgHandleFunc() MessageHandleFunc {
 	return h.wrapFunc
 }
+
+// SetOnce 是 handleFuncs 的一个方法，它设置 once 字段的值。
+// SetOnce is a method of handleFuncs that sets the value of the once field.
+func (h *handleFuncs) SetOnce(once *sync.Once) {
+	h.once.Store(once)
+}
+
+// GetOnce 是 handleFuncs 的一个方法，它返回 once 字段的值。
+// GetOnce is a method of handleFuncs that returns the value of the once field.
+func (h *handleFuncs) GetOnce() *sync.Once {
+	return h.once.Load()
+}
+
+// ResetOnce 是 handleFuncs 的一个方法，如果处理函数只执行一次，它会重置执行状态，以便可以再次执行。
+// ResetOnce is a method of handleFuncs that resets the execution state if the handling function is executed only once, so that it can be executed again.
+func (h *handleFuncs) ResetOnce() {
+	// 只有设置了 once 的处理函数才需要重置。
+	// Only handling functions with once set need to be reset.
+	if h.once.Load() != nil {
+		h.once.Store(&sync.Once{})
+	}
+}
@@ -1,11 +1,26 @@
 package events
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // MessageHandleFunc 是一个函数类型，它接受任何类型的消息，并返回任何类型的结果和一个错误。
 // MessageHandleFunc is a function type that takes a message of any type and returns a result of any type and an error.
 type MessageHandleFunc = func(msg any) (any, error)
 
+// ContextHandleFunc 是一个函数类型，它接受一个上下文和任何类型的消息，并返回任何类型的结果和一个错误。
+// 上下文在发出方的上下文结束，或者 EventEmitter 停止时被取消，并携带发出方上下文中的值。
+// ContextHandleFunc is a function type that takes a context and a message of any type and returns a result of any type and an error.
+// The context is cancelled when the emitter side's context is done or the EventEmitter is stopped, and carries the values of the emitter side's context.
+type ContextHandleFunc = func(ctx context.Context, msg any) (any, error)
+
+// EnvelopeHandleFunc 是一个函数类型，它接受一个 Envelope，并返回任何类型的结果和一个错误。
+// 通过 Envelope 可以获取事件的标识、时间、执行次数、头部和上下文。
+// EnvelopeHandleFunc is a function type that takes an Envelope and returns a result of any type and an error.
+// The identifier, times, attempt number, headers, and context of the event can be obtained through the Envelope.
+type EnvelopeHandleFunc = func(env *Envelope) (any, error)
+
 // Pipeline 是一个接口，它定义了三个方法：SubmitWithFunc，SubmitAfterWithFunc 和 Stop。
 // Pipeline is an interface that defines three methods: SubmitWithFunc, SubmitAfterWithFunc, and Stop.
 type Pipeline = interface {
@@ -21,3 +36,95 @@ type Pipeline = interface {
 	// The Stop method stops the pipeline from running.
 	Stop()
 }
+
+// Subscription 是一个接口，它表示一次消息处理函数的注册，可以用来精确地取消这次注册。
+// Subscription is an interface that represents one registration of a message handling function and can be used to cancel exactly this registration.
+type Subscription = interface {
+	// Unsubscribe 方法只移除这次注册的消息处理函数，同一主题上的其他处理函数不受影响。
+	// The Unsubscribe method removes only the message handling function of this registration, other handling functions on the same topic are not affected.
+	Unsubscribe()
+
+	// Topic 方法返回这次注册的主题。
+	// The Topic method returns the topic of this registration.
+	Topic() string
+
+	// ID 方法返回这次注册在 EventEmitter 中的唯一标识。
+	// The ID method returns the unique identifier of this registration within the EventEmitter.
+	ID() uint64
+
+	// Active 方法返回这次注册是否仍然有效。
+	// The Active method returns whether this registration is still active.
+	Active() bool
+}
+
+// Future 是一个接口，它表示一次异步发出的事件的处理结果。
+// Future is an interface that represents the handling result of an asynchronously emitted event.
+type Future = interface {
+	// Done 方法返回一个通道，所有处理函数执行完毕或者 Future 被取消后这个通道会被关闭。
+	// The Done method returns a channel that is closed after all handling functions have finished or the Future is cancelled.
+	Done() <-chan struct{}
+
+	// Result 方法阻塞直到 Done 返回的通道被关闭，然后返回第一个处理函数的结果和第一个错误。
+	// The Result method blocks until the channel returned by Done is closed, and then returns the result of the first handling function and the first error.
+	Result() (any, error)
+
+	// Cancel 方法取消 Future，之后 Result 返回 ErrorFutureCancelled。
+	// The Cancel method cancels the Future, after which Result returns ErrorFutureCancelled.
+	Cancel()
+}
+
+// ScheduledEvent 是一个接口，它表示一个尚未到期的延迟事件，由 ScheduleAfter 和 EmitAt 返回。
+// ScheduledEvent is an interface that represents a delayed event that is not yet due, returned by ScheduleAfter and EmitAt.
+type ScheduledEvent = interface {
+	// ID 方法返回事件的 ID，与处理函数收到的 Envelope.ID 相同。
+	// The ID method returns the ID of the event, the same as the Envelope.ID received by the handling functions.
+	ID() string
+
+	// Topic 方法返回发出事件的主题。
+	// The Topic method returns the topic the event is emitted on.
+	Topic() string
+
+	// When 方法返回事件到期的时间。
+	// The When method returns the time the event is due.
+	When() time.Time
+
+	// Cancel 方法在事件到期之前取消事件，只有事件确实被取消时才返回 true。
+	// The Cancel method cancels the event before it is due, and returns true only when the event is actually cancelled.
+	Cancel() bool
+
+	// Reschedule 方法把事件的到期时间改为从现在开始的 delay 之后，事件已经到期或者被取消时返回 ErrorEventNotScheduled。
+	// The Reschedule method changes the due time of the event to delay after now, and returns ErrorEventNotScheduled when the event is already due or cancelled.
+	Reschedule(delay time.Duration) error
+}
+
+// Schedule 是一个接口，它表示一个周期发出事件的计划，由 EmitEvery 和 EmitCron 返回。
+// Schedule is an interface that represents a plan of emitting events periodically, returned by EmitEvery and EmitCron.
+type Schedule = interface {
+	// Topic 方法返回发出事件的主题。
+	// The Topic method returns the topic the events are emitted on.
+	Topic() string
+
+	// Next 方法返回下一次发出事件的时间，计划暂停、取消或者已经结束时返回零值。
+	// The Next method returns the time of the next emission, and the zero value when the schedule is paused, cancelled or has ended.
+	Next() time.Time
+
+	// Pause 方法暂停计划，暂停期间到期的事件不会发出。
+	// The Pause method pauses the schedule, events due while it is paused are not emitted.
+	Pause()
+
+	// Resume 方法恢复暂停的计划，从恢复的时间开始计算下一次到期的时间。
+	// The Resume method resumes the paused schedule, calculating the next due time from the time it is resumed.
+	Resume()
+
+	// Cancel 方法取消计划，之后不能再恢复。EventEmitter 关闭时所有计划都会被取消。
+	// The Cancel method cancels the schedule, it cannot be resumed afterwards. All schedules are cancelled when the EventEmitter is closed.
+	Cancel()
+
+	// Paused 方法返回计划是否已经暂停。
+	// The Paused method returns whether the schedule has been paused.
+	Paused() bool
+
+	// Active 方法返回计划是否仍然有效，即没有被取消。
+	// The Active method returns whether the schedule is still active, that is, it has not been cancelled.
+	Active() bool
+}
@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors 是一个映射，键是预定义的描述符，值是它们对应的 cron 表达式。
+// cronDescriptors is a map with the predefined descriptors as keys and their corresponding cron expressions as values.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronMonthNames 和 cronWeekdayNames 是月份和星期的英文缩写与数值的对应关系。
+// cronMonthNames and cronWeekdayNames are the mappings from the English abbreviations of months and weekdays to their values.
+var (
+	cronMonthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	cronWeekdayNames = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+)
+
+// cronField 是一个结构体，它描述 cron 表达式中一个字段的取值范围。
+// cronField is a structure that describes the value range of a field in a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+// cronFields 是按顺序排列的 cron 表达式的五个字段：分钟、小时、日、月和星期。
+// cronFields is the five fields of a cron expression in order: minute, hour, day of month, month and day of week.
+var cronFields = [...]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: cronMonthNames},
+	{name: "day of week", min: 0, max: 7, names: cronWeekdayNames},
+}
+
+// CronSchedule 是一个结构体，它是解析后的 cron 表达式，每个字段用位图记录匹配的值。
+// CronSchedule is a structure that is a parsed cron expression, each field records the matching values with a bitmap.
+type CronSchedule struct {
+	// minute、hour、dom、month 和 dow 是分钟、小时、日、月和星期的位图。
+	// minute, hour, dom, month and dow are the bitmaps of minute, hour, day of month, month and day of week.
+	minute, hour, dom, month, dow uint64
+
+	// domStar 和 dowStar 表示日和星期字段是否是 *。两者都有限制时，满足其中一个即可。
+	// domStar and dowStar indicate whether the day of month and day of week fields are *. When both are restricted, matching either one is enough.
+	domStar, dowStar bool
+
+	// location 是计算时间使用的时区。
+	// location is the time zone used to calculate times.
+	location *time.Location
+}
+
+// ParseCron 是一个函数，它解析标准的五字段 cron 表达式（分钟 小时 日 月 星期），也支持 @daily 等描述符。
+// 表达式可以以 CRON_TZ=<时区> 或 TZ=<时区> 开头指定时区，否则使用 loc。
+// ParseCron is a function that parses a standard five-field cron expression (minute hour day-of-month month day-of-week), descriptors such as @daily are supported as well.
+// The expression can start with CRON_TZ=<zone> or TZ=<zone> to specify the time zone, otherwise loc is used.
+func ParseCron(spec string, loc *time.Location) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	// 解析时区前缀。
+	// Parse the time zone prefix.
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("missing fields after time zone in %q", spec)
+		}
+		name := spec[strings.Index(spec, "=")+1 : i]
+		zone, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %v", name, err)
+		}
+		loc, spec = zone, strings.TrimSpace(spec[i:])
+	}
+
+	// 展开描述符。
+	// Expand the descriptors.
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown descriptor %q", spec)
+		}
+		spec = expr
+	}
+
+	// 检查字段的数量。
+	// Check the number of fields.
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields, got %d in %q", len(cronFields), len(fields), spec)
+	}
+
+	// 依次解析每个字段。
+	// Parse each field in turn.
+	var bits [len(cronFields)]uint64
+	for i, field := range fields {
+		b, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+
+	// 星期字段中的 7 和 0 都表示星期日。
+	// Both 7 and 0 in the day of week field mean Sunday.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	// 返回解析后的 cron 表达式。
+	// Return the parsed cron expression.
+	if loc == nil {
+		loc = time.Local
+	}
+	return &CronSchedule{
+		minute:   bits[0],
+		hour:     bits[1],
+		dom:      bits[2],
+		month:    bits[3],
+		dow:      bits[4],
+		domStar:  fields[2] == "*" || fields[2] == "?",
+		dowStar:  fields[4] == "*" || fields[4] == "?",
+		location: loc,
+	}, nil
+}
+
+// parse 是 cronField 的一个方法，它解析一个字段，支持 *、?、数值、名称、范围 a-b、步长 /n 和以逗号分隔的列表。
+// parse is a method of cronField that parses a field, supporting *, ?, values, names, ranges a-b, steps /n and comma-separated lists.
+func (f cronField) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		// 拆分范围和步长。
+		// Split the range and the step.
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		// 解析范围，带步长的单个值表示从这个值到最大值。
+		// Parse the range, a single value with a step means from this value to the maximum.
+		var low, high int
+		switch {
+		case rng == "*" || rng == "?":
+			low, high = f.min, f.max
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			var err error
+			if low, err = f.value(rng[:i]); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(rng[i+1:]); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if low, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			high = low
+			if step > 1 {
+				high = f.max
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+		}
+
+		// 设置范围内按步长选取的值。
+		// Set the values picked by the step within the range.
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value 是 cronField 的一个方法，它解析一个数值或者名称，并检查它是否在取值范围内。
+// value is a method of cronField that parses a value or a name and checks whether it is within the value range.
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value in %s field %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Location 是 CronSchedule 的一个方法，它返回计算时间使用的时区。
+// Location is a method of CronSchedule that returns the time zone used to calculate times.
+func (s *CronSchedule) Location() *time.Location {
+	return s.location
+}
+
+// Next 是 CronSchedule 的一个方法，它返回 t 之后第一个匹配的时间，精确到分钟。五年内没有匹配的时间时返回零值。
+// Next is a method of CronSchedule that returns the first matching time after t, accurate to the minute. The zero value is returned when there is no matching time within five years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	// 从 t 之后的下一分钟开始查找。
+	// Start looking from the next minute after t.
+	t = t.In(s.location)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.location).Add(time.Minute)
+	limit := t.Year() + 5
+
+	// 从月到分钟逐级查找，某一级不匹配时跳到这一级的下一个值，并把更低的级别重置为最小值。
+	// Look from month down to minute level by level, when a level does not match, jump to its next value and reset the lower levels to their minimum.
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			// 夏令时切换时，确保时间一定向前推进。
+			// Make sure the time always moves forward when daylight saving time changes.
+			if !next.After(t) {
+				next = t.Truncate(time.Hour).Add(time.Hour)
+			}
+			t = next
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	// 没有找到匹配的时间。
+	// No matching time was found.
+	return time.Time{}
+}
+
+// matchDay 是 CronSchedule 的一个方法，它判断日期是否与日和星期字段匹配。
+// matchDay is a method of CronSchedule that determines whether the date matches the day of month and day of week fields.
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+)
+
+// idPrefix 是进程启动时随机生成的事件标识前缀，用于区分不同进程生成的标识。
+// idPrefix is the event identifier prefix randomly generated at process startup, used to distinguish identifiers generated by different processes.
+var idPrefix = newIDPrefix()
+
+// idCounter 是一个原子计数器，用于生成进程内唯一的事件标识。
+// idCounter is an atomic counter used to generate event identifiers unique within the process.
+var idCounter atomic.Uint64
+
+// newIDPrefix 是一个函数，它返回一个随机的十六进制字符串前缀。
+// newIDPrefix is a function that returns a random hexadecimal string prefix.
+func newIDPrefix() string {
+	b := make([]byte, 8)
+
+	// 读取随机数失败时退化为固定前缀，标识仍然在进程内唯一。
+	// Fall back to a fixed prefix when reading random bytes fails, the identifiers are still unique within the process.
+	if _, err := rand.Read(b); err != nil {
+		return "0000000000000000"
+	}
+
+	// 返回十六进制编码的前缀。
+	// Return the hexadecimal encoded prefix.
+	return hex.EncodeToString(b)
+}
+
+// NewEventID 是一个函数，它返回一个新的唯一事件标识，格式为随机前缀加递增序号。
+// NewEventID is a function that returns a new unique event identifier, formatted as a random prefix followed by an increasing sequence number.
+func NewEventID() string {
+	return idPrefix + "-" + strconv.FormatUint(idCounter.Add(1), 16)
+}
@@ -1,11 +1,13 @@
 package internal
 
 import (
+	"context"
 	"sync"
+	"time"
 )
 
-// Event 是一个结构体，它有三个字段：topic，data 和 value。
-// Event is a structure that has three fields: topic, data, and value.
+// Event 是一个结构体，它保存了一次分发给处理函数的事件的主题、数据、上下文和元数据。
+// Event is a structure that holds the topic, data, context, and metadata of an event dispatched to a handling function.
 type Event struct {
 	// topic 是一个字符串，表示事件的主题。
 	// topic is a string that represents the topic of the event.
@@ -18,6 +20,38 @@ type Event struct {
 	// value 是一个 int64 类型，表示事件的值。
 	// value is of type int64, representing the value of the event.
 	value int64
+
+	// ctx 是事件的上下文，处理函数通过它观察发出方或者 EventEmitter 的取消。
+	// ctx is the context of the event, through which handling functions observe the cancellation of the emitter side or of the EventEmitter.
+	ctx context.Context
+
+	// id 是事件的唯一标识，同一次发出的所有处理函数共享同一个标识。
+	// id is the unique identifier of the event, shared by all handling functions of the same emission.
+	id string
+
+	// timestamp 是事件发出的时间。
+	// timestamp is the time the event was emitted.
+	timestamp time.Time
+
+	// scheduledAt 是事件计划被处理的时间。
+	// scheduledAt is the time the event is scheduled to be handled.
+	scheduledAt time.Time
+
+	// enqueuedAt 是事件提交给 pipeline 或者重试到期的时间，用于计算排队等待的时间。
+	// enqueuedAt is the time the event was submitted to the pipeline or its retry became due, used to calculate the queue wait time.
+	enqueuedAt time.Time
+
+	// attempt 是处理函数的第几次执行，从 1 开始。
+	// attempt is the number of the execution of the handling function, starting from 1.
+	attempt int
+
+	// headers 是事件的字符串头部。
+	// headers is the string headers of the event.
+	headers map[string]string
+
+	// doneFunc 是一个函数，在事件处理完成后使用处理结果和错误调用。
+	// doneFunc is a function that is called with the result and error after the event has been handled.
+	doneFunc func(result any, err error)
 }
 
 // NewEvent 是一个函数，它返回一个新的 Event 实例。
@@ -44,6 +78,54 @@ func (e *Event) SetValue(value int64) {
 	e.value = value
 }
 
+// SetContext 是一个方法，它设置 Event 的 ctx 字段。
+// SetContext is a method that sets the ctx field of Event.
+func (e *Event) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// SetID 是一个方法，它设置 Event 的 id 字段。
+// SetID is a method that sets the id field of Event.
+func (e *Event) SetID(id string) {
+	e.id = id
+}
+
+// SetTimestamp 是一个方法，它设置 Event 的 timestamp 字段。
+// SetTimestamp is a method that sets the timestamp field of Event.
+func (e *Event) SetTimestamp(t time.Time) {
+	e.timestamp = t
+}
+
+// SetScheduledAt 是一个方法，它设置 Event 的 scheduledAt 字段。
+// SetScheduledAt is a method that sets the scheduledAt field of Event.
+func (e *Event) SetScheduledAt(t time.Time) {
+	e.scheduledAt = t
+}
+
+// SetEnqueuedAt 是一个方法，它设置 Event 的 enqueuedAt 字段。
+// SetEnqueuedAt is a method that sets the enqueuedAt field of Event.
+func (e *Event) SetEnqueuedAt(t time.Time) {
+	e.enqueuedAt = t
+}
+
+// SetAttempt 是一个方法，它设置 Event 的 attempt 字段。
+// SetAttempt is a method that sets the attempt field of Event.
+func (e *Event) SetAttempt(attempt int) {
+	e.attempt = attempt
+}
+
+// SetHeaders 是一个方法，它设置 Event 的 headers 字段。
+// SetHeaders is a method that sets the headers field of Event.
+func (e *Event) SetHeaders(headers map[string]string) {
+	e.headers = headers
+}
+
+// SetDoneFunc 是一个方法，它设置 Event 的 doneFunc 字段。
+// SetDoneFunc is a method that sets the doneFunc field of Event.
+func (e *Event) SetDoneFunc(fn func(result any, err error)) {
+	e.doneFunc = fn
+}
+
 // GetTopic 是一个方法，它返回 Event 的 topic 字段。
 // GetTopic is a method that returns the topic field of Event.
 func (e *Event) GetTopic() string {
@@ -62,6 +144,59 @@ func (e *Event) GetValue() int64 {
 	return e.value
 }
 
+// GetContext 是一个方法，它返回 Event 的 ctx 字段，如果没有设置则返回 context.Background()。
+// GetContext is a method that returns the ctx field of Event, or context.Background() if it is not set.
+func (e *Event) GetContext() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// GetID 是一个方法，它返回 Event 的 id 字段。
+// GetID is a method that returns the id field of Event.
+func (e *Event) GetID() string {
+	return e.id
+}
+
+// GetTimestamp 是一个方法，它返回 Event 的 timestamp 字段。
+// GetTimestamp is a method that returns the timestamp field of Event.
+func (e *Event) GetTimestamp() time.Time {
+	return e.timestamp
+}
+
+// GetScheduledAt 是一个方法，它返回 Event 的 scheduledAt 字段。
+// GetScheduledAt is a method that returns the scheduledAt field of Event.
+func (e *Event) GetScheduledAt() time.Time {
+	return e.scheduledAt
+}
+
+// GetEnqueuedAt 是一个方法，它返回 Event 的 enqueuedAt 字段。
+// GetEnqueuedAt is a method that returns the enqueuedAt field of Event.
+func (e *Event) GetEnqueuedAt() time.Time {
+	return e.enqueuedAt
+}
+
+// GetAttempt 是一个方法，它返回 Event 的 attempt 字段。
+// GetAttempt is a method that returns the attempt field of Event.
+func (e *Event) GetAttempt() int {
+	return e.attempt
+}
+
+// GetHeaders 是一个方法，它返回 Event 的 headers 字段。
+// GetHeaders is a method that returns the headers field of Event.
+func (e *Event) GetHeaders() map[string]string {
+	return e.headers
+}
+
+// Done 是一个方法，如果设置了 doneFunc，它使用处理结果和错误调用 doneFunc。
+// Done is a method that calls doneFunc with the result and error if doneFunc is set.
+func (e *Event) Done(result any, err error) {
+	if e.doneFunc != nil {
+		e.doneFunc(result, err)
+	}
+}
+
 // Reset 是 Event 结构体的一个方法，它将 Event 的所有字段重置为其零值。
 // Reset is a method of the Event structure that resets all fields of Event to their zero values.
 func (e *Event) Reset() {
@@ -76,6 +211,23 @@ func (e *Event) Reset() {
 	// 将 value 字段重置为 0。
 	// Reset the value field to 0.
 	e.value = 0
+
+	// 将 ctx 字段重置为 nil。
+	// Reset the ctx field to nil.
+	e.ctx = nil
+
+	// 将元数据字段重置为零值。
+	// Reset the metadata fields to their zero values.
+	e.id = ""
+	e.timestamp = time.Time{}
+	e.scheduledAt = time.Time{}
+	e.enqueuedAt = time.Time{}
+	e.attempt = 0
+	e.headers = nil
+
+	// 将 doneFunc 字段重置为 nil。
+	// Reset the doneFunc field to nil.
+	e.doneFunc = nil
 }
 
 // EventPool 是一个结构体，它包含一个同步池。
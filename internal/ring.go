@@ -0,0 +1,66 @@
+package internal
+
+// Ring 是一个结构体，它是一个可以增长的环形缓冲区，按加入的顺序保存元素，可以从头部移除最早的元素。
+// Ring is a structure that is a growable ring buffer, keeping elements in the order they were added, and the earliest element can be removed from the front.
+type Ring[T any] struct {
+	// items 是保存元素的底层数组。
+	// items is the underlying array holding the elements.
+	items []T
+
+	// head 是最早的元素在 items 中的位置。
+	// head is the position of the earliest element in items.
+	head int
+
+	// count 是元素的数量。
+	// count is the number of elements.
+	count int
+}
+
+// NewRing 是一个函数，它返回一个新的 Ring 实例，capacity 是初始容量。
+// NewRing is a function that returns a new instance of Ring, capacity is the initial capacity.
+func NewRing[T any](capacity int) *Ring[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring[T]{items: make([]T, capacity)}
+}
+
+// Len 是 Ring 的一个方法，它返回元素的数量。
+// Len is a method of Ring that returns the number of elements.
+func (r *Ring[T]) Len() int {
+	return r.count
+}
+
+// At 是 Ring 的一个方法，它返回从最早的元素开始的第 i 个元素。
+// At is a method of Ring that returns the i-th element starting from the earliest one.
+func (r *Ring[T]) At(i int) T {
+	return r.items[(r.head+i)%len(r.items)]
+}
+
+// Push 是 Ring 的一个方法，它在末尾加入一个元素，缓冲区已满时容量翻倍。
+// Push is a method of Ring that adds an element at the end, and doubles the capacity when the buffer is full.
+func (r *Ring[T]) Push(item T) {
+	if r.count == len(r.items) {
+		items := make([]T, len(r.items)*2)
+		for i := 0; i < r.count; i++ {
+			items[i] = r.At(i)
+		}
+		r.items, r.head = items, 0
+	}
+	r.items[(r.head+r.count)%len(r.items)] = item
+	r.count++
+}
+
+// PopFront 是 Ring 的一个方法，它移除并返回最早的元素，缓冲区为空时返回 false。
+// PopFront is a method of Ring that removes and returns the earliest element, and returns false when the buffer is empty.
+func (r *Ring[T]) PopFront() (T, bool) {
+	var zero T
+	if r.count == 0 {
+		return zero, false
+	}
+	item := r.items[r.head]
+	r.items[r.head] = zero
+	r.head = (r.head + 1) % len(r.items)
+	r.count--
+	return item, true
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracker 是一个结构体，它记录正在执行的任务数量，并允许在等待所有任务完成时使用上下文设置超时。
+// Tracker is a structure that records the number of running jobs and allows a context to bound the wait for all jobs to complete.
+type Tracker struct {
+	// lock 是 sync.Mutex 类型，用于保护 count 和 idle 的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to count and idle.
+	lock sync.Mutex
+
+	// count 是正在执行的任务数量。
+	// count is the number of running jobs.
+	count int64
+
+	// idle 是一个通道，任务数量降为 0 时被关闭，只有存在等待者时才不为 nil。
+	// idle is a channel that is closed when the number of jobs drops to 0, it is not nil only when there are waiters.
+	idle chan struct{}
+}
+
+// NewTracker 是一个函数，它返回一个新的 Tracker 实例。
+// NewTracker is a function that returns a new instance of Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Add 是 Tracker 的一个方法，它将任务数量增加 n，n 可以为负数。
+// Add is a method of Tracker that increases the number of jobs by n, n can be negative.
+func (t *Tracker) Add(n int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// 更新任务数量，降为 0 时唤醒所有等待者。
+	// Update the number of jobs, and wake up all waiters when it drops to 0.
+	t.count += n
+	if t.count <= 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Done 是 Tracker 的一个方法，它将任务数量减少 1。
+// Done is a method of Tracker that decreases the number of jobs by 1.
+func (t *Tracker) Done() {
+	t.Add(-1)
+}
+
+// Count 是 Tracker 的一个方法，它返回正在执行的任务数量。
+// Count is a method of Tracker that returns the number of running jobs.
+func (t *Tracker) Count() int64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.count
+}
+
+// Wait 是 Tracker 的一个方法，它阻塞直到任务数量降为 0 或者 ctx 结束，ctx 先结束时返回 ctx 的错误。
+// Wait is a method of Tracker that blocks until the number of jobs drops to 0 or ctx is done, and returns the error of ctx if ctx is done first.
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.lock.Lock()
+
+	// 如果没有正在执行的任务，直接返回。
+	// If there are no running jobs, return directly.
+	if t.count <= 0 {
+		t.lock.Unlock()
+		return nil
+	}
+
+	// 获取任务数量降为 0 时被关闭的通道。
+	// Get the channel that is closed when the number of jobs drops to 0.
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.lock.Unlock()
+
+	// 等待所有任务完成或者 ctx 结束。
+	// Wait for all jobs to complete or ctx to be done.
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
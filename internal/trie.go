@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// SingleLevelWildcard 是一个常量，表示匹配恰好一级主题的通配符。
+	// SingleLevelWildcard is a constant representing the wildcard that matches exactly one topic level.
+	SingleLevelWildcard = "+"
+
+	// SingleLevelWildcardAlias 是一个常量，它是 SingleLevelWildcard 的别名。
+	// SingleLevelWildcardAlias is a constant that is an alias of SingleLevelWildcard.
+	SingleLevelWildcardAlias = "*"
+
+	// MultiLevelWildcard 是一个常量，表示匹配零级或多级主题的通配符，只能出现在模式的最后一级。
+	// MultiLevelWildcard is a constant representing the wildcard that matches zero or more topic levels, it can only appear at the last level of a pattern.
+	MultiLevelWildcard = "#"
+)
+
+// 主题级别的类型，数值越小越具体。
+// Kinds of topic levels, the smaller the value, the more specific.
+const (
+	levelLiteral = iota
+	levelSingle
+	levelMulti
+)
+
+// levelKind 是一个函数，它返回主题级别的类型。
+// levelKind is a function that returns the kind of a topic level.
+func levelKind(level string) int {
+	switch level {
+	case SingleLevelWildcard, SingleLevelWildcardAlias:
+		return levelSingle
+	case MultiLevelWildcard:
+		return levelMulti
+	default:
+		return levelLiteral
+	}
+}
+
+// IsWildcardPattern 是一个函数，它判断主题中是否有某一级是通配符。
+// IsWildcardPattern is a function that determines whether any level of the topic is a wildcard.
+func IsWildcardPattern(topic, separator string) bool {
+	for _, level := range strings.Split(topic, separator) {
+		if levelKind(level) != levelLiteral {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPattern 是一个函数，它判断主题模式是否有效：不能为空，不能包含空的级别，多级通配符只能出现在最后一级。
+// IsValidPattern is a function that determines whether a topic pattern is valid: it cannot be empty or contain empty levels, and the multi-level wildcard can only appear at the last level.
+func IsValidPattern(pattern, separator string) bool {
+	levels := strings.Split(pattern, separator)
+	for i, level := range levels {
+		if level == "" || (level == MultiLevelWildcard && i != len(levels)-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// ComparePatterns 是一个函数，它按具体程度比较两个主题模式。
+// 逐级比较，字面量优先于单级通配符，单级通配符优先于多级通配符；完全相同时按字典序比较。
+// 返回值小于 0 表示 a 优先于 b。
+// ComparePatterns is a function that compares two topic patterns by specificity.
+// Levels are compared one by one: literals come before single-level wildcards, which come before multi-level wildcards; ties are broken lexically.
+// A return value less than 0 means a takes precedence over b.
+func ComparePatterns(a, b, separator string) int {
+	la, lb := strings.Split(a, separator), strings.Split(b, separator)
+
+	// 逐级比较两个模式的级别类型。
+	// Compare the level kinds of the two patterns level by level.
+	for i := 0; i < len(la) && i < len(lb); i++ {
+		if ka, kb := levelKind(la[i]), levelKind(lb[i]); ka != kb {
+			return ka - kb
+		}
+	}
+
+	// 级别更多的模式更具体。
+	// The pattern with more levels is more specific.
+	if len(la) != len(lb) {
+		return len(lb) - len(la)
+	}
+
+	// 按字典序比较。
+	// Compare lexically.
+	return strings.Compare(a, b)
+}
+
+// trieNode 是一个结构体，表示主题前缀树中的一个节点。
+// trieNode is a structure representing a node in the topic trie.
+type trieNode struct {
+	// children 是子节点的映射，键是主题的一级。
+	// children is a map of child nodes, keyed by one topic level.
+	children map[string]*trieNode
+
+	// pattern 是在这个节点结束的主题模式，如果没有则为空字符串。
+	// pattern is the topic pattern ending at this node, or an empty string if there is none.
+	pattern string
+}
+
+// newTrieNode 是一个函数，它返回一个新的 trieNode 实例。
+// newTrieNode is a function that returns a new instance of trieNode.
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[string]*trieNode)}
+}
+
+// TopicTrie 是一个结构体，它是按主题级别组织的前缀树，用于快速查找与主题匹配的通配符模式。
+// TopicTrie 不是并发安全的，调用方需要自己加锁。
+// TopicTrie is a structure that is a prefix tree organized by topic levels, used to quickly find the wildcard patterns that match a topic.
+// TopicTrie is not safe for concurrent use, the caller needs to hold its own lock.
+type TopicTrie struct {
+	// root 是前缀树的根节点。
+	// root is the root node of the trie.
+	root *trieNode
+
+	// separator 是主题级别之间的分隔符。
+	// separator is the separator between topic levels.
+	separator string
+}
+
+// NewTopicTrie 是一个函数，它返回一个新的 TopicTrie 实例。
+// NewTopicTrie is a function that returns a new instance of TopicTrie.
+func NewTopicTrie(separator string) *TopicTrie {
+	return &TopicTrie{root: newTrieNode(), separator: separator}
+}
+
+// Insert 是 TopicTrie 的一个方法，它将一个主题模式插入到前缀树中。
+// Insert is a method of TopicTrie that inserts a topic pattern into the trie.
+func (t *TopicTrie) Insert(pattern string) {
+	node := t.root
+
+	// 逐级查找或创建节点。
+	// Find or create nodes level by level.
+	for _, level := range strings.Split(pattern, t.separator) {
+		child, ok := node.children[level]
+		if !ok {
+			child = newTrieNode()
+			node.children[level] = child
+		}
+		node = child
+	}
+
+	// 在最后一个节点上记录主题模式。
+	// Record the topic pattern on the last node.
+	node.pattern = pattern
+}
+
+// Remove 是 TopicTrie 的一个方法，它从前缀树中移除一个主题模式，并清理不再使用的节点。
+// Remove is a method of TopicTrie that removes a topic pattern from the trie and cleans up nodes that are no longer used.
+func (t *TopicTrie) Remove(pattern string) {
+	levels := strings.Split(pattern, t.separator)
+
+	// 记录经过的节点，以便之后自底向上清理。
+	// Record the visited nodes so that they can be cleaned up from the bottom up later.
+	path := make([]*trieNode, 0, len(levels)+1)
+	node := t.root
+	path = append(path, node)
+	for _, level := range levels {
+		child, ok := node.children[level]
+		if !ok {
+			return
+		}
+		node = child
+		path = append(path, node)
+	}
+
+	// 清除主题模式。
+	// Clear the topic pattern.
+	node.pattern = ""
+
+	// 自底向上删除既没有主题模式也没有子节点的节点。
+	// Delete nodes that have neither a topic pattern nor child nodes from the bottom up.
+	for i := len(levels); i > 0; i-- {
+		n := path[i]
+		if n.pattern != "" || len(n.children) > 0 {
+			break
+		}
+		delete(path[i-1].children, levels[i-1])
+	}
+}
+
+// Empty 是 TopicTrie 的一个方法，它判断前缀树中是否没有任何主题模式。
+// Empty is a method of TopicTrie that determines whether there is no topic pattern in the trie.
+func (t *TopicTrie) Empty() bool {
+	return len(t.root.children) == 0
+}
+
+// Match 是 TopicTrie 的一个方法，它返回与主题匹配的所有主题模式，按具体程度从高到低排序。
+// Match is a method of TopicTrie that returns all topic patterns that match the topic, sorted from the most specific to the least specific.
+func (t *TopicTrie) Match(topic string) []string {
+	var patterns []string
+	t.match(t.root, strings.Split(topic, t.separator), &patterns)
+
+	// 按具体程度排序，保证优先级稳定。
+	// Sort by specificity to keep the precedence stable.
+	sort.Slice(patterns, func(i, j int) bool {
+		return ComparePatterns(patterns[i], patterns[j], t.separator) < 0
+	})
+
+	return patterns
+}
+
+// match 是 TopicTrie 的一个方法，它递归地收集与剩余主题级别匹配的主题模式。
+// match is a method of TopicTrie that recursively collects the topic patterns that match the remaining topic levels.
+func (t *TopicTrie) match(node *trieNode, levels []string, patterns *[]string) {
+	// 多级通配符匹配剩余的所有级别，包括零级。
+	// The multi-level wildcard matches all remaining levels, including zero levels.
+	if child, ok := node.children[MultiLevelWildcard]; ok && child.pattern != "" {
+		*patterns = append(*patterns, child.pattern)
+	}
+
+	// 所有级别都已匹配，记录在这个节点结束的主题模式。
+	// All levels have been matched, record the topic pattern ending at this node.
+	if len(levels) == 0 {
+		if node.pattern != "" {
+			*patterns = append(*patterns, node.pattern)
+		}
+		return
+	}
+
+	// 继续匹配字面量级别和单级通配符。
+	// Continue matching the literal level and the single-level wildcards.
+	for i, key := range [...]string{levels[0], SingleLevelWildcard, SingleLevelWildcardAlias} {
+		// 如果主题级别本身就是通配符，避免重复匹配同一个子节点。
+		// If the topic level itself is a wildcard, avoid matching the same child node twice.
+		if i > 0 && key == levels[0] {
+			continue
+		}
+		if child, ok := node.children[key]; ok {
+			t.match(child, levels[1:], patterns)
+		}
+	}
+}
+
+// IsValidTopic 是一个函数，它判断主题名称是否有效：不能为空，不能包含空的级别，也不能包含通配符。
+// IsValidTopic is a function that determines whether a topic name is valid: it cannot be empty, contain empty levels, or contain wildcards.
+func IsValidTopic(topic, separator string) bool {
+	for _, level := range strings.Split(topic, separator) {
+		if level == "" || levelKind(level) != levelLiteral {
+			return false
+		}
+	}
+	return true
+}
+
+// ParentTopics 是一个函数，它按从近到远的顺序返回主题的所有父级主题。
+// ParentTopics is a function that returns all parent topics of the topic, from the nearest to the farthest.
+func ParentTopics(topic, separator string) []string {
+	var parents []string
+	for i := strings.LastIndex(topic, separator); i > 0; i = strings.LastIndex(topic, separator) {
+		topic = topic[:i]
+		parents = append(parents, topic)
+	}
+	return parents
+}
+
+// MatchPattern 是一个函数，它判断主题是否与主题模式匹配。
+// MatchPattern is a function that determines whether the topic matches the topic pattern.
+func MatchPattern(pattern, topic, separator string) bool {
+	patternLevels, topicLevels := strings.Split(pattern, separator), strings.Split(topic, separator)
+	for i, level := range patternLevels {
+		switch levelKind(level) {
+		case levelMulti:
+			// 多级通配符匹配剩余的所有级别，包括零级。
+			// The multi-level wildcard matches all remaining levels, including zero levels.
+			return true
+		case levelSingle:
+			// 单级通配符匹配任意一级。
+			// The single-level wildcard matches any one level.
+			if i >= len(topicLevels) {
+				return false
+			}
+		default:
+			// 字面量级别必须完全相同。
+			// The literal level must be exactly the same.
+			if i >= len(topicLevels) || topicLevels[i] != level {
+				return false
+			}
+		}
+	}
+	return len(patternLevels) == len(topicLevels)
+}
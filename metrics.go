@@ -0,0 +1,326 @@
+package events
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Outcome 是一个类型，它表示处理函数一次执行的结果。
+// Outcome is a type that represents the result of one execution of a handling function.
+type Outcome int
+
+const (
+	// OutcomeSucceeded 表示处理函数执行成功，返回 ErrorStopPropagation 和 ErrorTopicExecutedOnce 也视为成功。
+	// OutcomeSucceeded indicates that the handling function succeeded, returning ErrorStopPropagation and ErrorTopicExecutedOnce also counts as success.
+	OutcomeSucceeded Outcome = iota
+
+	// OutcomeFailed 表示处理函数返回了错误。
+	// OutcomeFailed indicates that the handling function returned an error.
+	OutcomeFailed
+
+	// OutcomePanicked 表示处理函数发生了 panic。
+	// OutcomePanicked indicates that the handling function panicked.
+	OutcomePanicked
+)
+
+// String 是 Outcome 的一个方法，它返回结果的名称，可以用作指标的标签。
+// String is a method of Outcome that returns the name of the result, which can be used as a metric label.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSucceeded:
+		return "succeeded"
+	case OutcomeFailed:
+		return "failed"
+	case OutcomePanicked:
+		return "panicked"
+	default:
+		return "unknown"
+	}
+}
+
+// OutcomeOf 是一个函数，它根据处理函数返回的错误判断执行的结果。MetricsRecorder 收到的结果和扩展模块（例如链路追踪）记录的状态都使用它分类。
+// OutcomeOf is a function that determines the result of an execution from the error returned by the handling function. Both the results received by MetricsRecorder and the statuses recorded by extensions such as tracing are classified with it.
+func OutcomeOf(err error) Outcome {
+	switch {
+	case errors.Is(err, ErrorHandlerPanicked):
+		return OutcomePanicked
+	case isHandlingFailure(err), isInterrupted(err):
+		return OutcomeFailed
+	default:
+		return OutcomeSucceeded
+	}
+}
+
+// MetricsRecorder 是一个接口，EventEmitter 在发出事件和执行处理函数时调用它记录指标，所有方法都需要是并发安全的。
+// 每次发出要么记录为 OnEmitted，要么记录为 OnRejected；每个提交给 pipeline 的处理函数任务记录一次 OnSubmitted；
+// 处理函数的每一次执行（包括重试）记录一次 OnStarted 和一次 OnHandled。
+// MetricsRecorder is an interface that the EventEmitter calls to record metrics when emitting events and executing handling functions, all methods need to be safe for concurrent use.
+// Every emission is recorded either as OnEmitted or as OnRejected; every handling function job submitted to the pipeline is recorded once by OnSubmitted;
+// every execution of a handling function (including retries) is recorded once by OnStarted and once by OnHandled.
+type MetricsRecorder = interface {
+	// OnEmitted 在事件被接受时调用。
+	// OnEmitted is called when an event has been accepted.
+	OnEmitted(topic string)
+
+	// OnRejected 在发出事件返回错误时调用，例如主题不存在或者 EventEmitter 已经关闭；事件冒泡到父主题时提交失败也会调用它。
+	// OnRejected is called when emitting an event returns an error, for example the topic does not exist or the EventEmitter is closed; it is also called when the submission fails while the event bubbles up to a parent topic.
+	OnRejected(topic string, err error)
+
+	// OnSubmitted 在一个处理函数任务提交给 pipeline 之后调用。
+	// OnSubmitted is called after a handling function job has been submitted to the pipeline.
+	OnSubmitted(topic string)
+
+	// OnStarted 在处理函数开始执行时调用，wait 是任务在 pipeline 中排队等待的时间。
+	// OnStarted is called when a handling function starts, wait is the time the job waited in the queue of the pipeline.
+	OnStarted(topic string, wait time.Duration)
+
+	// OnHandled 在处理函数执行结束时调用，duration 是处理函数执行的时间。
+	// OnHandled is called when a handling function has finished, duration is the time the handling function took.
+	OnHandled(topic string, outcome Outcome, duration time.Duration)
+}
+
+// emptyMetricsRecorder 是一个结构体，它实现了一个什么也不做的 MetricsRecorder。
+// emptyMetricsRecorder is a structure that implements a MetricsRecorder doing nothing.
+type emptyMetricsRecorder struct{}
+
+// OnEmitted 是 emptyMetricsRecorder 的一个方法，它什么也不做。
+// OnEmitted is a method of emptyMetricsRecorder that does nothing.
+func (emptyMetricsRecorder) OnEmitted(string) {}
+
+// OnRejected 是 emptyMetricsRecorder 的一个方法，它什么也不做。
+// OnRejected is a method of emptyMetricsRecorder that does nothing.
+func (emptyMetricsRecorder) OnRejected(string, error) {}
+
+// OnSubmitted 是 emptyMetricsRecorder 的一个方法，它什么也不做。
+// OnSubmitted is a method of emptyMetricsRecorder that does nothing.
+func (emptyMetricsRecorder) OnSubmitted(string) {}
+
+// OnStarted 是 emptyMetricsRecorder 的一个方法，它什么也不做。
+// OnStarted is a method of emptyMetricsRecorder that does nothing.
+func (emptyMetricsRecorder) OnStarted(string, time.Duration) {}
+
+// OnHandled 是 emptyMetricsRecorder 的一个方法，它什么也不做。
+// OnHandled is a method of emptyMetricsRecorder that does nothing.
+func (emptyMetricsRecorder) OnHandled(string, Outcome, time.Duration) {}
+
+// NewEmptyMetricsRecorder 是一个函数，它返回一个什么也不做的 MetricsRecorder。
+// NewEmptyMetricsRecorder is a function that returns a MetricsRecorder that does nothing.
+func NewEmptyMetricsRecorder() MetricsRecorder {
+	return emptyMetricsRecorder{}
+}
+
+// DefaultLatencyBuckets 是 MemoryMetrics 默认使用的延迟直方图的桶上界。
+// DefaultLatencyBuckets is the upper bounds of the buckets of the latency histograms used by MemoryMetrics by default.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyBucket 是一个结构体，它表示延迟直方图的一个桶。
+// LatencyBucket is a structure that represents a bucket of a latency histogram.
+type LatencyBucket struct {
+	// UpperBound 是桶的上界。
+	// UpperBound is the upper bound of the bucket.
+	UpperBound time.Duration
+
+	// Count 是不大于上界的观测值的累计数量。
+	// Count is the cumulative number of observations not greater than the upper bound.
+	Count uint64
+}
+
+// LatencyStats 是一个结构体，它是一个延迟直方图的快照。
+// LatencyStats is a structure that is a snapshot of a latency histogram.
+type LatencyStats struct {
+	// Count 是观测值的数量。
+	// Count is the number of observations.
+	Count uint64
+
+	// Sum 是观测值的总和。
+	// Sum is the sum of the observations.
+	Sum time.Duration
+
+	// Min 和 Max 是最小和最大的观测值。
+	// Min and Max are the smallest and largest observations.
+	Min, Max time.Duration
+
+	// Buckets 是按上界升序排列的累计桶。
+	// Buckets is the cumulative buckets sorted by upper bound in ascending order.
+	Buckets []LatencyBucket
+}
+
+// Mean 是 LatencyStats 的一个方法，它返回观测值的平均值，没有观测值时返回 0。
+// Mean is a method of LatencyStats that returns the mean of the observations, or 0 when there are none.
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// observe 是 LatencyStats 的一个方法，它记录一个观测值。
+// observe is a method of LatencyStats that records an observation.
+func (s *LatencyStats) observe(d time.Duration) {
+	if s.Count == 0 || d < s.Min {
+		s.Min = d
+	}
+	if d > s.Max {
+		s.Max = d
+	}
+	s.Count++
+	s.Sum += d
+	for i := range s.Buckets {
+		if d <= s.Buckets[i].UpperBound {
+			s.Buckets[i].Count++
+		}
+	}
+}
+
+// clone 是 LatencyStats 的一个方法，它返回一个不与原值共享桶的副本。
+// clone is a method of LatencyStats that returns a copy not sharing the buckets with the original.
+func (s LatencyStats) clone() LatencyStats {
+	s.Buckets = append([]LatencyBucket(nil), s.Buckets...)
+	return s
+}
+
+// TopicStats 是一个结构体，它是一个主题的指标快照。
+// TopicStats is a structure that is a snapshot of the metrics of a topic.
+type TopicStats struct {
+	// Emitted、Rejected 是被接受和被拒绝的发出次数。
+	// Emitted and Rejected are the numbers of accepted and rejected emissions.
+	Emitted, Rejected uint64
+
+	// Submitted 是提交给 pipeline 的处理函数任务数量。
+	// Submitted is the number of handling function jobs submitted to the pipeline.
+	Submitted uint64
+
+	// Succeeded、Failed、Panicked 是处理函数各种执行结果的次数。
+	// Succeeded, Failed and Panicked are the numbers of each result of handling function executions.
+	Succeeded, Failed, Panicked uint64
+
+	// QueueWait 是任务排队等待时间的直方图。
+	// QueueWait is the histogram of the queue wait times of jobs.
+	QueueWait LatencyStats
+
+	// HandlerDuration 是处理函数执行时间的直方图。
+	// HandlerDuration is the histogram of the execution times of handling functions.
+	HandlerDuration LatencyStats
+}
+
+// MemoryMetrics 是一个结构体，它是一个在内存中按主题汇总指标的 MetricsRecorder。
+// MemoryMetrics is a structure that is a MetricsRecorder aggregating metrics per topic in memory.
+type MemoryMetrics struct {
+	// lock 是 sync.Mutex 类型，用于保护 topics 的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to topics.
+	lock sync.Mutex
+
+	// buckets 是延迟直方图的桶上界。
+	// buckets is the upper bounds of the buckets of the latency histograms.
+	buckets []time.Duration
+
+	// topics 是每个主题的指标。
+	// topics is the metrics of each topic.
+	topics map[string]*TopicStats
+}
+
+// NewMemoryMetrics 是一个函数，它返回一个新的 MemoryMetrics，buckets 是延迟直方图的桶上界，为空时使用 DefaultLatencyBuckets。
+// NewMemoryMetrics is a function that returns a new MemoryMetrics, buckets is the upper bounds of the buckets of the latency histograms, DefaultLatencyBuckets is used when it is empty.
+func NewMemoryMetrics(buckets ...time.Duration) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &MemoryMetrics{buckets: buckets, topics: make(map[string]*TopicStats)}
+}
+
+// topic 是 MemoryMetrics 的一个方法，它返回主题的指标，不存在时创建，调用方需要持有锁。
+// topic is a method of MemoryMetrics that returns the metrics of the topic, creating them when absent, the caller needs to hold the lock.
+func (m *MemoryMetrics) topic(topic string) *TopicStats {
+	s, ok := m.topics[topic]
+	if !ok {
+		s = &TopicStats{}
+		s.QueueWait.Buckets = make([]LatencyBucket, len(m.buckets))
+		s.HandlerDuration.Buckets = make([]LatencyBucket, len(m.buckets))
+		for i, b := range m.buckets {
+			s.QueueWait.Buckets[i].UpperBound = b
+			s.HandlerDuration.Buckets[i].UpperBound = b
+		}
+		m.topics[topic] = s
+	}
+	return s
+}
+
+// OnEmitted 是 MemoryMetrics 的一个方法，它增加主题被接受的发出次数。
+// OnEmitted is a method of MemoryMetrics that increases the number of accepted emissions of the topic.
+func (m *MemoryMetrics) OnEmitted(topic string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.topic(topic).Emitted++
+}
+
+// OnRejected 是 MemoryMetrics 的一个方法，它增加主题被拒绝的发出次数。
+// OnRejected is a method of MemoryMetrics that increases the number of rejected emissions of the topic.
+func (m *MemoryMetrics) OnRejected(topic string, _ error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.topic(topic).Rejected++
+}
+
+// OnSubmitted 是 MemoryMetrics 的一个方法，它增加主题提交的任务数量。
+// OnSubmitted is a method of MemoryMetrics that increases the number of jobs submitted for the topic.
+func (m *MemoryMetrics) OnSubmitted(topic string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.topic(topic).Submitted++
+}
+
+// OnStarted 是 MemoryMetrics 的一个方法，它记录任务排队等待的时间。
+// OnStarted is a method of MemoryMetrics that records the queue wait time of a job.
+func (m *MemoryMetrics) OnStarted(topic string, wait time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.topic(topic).QueueWait.observe(wait)
+}
+
+// OnHandled 是 MemoryMetrics 的一个方法，它记录处理函数的执行结果和执行时间。
+// OnHandled is a method of MemoryMetrics that records the result and execution time of a handling function.
+func (m *MemoryMetrics) OnHandled(topic string, outcome Outcome, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s := m.topic(topic)
+	switch outcome {
+	case OutcomeSucceeded:
+		s.Succeeded++
+	case OutcomeFailed:
+		s.Failed++
+	case OutcomePanicked:
+		s.Panicked++
+	}
+	s.HandlerDuration.observe(duration)
+}
+
+// Stats 是 MemoryMetrics 的一个方法，它返回每个主题的指标快照。
+// Stats is a method of MemoryMetrics that returns a snapshot of the metrics of each topic.
+func (m *MemoryMetrics) Stats() map[string]TopicStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stats := make(map[string]TopicStats, len(m.topics))
+	for topic, s := range m.topics {
+		snapshot := *s
+		snapshot.QueueWait = s.QueueWait.clone()
+		snapshot.HandlerDuration = s.HandlerDuration.clone()
+		stats[topic] = snapshot
+	}
+	return stats
+}
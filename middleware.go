@@ -0,0 +1,112 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrorHandlerPanicked 是一个变量，它的值为一个新的错误，表示处理函数发生了 panic。
+// ErrorHandlerPanicked is a variable, its value is a new error, indicating that a handling function panicked.
+var ErrorHandlerPanicked = errors.New("message handle function panicked")
+
+// Middleware 是一个函数类型，它接受下一个消息处理函数，并返回包装后的消息处理函数。
+// 中间件收到的消息是 *Envelope，可以从中获取主题、数据和上下文；调用 next 时应当传入 *Envelope。
+// Middleware is a function type that takes the next message handling function and returns the wrapped message handling function.
+// The message received by a middleware is *Envelope, from which the topic, data, and context can be obtained; *Envelope should be passed when calling next.
+type Middleware = func(next MessageHandleFunc) MessageHandleFunc
+
+// chainMiddlewares 是一个函数，它按顺序将中间件组合到消息处理函数上，第一个中间件位于最外层。
+// chainMiddlewares is a function that composes middlewares onto the message handling function in order, the first middleware is the outermost.
+func chainMiddlewares(fn MessageHandleFunc, mws []Middleware) MessageHandleFunc {
+	// 从最后一个中间件开始向外包装。
+	// Wrap outwards starting from the last middleware.
+	for i := len(mws) - 1; i >= 0; i-- {
+		fn = mws[i](fn)
+	}
+
+	// 返回包装后的消息处理函数。
+	// Return the wrapped message handling function.
+	return fn
+}
+
+// Use 是 EventEmitter 的一个方法，它添加作用于所有处理函数的中间件，包括已经注册的处理函数。
+// EventEmitter 的中间件按添加顺序执行，并位于注册时通过 WithMiddleware 指定的中间件之外。
+// Use is a method of EventEmitter that adds middlewares applied to all handling functions, including the handling functions already registered.
+// The middlewares of EventEmitter are executed in the order they are added, and are placed outside the middlewares specified by WithMiddleware at registration.
+func (ee *EventEmitter) Use(mws ...Middleware) {
+	// 锁定 EventEmitter，以防止并发修改。
+	// Lock the EventEmitter to prevent concurrent modifications.
+	ee.lock.Lock()
+	defer ee.lock.Unlock()
+
+	// 复制已有的中间件，并追加所有非 nil 的新中间件，正在执行的处理函数不受影响。
+	// Copy the existing middlewares and append all new non-nil middlewares, handling functions being executed are not affected.
+	var list []Middleware
+	if old := ee.middlewares.Load(); old != nil {
+		list = append(list, *old...)
+	}
+	for _, mw := range mws {
+		if mw != nil {
+			list = append(list, mw)
+		}
+	}
+
+	// 保存新的中间件列表。
+	// Save the new middleware list.
+	ee.middlewares.Store(&list)
+}
+
+// middlewareChain 是一个结构体，它缓存组合了 EventEmitter 中间件的处理链，以及组合时使用的中间件列表。
+// middlewareChain is a structure that caches the handling chain composed with the middlewares of EventEmitter, and the middleware list used for composing it.
+type middlewareChain struct {
+	// mws 是组合时 EventEmitter 的中间件列表，Use 每次都会保存一个新的列表，因此可以用指针判断列表是否变化。
+	// mws is the middleware list of EventEmitter at composing time, Use saves a new list every time, so the pointer tells whether the list has changed.
+	mws *[]Middleware
+
+	// fn 是组合后的处理链。
+	// fn is the composed handling chain.
+	fn MessageHandleFunc
+}
+
+// applyMiddlewares 是 EventEmitter 的一个方法，它返回将 EventEmitter 的中间件组合到消息处理函数上的处理链。
+// 处理链缓存在 cache 中，只有在 Use 改变了中间件列表之后才会重新组合。
+// applyMiddlewares is a method of EventEmitter that returns the handling chain composing the middlewares of EventEmitter onto the message handling function.
+// The chain is cached in cache, and is composed again only after Use has changed the middleware list.
+func (ee *EventEmitter) applyMiddlewares(cache *atomic.Pointer[middlewareChain], fn MessageHandleFunc) MessageHandleFunc {
+	// 如果中间件列表没有变化，返回缓存的处理链。
+	// If the middleware list has not changed, return the cached chain.
+	mws := ee.middlewares.Load()
+	if c := cache.Load(); c != nil && c.mws == mws {
+		return c.fn
+	}
+
+	// 重新组合处理链并缓存它。并发组合时保存任意一个结果都是正确的。
+	// Compose the chain again and cache it. Saving either result is correct when composing concurrently.
+	c := &middlewareChain{mws: mws, fn: fn}
+	if mws != nil {
+		c.fn = chainMiddlewares(fn, *mws)
+	}
+	cache.Store(c)
+	return c.fn
+}
+
+// Recovery 是一个函数，它返回一个中间件，将处理函数中的 panic 转换为包装了 ErrorHandlerPanicked 的错误。
+// Recovery is a function that returns a middleware which converts panics in handling functions into errors wrapping ErrorHandlerPanicked.
+func Recovery() Middleware {
+	return func(next MessageHandleFunc) MessageHandleFunc {
+		return func(msg any) (data any, err error) {
+			// 使用 defer 语句捕获 panic，并将它转换为错误。
+			// Use the defer statement to catch the panic and convert it into an error.
+			defer func() {
+				if r := recover(); r != nil {
+					data, err = nil, fmt.Errorf("%w: %v", ErrorHandlerPanicked, r)
+				}
+			}()
+
+			// 调用下一个消息处理函数。
+			// Call the next message handling function.
+			return next(msg)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package events
+
+// registerOptions 是一个结构体，它保存了注册消息处理函数时使用的选项。
+// registerOptions is a structure that holds the options used when registering a message handling function.
+type registerOptions struct {
+	// replace 表示是否用新的处理函数替换主题上已注册的所有处理函数。
+	// replace indicates whether to replace all handling functions registered on the topic with the new one.
+	replace bool
+
+	// middlewares 是只作用于这次注册的中间件。
+	// middlewares is the middlewares applied only to this registration.
+	middlewares []Middleware
+
+	// retry 是处理函数失败后的重试策略，为 nil 时不重试。
+	// retry is the retry policy after the handling function fails, no retry when it is nil.
+	retry *RetryPolicy
+
+	// replay 表示是否先把主题上保留的历史事件重放给新的处理函数。
+	// replay indicates whether to replay the history events kept on the topic to the new handling function first.
+	replay bool
+}
+
+// RegisterOption 是一个函数类型，用于修改注册消息处理函数时使用的选项。
+// RegisterOption is a function type used to modify the options used when registering a message handling function.
+type RegisterOption func(opts *registerOptions)
+
+// newRegisterOptions 是一个函数，它依次应用所有的选项，并返回最终的 registerOptions 实例。
+// newRegisterOptions is a function that applies all options in order and returns the final instance of registerOptions.
+func newRegisterOptions(opts []RegisterOption) *registerOptions {
+	// 创建一个默认的 registerOptions 实例。
+	// Create a default instance of registerOptions.
+	o := &registerOptions{}
+
+	// 依次应用所有非 nil 的选项。
+	// Apply all non-nil options in order.
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	// 返回 registerOptions 实例。
+	// Return the instance of registerOptions.
+	return o
+}
+
+// WithReplace 是一个函数，它返回一个选项，使新的处理函数替换主题上已注册的所有处理函数，而不是追加到末尾。
+// WithReplace is a function that returns an option which makes the new handling function replace all handling functions registered on the topic instead of being appended.
+func WithReplace() RegisterOption {
+	return func(opts *registerOptions) {
+		opts.replace = true
+	}
+}
+
+// WithMiddleware 是一个函数，它返回一个选项，为这次注册的处理函数添加中间件，中间件按传入顺序执行。
+// WithMiddleware is a function that returns an option which adds middlewares to the handling function of this registration, the middlewares are executed in the order passed in.
+func WithMiddleware(mws ...Middleware) RegisterOption {
+	return func(opts *registerOptions) {
+		for _, mw := range mws {
+			if mw != nil {
+				opts.middlewares = append(opts.middlewares, mw)
+			}
+		}
+	}
+}
+
+// WithRetry 是一个函数，它返回一个选项，使处理函数失败后按照重试策略在延迟后重新执行，policy 为 nil 时使用默认的重试策略。
+// 处理函数可以通过 Envelope.Attempt 获取当前是第几次执行。只执行一次的处理函数不会重试。
+// WithRetry is a function that returns an option which makes the handling function be executed again after a delay according to the retry policy when it fails, the default retry policy is used when policy is nil.
+// The handling function can get the number of the current attempt through Envelope.Attempt. Run-once handling functions are not retried.
+func WithRetry(policy *RetryPolicy) RegisterOption {
+	return func(opts *registerOptions) {
+		opts.retry = isRetryPolicyValid(policy)
+	}
+}
+
+// WithReplay 是一个函数，它返回一个选项，使新的处理函数先按顺序收到 Config.WithReplayBuffer 在匹配的主题上保留的历史事件，再收到新的事件。
+// WithReplay is a function that returns an option which makes the new handling function first receive, in order, the history events kept by Config.WithReplayBuffer on the matching topics, and then the new events.
+func WithReplay() RegisterOption {
+	return func(opts *registerOptions) {
+		opts.replay = true
+	}
+}
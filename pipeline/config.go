@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"runtime"
+
+	"github.com/shengyanli1982/events/clock"
+)
+
+// Config 是一个结构体，用于配置 Pipeline 的参数。
+// Config is a structure used to configure the parameters of Pipeline.
+type Config struct {
+	// workers 是执行消息处理函数的工作协程数量。
+	// workers is the number of worker goroutines executing message handling functions.
+	workers int
+
+	// capacity 是等待执行的任务队列的容量，0 表示不限制。
+	// capacity is the capacity of the queue of jobs waiting to be executed, 0 means unlimited.
+	capacity int
+
+	// callback 是消息处理前后的回调。
+	// callback is the callback before and after a message is handled.
+	callback Callback
+
+	// clock 是计算延迟任务到期时间和等待延迟任务的时钟。
+	// clock is the clock computing the due times of delayed jobs and waiting for them.
+	clock clock.Clock
+}
+
+// NewConfig 是一个函数，用于创建并返回一个新的 Config 结构体的指针。
+// NewConfig is a function that creates and returns a pointer to a new Config structure.
+func NewConfig() *Config {
+	return &Config{
+		// workers 默认为 CPU 的数量。
+		// workers defaults to the number of CPUs.
+		workers: runtime.NumCPU(),
+
+		// callback 默认为一个什么也不做的回调。
+		// callback defaults to a callback that does nothing.
+		callback: NewEmptyCallback(),
+
+		// clock 默认为真实时间的时钟。
+		// clock defaults to a clock using real time.
+		clock: clock.NewRealClock(),
+	}
+}
+
+// DefaultConfig 是一个函数，用于创建一个默认的配置。
+// DefaultConfig is a function that creates a default configuration.
+func DefaultConfig() *Config {
+	return NewConfig()
+}
+
+// WithWorkerNumber 是一个方法，用于设置工作协程的数量。
+// WithWorkerNumber is a method used to set the number of worker goroutines.
+func (c *Config) WithWorkerNumber(num int) *Config {
+	c.workers = num
+	return c
+}
+
+// WithQueueCapacity 是一个方法，用于设置等待执行的任务队列的容量，队列已满时提交任务会返回 ErrorQueueFull，0 表示不限制。
+// WithQueueCapacity is a method used to set the capacity of the queue of jobs waiting to be executed. Submitting a job when the queue is full returns ErrorQueueFull, 0 means unlimited.
+func (c *Config) WithQueueCapacity(capacity int) *Config {
+	c.capacity = capacity
+	return c
+}
+
+// WithCallback 是一个方法，用于设置消息处理前后的回调。
+// WithCallback is a method used to set the callback before and after a message is handled.
+func (c *Config) WithCallback(callback Callback) *Config {
+	c.callback = callback
+	return c
+}
+
+// WithClock 是一个方法，用于设置计算和等待延迟任务的时钟，例如在测试中使用 clock.FakeClock。
+// WithClock is a method used to set the clock computing and waiting for delayed jobs, for example clock.FakeClock in tests.
+func (c *Config) WithClock(clock clock.Clock) *Config {
+	c.clock = clock
+	return c
+}
+
+// isConfigValid 是一个函数，用于检查配置是否有效，如果无效则返回一个默认的配置。
+// isConfigValid is a function that checks whether the configuration is valid, and returns a default configuration if it is not.
+func isConfigValid(conf *Config) *Config {
+	// 如果配置为 nil，创建一个默认的配置。
+	// If the configuration is nil, create a default configuration.
+	if conf == nil {
+		return DefaultConfig()
+	}
+
+	// 如果工作协程的数量无效，设置为 CPU 的数量。
+	// If the number of worker goroutines is invalid, set it to the number of CPUs.
+	if conf.workers <= 0 {
+		conf.workers = runtime.NumCPU()
+	}
+
+	// 如果队列容量无效，设置为不限制。
+	// If the queue capacity is invalid, set it to unlimited.
+	if conf.capacity < 0 {
+		conf.capacity = 0
+	}
+
+	// 如果回调为 nil，设置为一个什么也不做的回调。
+	// If the callback is nil, set it to a callback that does nothing.
+	if conf.callback == nil {
+		conf.callback = NewEmptyCallback()
+	}
+
+	// 如果时钟为 nil，设置为真实时间的时钟。
+	// If the clock is nil, set it to a clock using real time.
+	if conf.clock == nil {
+		conf.clock = clock.NewRealClock()
+	}
+
+	// 返回配置。
+	// Return the configuration.
+	return conf
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import "time"
+
+// job 是一个结构体，它表示一个提交给 Pipeline 的任务。
+// job is a structure that represents a job submitted to the Pipeline.
+type job struct {
+	// fn 是消息处理函数。
+	// fn is the message handling function.
+	fn MessageHandleFunc
+
+	// msg 是消息。
+	// msg is the message.
+	msg any
+
+	// due 是延迟任务的到期时间。
+	// due is the due time of a delayed job.
+	due time.Time
+
+	// seq 是延迟任务的提交序号，到期时间相同的任务按提交顺序执行。
+	// seq is the submission sequence number of a delayed job, jobs with the same due time are executed in submission order.
+	seq uint64
+}
+
+// timerHeap 是一个按到期时间排序的延迟任务最小堆，实现了 container/heap 的 Interface。
+// timerHeap is a min-heap of delayed jobs sorted by due time, implementing the Interface of container/heap.
+type timerHeap []*job
+
+// Len 是 timerHeap 的一个方法，它返回堆中任务的数量。
+// Len is a method of timerHeap that returns the number of jobs in the heap.
+func (h timerHeap) Len() int { return len(h) }
+
+// Less 是 timerHeap 的一个方法，它比较两个任务的到期时间，到期时间相同时比较提交序号。
+// Less is a method of timerHeap that compares the due times of two jobs, and the submission sequence numbers when the due times are equal.
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+
+// Swap 是 timerHeap 的一个方法，它交换两个任务的位置。
+// Swap is a method of timerHeap that swaps the positions of two jobs.
+func (h timerHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Push 是 timerHeap 的一个方法，它将一个任务加入堆的末尾。
+// Push is a method of timerHeap that appends a job to the end of the heap.
+func (h *timerHeap) Push(x any) { *h = append(*h, x.(*job)) }
+
+// Pop 是 timerHeap 的一个方法，它移除并返回堆的最后一个任务。
+// Pop is a method of timerHeap that removes and returns the last job of the heap.
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return j
+}
+
+// peek 是 timerHeap 的一个方法，它返回最早到期的任务，堆为空时返回 nil。
+// peek is a method of timerHeap that returns the earliest due job, or nil when the heap is empty.
+func (h timerHeap) peek() *job {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
@@ -0,0 +1,35 @@
+package pipeline
+
+// MessageHandleFunc 是一个函数类型，它接受任何类型的消息，并返回任何类型的结果和一个错误。
+// MessageHandleFunc is a function type that takes a message of any type and returns a result of any type and an error.
+type MessageHandleFunc = func(msg any) (any, error)
+
+// Callback 是一个接口，它定义了消息处理前后的回调函数。
+// Callback is an interface that defines the callback functions before and after a message is handled.
+type Callback = interface {
+	// OnBefore 方法在消息处理函数执行之前被调用。
+	// The OnBefore method is called before the message handling function is executed.
+	OnBefore(msg any)
+
+	// OnAfter 方法在消息处理函数执行之后被调用，参数是消息、处理结果和错误。
+	// The OnAfter method is called after the message handling function is executed, with the message, the handling result, and the error.
+	OnAfter(msg, result any, err error)
+}
+
+// emptyCallback 是一个空的回调实现，它的所有方法都什么也不做。
+// emptyCallback is an empty callback implementation, all of its methods do nothing.
+type emptyCallback struct{}
+
+// OnBefore 是 emptyCallback 的一个方法，它什么也不做。
+// OnBefore is a method of emptyCallback that does nothing.
+func (emptyCallback) OnBefore(msg any) {}
+
+// OnAfter 是 emptyCallback 的一个方法，它什么也不做。
+// OnAfter is a method of emptyCallback that does nothing.
+func (emptyCallback) OnAfter(msg, result any, err error) {}
+
+// NewEmptyCallback 是一个函数，它返回一个什么也不做的回调。
+// NewEmptyCallback is a function that returns a callback that does nothing.
+func NewEmptyCallback() Callback {
+	return emptyCallback{}
+}
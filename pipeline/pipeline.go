@@ -0,0 +1,384 @@
+package pipeline
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shengyanli1982/events/clock"
+)
+
+// ErrorPipelineClosed 是一个变量，它的值为一个新的错误，表示 Pipeline 已经停止。
+// ErrorPipelineClosed is a variable, its value is a new error, indicating that the Pipeline has been stopped.
+var ErrorPipelineClosed = errors.New("pipeline is closed")
+
+// ErrorQueueFull 是一个变量，它的值为一个新的错误，表示等待执行的任务队列已满。
+// ErrorQueueFull is a variable, its value is a new error, indicating that the queue of jobs waiting to be executed is full.
+var ErrorQueueFull = errors.New("pipeline queue is full")
+
+// ErrorHandleFuncIsNil 是一个变量，它的值为一个新的错误，表示消息处理函数为 nil。
+// ErrorHandleFuncIsNil is a variable, its value is a new error, indicating that the message handling function is nil.
+var ErrorHandleFuncIsNil = errors.New("message handle function is nil")
+
+// ErrorHandleFuncPanicked 是一个变量，它的值为一个新的错误，表示消息处理函数发生了 panic。
+// ErrorHandleFuncPanicked is a variable, its value is a new error, indicating that the message handling function panicked.
+var ErrorHandleFuncPanicked = errors.New("message handle function panicked")
+
+// Pipeline 是一个结构体，它使用固定数量的工作协程执行提交的任务，并使用一个按到期时间排序的最小堆管理延迟任务。
+// 它不依赖任何第三方库，满足 events.Pipeline 接口。
+// Pipeline is a structure that executes submitted jobs with a fixed number of worker goroutines and manages delayed jobs with a min-heap sorted by due time.
+// It does not depend on any third-party library and satisfies the events.Pipeline interface.
+type Pipeline struct {
+	// config 是 Pipeline 的配置。
+	// config is the configuration of Pipeline.
+	config *Config
+
+	// lock 是 sync.Mutex 类型，用于保护 queue、timers、seq、closed、busy、alive 和 stopping 的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to queue, timers, seq, closed, busy, alive, and stopping.
+	lock sync.Mutex
+
+	// cond 是与 lock 关联的条件变量，用于唤醒等待任务的工作协程。
+	// cond is the condition variable associated with lock, used to wake up worker goroutines waiting for jobs.
+	cond *sync.Cond
+
+	// exited 是与 lock 关联的条件变量，用于在工作协程退出时唤醒等待的 Stop。
+	// exited is the condition variable associated with lock, used to wake up the waiting Stop when a worker goroutine exits.
+	exited *sync.Cond
+
+	// busy 是正在执行任务的工作协程的数量。
+	// busy is the number of worker goroutines executing a job.
+	busy int
+
+	// alive 是尚未退出的工作协程的数量。
+	// alive is the number of worker goroutines that have not exited.
+	alive int
+
+	// stopping 是正在任务中调用 Stop 的工作协程的数量，它们要等 Stop 返回后才能退出。
+	// stopping is the number of worker goroutines calling Stop within a job, they can only exit after Stop returns.
+	stopping int
+
+	// queue 是等待执行的任务队列。
+	// queue is the queue of jobs waiting to be executed.
+	queue []*job
+
+	// timers 是尚未到期的延迟任务。
+	// timers is the delayed jobs that are not yet due.
+	timers timerHeap
+
+	// seq 是延迟任务的提交序号。
+	// seq is the submission sequence number of delayed jobs.
+	seq uint64
+
+	// closed 表示 Pipeline 是否已经停止。
+	// closed indicates whether the Pipeline has been stopped.
+	closed bool
+
+	// wake 是一个通道，用于在最早到期的延迟任务变化时唤醒调度协程。
+	// wake is a channel used to wake up the scheduling goroutine when the earliest due delayed job changes.
+	wake chan struct{}
+
+	// done 是一个通道，Pipeline 停止时被关闭。
+	// done is a channel that is closed when the Pipeline is stopped.
+	done chan struct{}
+
+	// wg 是 sync.WaitGroup 类型，用于等待调度协程退出。
+	// wg is of type sync.WaitGroup, used to wait for the scheduling goroutine to exit.
+	wg sync.WaitGroup
+}
+
+// NewPipeline 是一个函数，它使用配置创建一个新的 Pipeline，并启动工作协程和调度协程。
+// NewPipeline is a function that creates a new Pipeline with the configuration and starts the worker goroutines and the scheduling goroutine.
+func NewPipeline(conf *Config) *Pipeline {
+	// 检查配置是否有效。
+	// Check whether the configuration is valid.
+	conf = isConfigValid(conf)
+
+	// 创建一个新的 Pipeline 实例。
+	// Create a new instance of Pipeline.
+	p := &Pipeline{
+		config: conf,
+		alive:  conf.workers,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	p.cond = sync.NewCond(&p.lock)
+	p.exited = sync.NewCond(&p.lock)
+
+	// 启动工作协程。
+	// Start the worker goroutines.
+	for i := 0; i < conf.workers; i++ {
+		go p.worker()
+	}
+
+	// 启动调度协程。
+	// Start the scheduling goroutine.
+	p.wg.Add(1)
+	go p.scheduler()
+
+	// 返回 Pipeline 实例。
+	// Return the instance of Pipeline.
+	return p
+}
+
+// SubmitWithFunc 是 Pipeline 的一个方法，它提交一个立即执行的任务。
+// SubmitWithFunc is a method of Pipeline that submits a job to be executed immediately.
+func (p *Pipeline) SubmitWithFunc(fn MessageHandleFunc, msg any) error {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return ErrorHandleFuncIsNil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// 如果 Pipeline 已经停止，返回错误 ErrorPipelineClosed。
+	// If the Pipeline has been stopped, return the error ErrorPipelineClosed.
+	if p.closed {
+		return ErrorPipelineClosed
+	}
+
+	// 如果队列已满，返回错误 ErrorQueueFull。
+	// If the queue is full, return the error ErrorQueueFull.
+	if p.config.capacity > 0 && len(p.queue) >= p.config.capacity {
+		return ErrorQueueFull
+	}
+
+	// 将任务加入队列，并唤醒一个工作协程。
+	// Add the job to the queue and wake up a worker goroutine.
+	p.enqueue(&job{fn: fn, msg: msg})
+	return nil
+}
+
+// SubmitAfterWithFunc 是 Pipeline 的一个方法，它提交一个在延迟之后执行的任务，延迟不大于 0 时立即执行。
+// 延迟任务到期之前不占用队列容量。
+// SubmitAfterWithFunc is a method of Pipeline that submits a job to be executed after the delay, it is executed immediately when the delay is not greater than 0.
+// Delayed jobs do not take up queue capacity before they are due.
+func (p *Pipeline) SubmitAfterWithFunc(fn MessageHandleFunc, msg any, delay time.Duration) error {
+	// 延迟不大于 0 时立即提交。
+	// Submit immediately when the delay is not greater than 0.
+	if delay <= 0 {
+		return p.SubmitWithFunc(fn, msg)
+	}
+
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return ErrorHandleFuncIsNil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// 如果 Pipeline 已经停止，返回错误 ErrorPipelineClosed。
+	// If the Pipeline has been stopped, return the error ErrorPipelineClosed.
+	if p.closed {
+		return ErrorPipelineClosed
+	}
+
+	// 将任务加入延迟任务的堆中。
+	// Add the job to the heap of delayed jobs.
+	p.seq++
+	j := &job{fn: fn, msg: msg, due: p.config.clock.Now().Add(delay), seq: p.seq}
+	heap.Push(&p.timers, j)
+
+	// 如果新任务是最早到期的任务，唤醒调度协程重新计算等待时间。
+	// If the new job is the earliest due job, wake up the scheduling goroutine to recalculate the wait time.
+	if p.timers.peek() == j {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	// 如果没有发生错误，返回 nil。
+	// If no error occurs, return nil.
+	return nil
+}
+
+// Stop 是 Pipeline 的一个方法，它停止 Pipeline：之后提交的任务返回 ErrorPipelineClosed，已经接受的任务都会被执行，
+// 队列中的任务按顺序执行，尚未到期的延迟任务按到期时间顺序立即执行；Stop 等待这些任务执行完毕后返回。
+// 在任务中调用 Stop 时，它不等待调用它的工作协程，这个工作协程在任务返回后执行剩余的任务，然后退出。
+// Stop is a method of Pipeline that stops the Pipeline: jobs submitted afterwards return ErrorPipelineClosed, and all accepted jobs are executed,
+// the jobs in the queue in order and the delayed jobs not yet due immediately in due order; Stop returns after these jobs have been executed.
+// When Stop is called within a job, it does not wait for the worker goroutine calling it, which executes the remaining jobs after the job returns and then exits.
+func (p *Pipeline) Stop() {
+	p.lock.Lock()
+
+	// 第一次调用时，标记 Pipeline 已经停止，将尚未到期的延迟任务按到期时间顺序移入队列，并唤醒所有协程。
+	// On the first call, mark the Pipeline as stopped, move the delayed jobs not yet due into the queue in due order, and wake up all goroutines.
+	if !p.closed {
+		p.closed = true
+		for p.timers.Len() > 0 {
+			p.queue = append(p.queue, heap.Pop(&p.timers).(*job))
+		}
+		p.timers = nil
+		p.cond.Broadcast()
+		close(p.done)
+	}
+
+	// 等待所有工作协程执行完剩余的任务并退出。在任务中调用 Stop 的工作协程不计算在内，否则它会等待自己。
+	// Wait for all worker goroutines to execute the remaining jobs and exit. Worker goroutines calling Stop within a job are not counted, otherwise they would wait for themselves.
+	inJob := p.busy > 0 && withinJob()
+	if inJob {
+		p.stopping++
+		p.exited.Broadcast()
+	}
+	for p.alive > p.stopping {
+		p.exited.Wait()
+	}
+	if inJob {
+		p.stopping--
+	}
+	p.lock.Unlock()
+
+	// 等待调度协程退出。
+	// Wait for the scheduling goroutine to exit.
+	p.wg.Wait()
+}
+
+// enqueue 是 Pipeline 的一个方法，它将任务加入队列并唤醒一个工作协程，调用方需要持有锁。
+// enqueue is a method of Pipeline that adds the job to the queue and wakes up a worker goroutine, the caller needs to hold the lock.
+func (p *Pipeline) enqueue(j *job) {
+	p.queue = append(p.queue, j)
+	p.cond.Signal()
+}
+
+// worker 是 Pipeline 的一个方法，它是工作协程的主循环，不断从队列中取出任务并执行，直到 Pipeline 停止。
+// worker is a method of Pipeline that is the main loop of a worker goroutine, it keeps taking jobs from the queue and executing them until the Pipeline is stopped.
+func (p *Pipeline) worker() {
+	for {
+		// 等待队列中出现任务或者 Pipeline 停止。Pipeline 停止后继续执行队列中剩余的任务，队列为空时退出，并通知等待的 Stop。
+		// Wait for a job to appear in the queue or the Pipeline to be stopped. After the Pipeline is stopped, keep executing the remaining jobs in the queue, and exit when the queue is empty, notifying the waiting Stop.
+		p.lock.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.alive--
+			p.exited.Broadcast()
+			p.lock.Unlock()
+			return
+		}
+
+		// 取出队列头部的任务，并标记工作协程正在执行任务。
+		// Take the job at the head of the queue, and mark the worker goroutine as executing a job.
+		j := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.busy++
+		p.lock.Unlock()
+
+		// 执行任务。
+		// Execute the job.
+		p.execute(j)
+
+		p.lock.Lock()
+		p.busy--
+		p.lock.Unlock()
+	}
+}
+
+// execute 是 Pipeline 的一个方法，它执行一个任务，并在执行前后调用回调。处理函数中的 panic 会被转换为错误，工作协程不会退出。
+// execute is a method of Pipeline that executes a job and calls the callback before and after the execution. Panics in the handling function are converted into errors, and the worker goroutine does not exit.
+func (p *Pipeline) execute(j *job) {
+	p.config.callback.OnBefore(j.msg)
+	result, err := invoke(j.fn, j.msg)
+	p.config.callback.OnAfter(j.msg, result, err)
+}
+
+// invoke 是一个函数，它调用消息处理函数，并将 panic 转换为包装了 ErrorHandleFuncPanicked 的错误。
+// invoke is a function that calls the message handling function and converts panics into errors wrapping ErrorHandleFuncPanicked.
+func invoke(fn MessageHandleFunc, msg any) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%w: %v", ErrorHandleFuncPanicked, r)
+		}
+	}()
+	return fn(msg)
+}
+
+// invokeEntry 是 invoke 函数的入口地址，用于在调用栈中识别正在执行的任务。
+// invokeEntry is the entry address of the invoke function, used to recognize a job being executed on the call stack.
+var invokeEntry = reflect.ValueOf(invoke).Pointer()
+
+// withinJob 是一个函数，它检查调用方是否在任务中，即调用栈中是否有 invoke 的栈帧。它只比较函数的入口地址，不依赖调用栈的文本格式。
+// withinJob is a function that checks whether the caller is within a job, that is, whether there is a frame of invoke on the call stack. It only compares the entry addresses of functions and does not depend on the text format of the call stack.
+func withinJob() bool {
+	var pcs [64]uintptr
+	for skip := 2; ; skip += len(pcs) {
+		// 分段读取调用栈，直到找到 invoke 的栈帧或者读完整个调用栈。
+		// Read the call stack in chunks until a frame of invoke is found or the whole call stack has been read.
+		n := runtime.Callers(skip, pcs[:])
+		frames := runtime.CallersFrames(pcs[:n])
+		for {
+			frame, more := frames.Next()
+			if frame.Entry == invokeEntry {
+				return true
+			}
+			if !more {
+				break
+			}
+		}
+		if n < len(pcs) {
+			return false
+		}
+	}
+}
+
+// scheduler 是 Pipeline 的一个方法，它是调度协程的主循环，将到期的延迟任务移入队列，并等待下一个任务到期。
+// scheduler is a method of Pipeline that is the main loop of the scheduling goroutine, it moves due delayed jobs into the queue and waits for the next job to be due.
+func (p *Pipeline) scheduler() {
+	defer p.wg.Done()
+
+	for {
+		// 将所有到期的延迟任务移入队列，已经接受的延迟任务不受队列容量的限制。
+		// Move all due delayed jobs into the queue, accepted delayed jobs are not limited by the queue capacity.
+		p.lock.Lock()
+		now := p.config.clock.Now()
+		for next := p.timers.peek(); next != nil && !next.due.After(now); next = p.timers.peek() {
+			p.enqueue(heap.Pop(&p.timers).(*job))
+		}
+
+		// 计算下一个延迟任务到期之前的等待时间。
+		// Calculate the wait time before the next delayed job is due.
+		var timer clock.Timer
+		var expired <-chan time.Time
+		if next := p.timers.peek(); next != nil {
+			timer = p.config.clock.NewTimer(next.due.Sub(now))
+			expired = timer.C()
+
+			// 如果时钟在创建定时器之前已经走过到期时间（例如推进了虚拟时钟），立即重新检查。
+			// If the clock has already moved past the due time before the timer was created (for example a virtual clock was advanced), check again immediately.
+			if !next.due.After(p.config.clock.Now()) {
+				timer.Stop()
+				p.lock.Unlock()
+				continue
+			}
+		}
+		p.lock.Unlock()
+
+		// 等待下一个任务到期、最早到期的任务变化或者 Pipeline 停止。
+		// Wait for the next job to be due, the earliest due job to change, or the Pipeline to be stopped.
+		select {
+		case <-expired:
+		case <-p.wake:
+		case <-p.done:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		// 如果 Pipeline 已经停止，退出。
+		// If the Pipeline has been stopped, exit.
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shengyanli1982/events/clock"
+)
+
+// SyncPipeline 是一个结构体，它在调用方的协程上同步执行立即提交的任务，并将延迟任务按一个可控的虚拟时钟排队。
+// 延迟任务只有在调用 Advance 使虚拟时钟走过它们的到期时间后才会执行，适合编写不依赖 time.Sleep 的确定性单元测试。
+// 与 Pipeline 一样，处理函数中的 panic 会被转换为包装了 ErrorHandleFuncPanicked 的错误交给回调，不会传播给调用方。
+// SyncPipeline is a structure that executes immediately submitted jobs synchronously on the caller's goroutine, and queues delayed jobs against a controllable virtual clock.
+// Delayed jobs are executed only after Advance moves the virtual clock past their due times, which suits deterministic unit tests that do not rely on time.Sleep.
+// As with Pipeline, panics in handling functions are converted into errors wrapping ErrorHandleFuncPanicked and handed to the callback, they do not propagate to the caller.
+type SyncPipeline struct {
+	// clock 是延迟任务使用的虚拟时钟。
+	// clock is the virtual clock used by delayed jobs.
+	clock *clock.FakeClock
+
+	// callback 是消息处理前后的回调。
+	// callback is the callback before and after a message is handled.
+	callback Callback
+
+	// lock 是 sync.Mutex 类型，用于保护 pending 和 closed 的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to pending and closed.
+	lock sync.Mutex
+
+	// pending 是尚未到期的延迟任务及其定时器。
+	// pending is the delayed jobs that are not yet due and their timers.
+	pending map[*job]clock.Timer
+
+	// closed 表示 SyncPipeline 是否已经停止。
+	// closed indicates whether the SyncPipeline has been stopped.
+	closed bool
+}
+
+// NewSyncPipeline 是一个函数，它返回一个新的 SyncPipeline，虚拟时钟从当前的真实时间开始。
+// NewSyncPipeline is a function that returns a new SyncPipeline, whose virtual clock starts at the current real time.
+func NewSyncPipeline() *SyncPipeline {
+	return NewSyncPipelineWithClock(clock.NewFakeClock(time.Now()))
+}
+
+// NewSyncPipelineWithClock 是一个函数，它返回一个使用指定虚拟时钟的 SyncPipeline，推进该时钟同样会执行到期的延迟任务。
+// NewSyncPipelineWithClock is a function that returns a SyncPipeline using the specified virtual clock, advancing that clock also executes the due delayed jobs.
+func NewSyncPipelineWithClock(fake *clock.FakeClock) *SyncPipeline {
+	return &SyncPipeline{clock: fake, callback: NewEmptyCallback(), pending: make(map[*job]clock.Timer)}
+}
+
+// NewSyncPipelineWithConfig 是一个函数，它返回一个使用配置中的回调的 SyncPipeline，工作协程数量和队列容量不起作用。
+// 配置的时钟是 clock.FakeClock 时直接使用它，否则虚拟时钟从配置的时钟的当前时间开始。
+// NewSyncPipelineWithConfig is a function that returns a SyncPipeline using the callback of the configuration, the number of worker goroutines and the queue capacity have no effect.
+// When the configured clock is a clock.FakeClock it is used directly, otherwise the virtual clock starts at the current time of the configured clock.
+func NewSyncPipelineWithConfig(conf *Config) *SyncPipeline {
+	// 检查配置是否有效。
+	// Check whether the configuration is valid.
+	conf = isConfigValid(conf)
+
+	// 使用配置的虚拟时钟，或者创建一个新的虚拟时钟。
+	// Use the configured virtual clock, or create a new virtual clock.
+	fake, ok := conf.clock.(*clock.FakeClock)
+	if !ok {
+		fake = clock.NewFakeClock(conf.clock.Now())
+	}
+
+	// 创建 SyncPipeline 并设置回调。
+	// Create the SyncPipeline and set the callback.
+	p := NewSyncPipelineWithClock(fake)
+	p.callback = conf.callback
+	return p
+}
+
+// SubmitWithFunc 是 SyncPipeline 的一个方法，它在调用方的协程上立即执行任务，任务执行完毕后才返回。
+// SubmitWithFunc is a method of SyncPipeline that executes the job immediately on the caller's goroutine and returns only after the job has been executed.
+func (p *SyncPipeline) SubmitWithFunc(fn MessageHandleFunc, msg any) error {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return ErrorHandleFuncIsNil
+	}
+
+	// 如果 SyncPipeline 已经停止，返回错误 ErrorPipelineClosed。
+	// If the SyncPipeline has been stopped, return the error ErrorPipelineClosed.
+	p.lock.Lock()
+	closed := p.closed
+	p.lock.Unlock()
+	if closed {
+		return ErrorPipelineClosed
+	}
+
+	// 在不持有锁的情况下执行任务，任务中可以继续提交新的任务。
+	// Execute the job without holding the lock, new jobs can be submitted within the job.
+	p.execute(&job{fn: fn, msg: msg})
+	return nil
+}
+
+// SubmitAfterWithFunc 是 SyncPipeline 的一个方法，它将任务按虚拟时钟排队，延迟不大于 0 时立即执行。
+// SubmitAfterWithFunc is a method of SyncPipeline that queues the job against the virtual clock, it is executed immediately when the delay is not greater than 0.
+func (p *SyncPipeline) SubmitAfterWithFunc(fn MessageHandleFunc, msg any, delay time.Duration) error {
+	// 延迟不大于 0 时立即执行。
+	// Execute immediately when the delay is not greater than 0.
+	if delay <= 0 {
+		return p.SubmitWithFunc(fn, msg)
+	}
+
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return ErrorHandleFuncIsNil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// 如果 SyncPipeline 已经停止，返回错误 ErrorPipelineClosed。
+	// If the SyncPipeline has been stopped, return the error ErrorPipelineClosed.
+	if p.closed {
+		return ErrorPipelineClosed
+	}
+
+	// 在虚拟时钟上设置定时器，定时器只会在 Advance 中触发，因此在持有锁时设置是安全的。
+	// Set a timer on the virtual clock, the timer only fires within Advance, so setting it while holding the lock is safe.
+	j := &job{fn: fn, msg: msg}
+	p.pending[j] = p.clock.AfterFunc(delay, func() { p.run(j) })
+	return nil
+}
+
+// run 是 SyncPipeline 的一个方法，它在延迟任务到期时执行任务，已经被 Stop 丢弃的任务不会执行。
+// run is a method of SyncPipeline that executes a delayed job when it is due, jobs discarded by Stop are not executed.
+func (p *SyncPipeline) run(j *job) {
+	p.lock.Lock()
+	_, ok := p.pending[j]
+	delete(p.pending, j)
+	p.lock.Unlock()
+	if ok {
+		p.execute(j)
+	}
+}
+
+// execute 是 SyncPipeline 的一个方法，它执行一个任务，并在执行前后调用回调。处理函数中的 panic 会被转换为错误。
+// execute is a method of SyncPipeline that executes a job and calls the callback before and after the execution. Panics in the handling function are converted into errors.
+func (p *SyncPipeline) execute(j *job) {
+	p.callback.OnBefore(j.msg)
+	result, err := invoke(j.fn, j.msg)
+	p.callback.OnAfter(j.msg, result, err)
+}
+
+// Advance 是 SyncPipeline 的一个方法，它将虚拟时钟向前推进 d，并在调用方的协程上按到期顺序执行期间到期的所有延迟任务。
+// 执行任务时虚拟时钟位于任务的到期时间，任务中提交的、在推进范围内到期的延迟任务也会被执行。
+// Advance is a method of SyncPipeline that moves the virtual clock forward by d and executes all delayed jobs due in the meantime on the caller's goroutine in due order.
+// While a job is executed, the virtual clock is at the due time of the job, and delayed jobs submitted by the job that are due within the range are also executed.
+func (p *SyncPipeline) Advance(d time.Duration) {
+	p.clock.Advance(d)
+}
+
+// Now 是 SyncPipeline 的一个方法，它返回虚拟时钟的当前时间。
+// Now is a method of SyncPipeline that returns the current time of the virtual clock.
+func (p *SyncPipeline) Now() time.Time {
+	return p.clock.Now()
+}
+
+// Clock 是 SyncPipeline 的一个方法，它返回 SyncPipeline 使用的虚拟时钟，可以通过 events.Config 的 WithClock 共享给 EventEmitter。
+// Clock is a method of SyncPipeline that returns the virtual clock used by the SyncPipeline, which can be shared with the EventEmitter through WithClock of events.Config.
+func (p *SyncPipeline) Clock() *clock.FakeClock {
+	return p.clock
+}
+
+// Len 是 SyncPipeline 的一个方法，它返回尚未到期的延迟任务的数量。
+// Len is a method of SyncPipeline that returns the number of delayed jobs that are not yet due.
+func (p *SyncPipeline) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.pending)
+}
+
+// Stop 是 SyncPipeline 的一个方法，它停止 SyncPipeline，并丢弃尚未到期的延迟任务。
+// Stop is a method of SyncPipeline that stops the SyncPipeline and discards the delayed jobs that are not yet due.
+func (p *SyncPipeline) Stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	for j, timer := range p.pending {
+		timer.Stop()
+		delete(p.pending, j)
+	}
+}
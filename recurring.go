@@ -0,0 +1,313 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// ErrorScheduleInvalid 是一个变量，它的值为一个新的错误，表示周期发出的间隔、cron 表达式或者消息工厂无效。
+// ErrorScheduleInvalid is a variable, its value is a new error, indicating that the interval, the cron expression or the message factory of a recurring emission is invalid.
+var ErrorScheduleInvalid = errors.New("schedule is invalid")
+
+// recurringSchedule 是一个结构体，它实现了 Schedule 接口，按计划周期地在主题上发出事件。
+// 每一次到期只向 pipeline 提交一个到期任务，暂停、恢复和取消会增加代数，使已经提交的旧任务失效。
+// recurringSchedule is a structure that implements the Schedule interface and emits events on a topic periodically according to the plan.
+// Each due time submits only one due job to the pipeline, and pausing, resuming and cancelling increase the generation so that old jobs already submitted become invalid.
+type recurringSchedule struct {
+	// emitter 是发出事件的 EventEmitter。
+	// emitter is the EventEmitter emitting the events.
+	emitter *EventEmitter
+
+	// topic 是发出事件的主题。
+	// topic is the topic the events are emitted on.
+	topic string
+
+	// factory 是每次到期时生成事件数据的函数。
+	// factory is the function generating the data of the event at each due time.
+	factory func() any
+
+	// next 是计算下一次到期时间的函数，参数是上一次到期的时间。
+	// next is the function calculating the next due time, the argument is the last due time.
+	next func(last time.Time) time.Time
+
+	// lock 用于保护下面字段的并发访问。
+	// lock is used to protect concurrent access to the fields below.
+	lock sync.Mutex
+
+	// generation 是当前的代数，只有代数相同的到期任务才会发出事件。
+	// generation is the current generation, only due jobs of the same generation emit events.
+	generation uint64
+
+	// due 是下一次到期的时间。
+	// due is the next due time.
+	due time.Time
+
+	// paused 表示计划是否已经暂停。
+	// paused indicates whether the schedule has been paused.
+	paused bool
+
+	// cancelled 表示计划是否已经取消。
+	// cancelled indicates whether the schedule has been cancelled.
+	cancelled bool
+}
+
+// recurringTick 是一个结构体，它是提交给 pipeline 的到期任务的消息。
+// recurringTick is a structure that is the message of the due job submitted to the pipeline.
+type recurringTick struct {
+	// schedule 是到期的计划。
+	// schedule is the schedule that is due.
+	schedule *recurringSchedule
+
+	// generation 是提交任务时计划的代数。
+	// generation is the generation of the schedule when the job was submitted.
+	generation uint64
+}
+
+// EmitEvery 是 EventEmitter 的一个方法，它从现在开始每隔 interval 在主题上发出一次 msg，并返回可以暂停、恢复和取消的 Schedule。
+// 计划在 EventEmitter 关闭时自动取消。interval 必须大于 0，否则返回 ErrorScheduleInvalid。
+// EmitEvery is a method of EventEmitter that emits msg on the topic every interval from now on, and returns a Schedule that can be paused, resumed and cancelled.
+// The schedule is cancelled automatically when the EventEmitter is closed. interval must be greater than 0, otherwise ErrorScheduleInvalid is returned.
+func (ee *EventEmitter) EmitEvery(topic string, msg any, interval time.Duration) (Schedule, error) {
+	// 如果间隔无效，返回 ErrorScheduleInvalid 错误。
+	// If the interval is invalid, return the ErrorScheduleInvalid error.
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: interval %v must be positive", ErrorScheduleInvalid, interval)
+	}
+
+	// 按固定的间隔计算到期时间，避免累积误差。
+	// Calculate the due times at a fixed interval to avoid accumulating drift.
+	return ee.startRecurring(topic, func() any { return msg }, func(last time.Time) time.Time {
+		return last.Add(interval)
+	})
+}
+
+// EmitCron 是 EventEmitter 的一个方法，它按 cron 表达式在主题上周期地发出事件，每次到期时调用 factory 生成事件的数据，并返回可以暂停、恢复和取消的 Schedule。
+// 表达式是标准的五个字段（分钟 小时 日 月 星期），支持 *、?、范围、步长、列表、月份和星期的英文缩写，以及 @hourly、@daily、@weekly、@monthly 和 @yearly。
+// 表达式默认使用本地时区，可以以 CRON_TZ=<时区> 开头指定时区，例如 "CRON_TZ=Asia/Shanghai 0 9 * * MON-FRI"。计划在 EventEmitter 关闭时自动取消。
+// EmitCron is a method of EventEmitter that emits events on the topic periodically according to the cron expression, calls factory to generate the data of the event at each due time, and returns a Schedule that can be paused, resumed and cancelled.
+// The expression has the standard five fields (minute hour day-of-month month day-of-week), supporting *, ?, ranges, steps, lists, English abbreviations of months and weekdays, as well as @hourly, @daily, @weekly, @monthly and @yearly.
+// The expression uses the local time zone by default, and can start with CRON_TZ=<zone> to specify the time zone, for example "CRON_TZ=Asia/Shanghai 0 9 * * MON-FRI". The schedule is cancelled automatically when the EventEmitter is closed.
+func (ee *EventEmitter) EmitCron(topic string, factory func() any, spec string) (Schedule, error) {
+	// 如果消息工厂为 nil，返回 ErrorScheduleInvalid 错误。
+	// If the message factory is nil, return the ErrorScheduleInvalid error.
+	if factory == nil {
+		return nil, fmt.Errorf("%w: message factory is nil", ErrorScheduleInvalid)
+	}
+
+	// 解析 cron 表达式，如果无效，返回 ErrorScheduleInvalid 错误。
+	// Parse the cron expression, and return the ErrorScheduleInvalid error if it is invalid.
+	cron, err := internal.ParseCron(spec, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorScheduleInvalid, err)
+	}
+
+	// 按 cron 表达式计算到期时间。
+	// Calculate the due times according to the cron expression.
+	return ee.startRecurring(topic, factory, cron.Next)
+}
+
+// startRecurring 是 EventEmitter 的一个方法，它创建并记录一个周期发出的计划，然后提交第一次到期的任务。
+// startRecurring is a method of EventEmitter that creates and records a recurring schedule, and then submits the due job of the first due time.
+func (ee *EventEmitter) startRecurring(topic string, factory func() any, next func(last time.Time) time.Time) (Schedule, error) {
+	// 如果主题名称无效，返回 ErrorTopicInvalid 错误。
+	// If the topic name is invalid, return the ErrorTopicInvalid error.
+	ee.lock.RLock()
+	valid := ee.isValidTopic(topic)
+	ee.lock.RUnlock()
+	if !valid {
+		return nil, ErrorTopicInvalid
+	}
+
+	// 创建一个新的计划。
+	// Create a new schedule.
+	s := &recurringSchedule{emitter: ee, topic: topic, factory: factory, next: next}
+
+	// 锁定计划的集合，如果 EventEmitter 已经关闭，返回 ErrEmitterClosed 错误。
+	// Lock the set of schedules, and return the ErrEmitterClosed error if the EventEmitter is closed.
+	ee.scheduleLock.Lock()
+	if ee.closed.Load() {
+		ee.scheduleLock.Unlock()
+		return nil, ErrEmitterClosed
+	}
+	ee.recurring[s] = struct{}{}
+	ee.scheduleLock.Unlock()
+
+	// 从现在开始计算第一次到期的时间，并提交到期任务。
+	// Calculate the first due time from now on, and submit the due job.
+	s.lock.Lock()
+	tick, delay := s.arm(ee.config.clock.Now())
+	s.lock.Unlock()
+	s.submit(tick, delay)
+
+	// 返回计划。
+	// Return the schedule.
+	return s, nil
+}
+
+// arm 是 recurringSchedule 的一个方法，它计算 last 之后第一个晚于当前时间的到期时间，增加代数，并返回需要提交的到期任务和延迟，调用方需要持有锁。
+// arm is a method of recurringSchedule that calculates the first due time after last that is later than now, increases the generation, and returns the due job to submit and the delay, the caller needs to hold the lock.
+func (s *recurringSchedule) arm(last time.Time) (*recurringTick, time.Duration) {
+	// 跳过已经错过的到期时间。
+	// Skip the due times that have been missed.
+	now := s.emitter.config.clock.Now()
+	due := s.next(last)
+	for !due.IsZero() && !due.After(now) {
+		due = s.next(due)
+	}
+
+	// 没有下一次到期的时间，计划结束。
+	// There is no next due time, the schedule ends.
+	s.generation++
+	s.due = due
+	if due.IsZero() {
+		return nil, 0
+	}
+
+	// 返回到期任务和延迟。
+	// Return the due job and the delay.
+	return &recurringTick{schedule: s, generation: s.generation}, due.Sub(now)
+}
+
+// submit 是 recurringSchedule 的一个方法，它在锁外向 pipeline 提交到期任务，提交失败时取消计划。
+// submit is a method of recurringSchedule that submits the due job to the pipeline outside the lock, and cancels the schedule when the submission fails.
+func (s *recurringSchedule) submit(tick *recurringTick, delay time.Duration) {
+	if tick == nil {
+		return
+	}
+	if err := s.emitter.pipeline.SubmitAfterWithFunc(s.emitter.fireRecurring, tick, delay); err != nil {
+		s.Cancel()
+	}
+}
+
+// fireRecurring 是 EventEmitter 的一个方法，它是周期发出的到期任务：如果任务仍然有效，提交下一次到期的任务，然后在主题上发出事件。
+// 发出失败的错误由 MetricsRecorder 的 OnRejected 记录；EventEmitter 关闭后计划会被取消。
+// fireRecurring is a method of EventEmitter that is the due job of recurring emissions: if the job is still valid, it submits the due job of the next due time, and then emits the event on the topic.
+// Errors of failed emissions are recorded by OnRejected of the MetricsRecorder; the schedule is cancelled after the EventEmitter is closed.
+func (ee *EventEmitter) fireRecurring(msg any) (any, error) {
+	tick := msg.(*recurringTick)
+	s := tick.schedule
+
+	// 如果任务已经失效，什么也不做。
+	// If the job has become invalid, do nothing.
+	s.lock.Lock()
+	if tick.generation != s.generation || s.paused || s.cancelled {
+		s.lock.Unlock()
+		return nil, nil
+	}
+
+	// 提交下一次到期的任务。
+	// Submit the due job of the next due time.
+	next, delay := s.arm(s.due)
+	s.lock.Unlock()
+	s.submit(next, delay)
+
+	// 在主题上发出事件，如果 EventEmitter 已经关闭，取消计划。
+	// Emit the event on the topic, and cancel the schedule if the EventEmitter is closed.
+	err := ee.emit(context.Background(), s.topic, s.factory(), executeImmediately, nil)
+	if errors.Is(err, ErrEmitterClosed) {
+		s.Cancel()
+	}
+	return nil, err
+}
+
+// Topic 是 recurringSchedule 的一个方法，它返回发出事件的主题。
+// Topic is a method of recurringSchedule that returns the topic the events are emitted on.
+func (s *recurringSchedule) Topic() string {
+	return s.topic
+}
+
+// Next 是 recurringSchedule 的一个方法，它返回下一次到期的时间，计划暂停、取消或者已经结束时返回零值。
+// Next is a method of recurringSchedule that returns the next due time, and the zero value when the schedule is paused, cancelled or has ended.
+func (s *recurringSchedule) Next() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.paused || s.cancelled {
+		return time.Time{}
+	}
+	return s.due
+}
+
+// Pause 是 recurringSchedule 的一个方法，它暂停计划，暂停期间到期的事件不会发出。
+// Pause is a method of recurringSchedule that pauses the schedule, events due while it is paused are not emitted.
+func (s *recurringSchedule) Pause() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.paused || s.cancelled {
+		return
+	}
+	s.paused = true
+	s.generation++
+}
+
+// Resume 是 recurringSchedule 的一个方法，它恢复暂停的计划，从现在开始计算下一次到期的时间。
+// Resume is a method of recurringSchedule that resumes the paused schedule, calculating the next due time from now on.
+func (s *recurringSchedule) Resume() {
+	s.lock.Lock()
+	if !s.paused || s.cancelled {
+		s.lock.Unlock()
+		return
+	}
+	s.paused = false
+	tick, delay := s.arm(s.emitter.config.clock.Now())
+	s.lock.Unlock()
+	s.submit(tick, delay)
+}
+
+// Cancel 是 recurringSchedule 的一个方法，它取消计划，之后不会再发出事件，也不能恢复。
+// Cancel is a method of recurringSchedule that cancels the schedule, no more events are emitted afterwards and it cannot be resumed.
+func (s *recurringSchedule) Cancel() {
+	s.lock.Lock()
+	if s.cancelled {
+		s.lock.Unlock()
+		return
+	}
+	s.cancelled = true
+	s.generation++
+	s.lock.Unlock()
+
+	// 从 EventEmitter 的计划集合中移除。
+	// Remove it from the set of schedules of the EventEmitter.
+	s.emitter.scheduleLock.Lock()
+	delete(s.emitter.recurring, s)
+	s.emitter.scheduleLock.Unlock()
+}
+
+// Paused 是 recurringSchedule 的一个方法，它返回计划是否已经暂停。
+// Paused is a method of recurringSchedule that returns whether the schedule has been paused.
+func (s *recurringSchedule) Paused() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paused
+}
+
+// Active 是 recurringSchedule 的一个方法，它返回计划是否仍然有效，即没有被取消。
+// Active is a method of recurringSchedule that returns whether the schedule is still active, that is, it has not been cancelled.
+func (s *recurringSchedule) Active() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.cancelled
+}
+
+// cancelRecurring 是 EventEmitter 的一个方法，它取消所有周期发出的计划，在 EventEmitter 关闭时调用。
+// cancelRecurring is a method of EventEmitter that cancels all recurring schedules, called when the EventEmitter is closed.
+func (ee *EventEmitter) cancelRecurring() {
+	// 取走所有的计划。
+	// Take all schedules.
+	ee.scheduleLock.Lock()
+	schedules := make([]*recurringSchedule, 0, len(ee.recurring))
+	for s := range ee.recurring {
+		schedules = append(schedules, s)
+	}
+	ee.scheduleLock.Unlock()
+
+	// 在锁外依次取消。
+	// Cancel them one by one outside the lock.
+	for _, s := range schedules {
+		s.Cancel()
+	}
+}
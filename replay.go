@@ -0,0 +1,283 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// replayLimit 是一个结构体，它记录主题上保留历史事件的限制。
+// replayLimit is a structure that records the limits of keeping history events on a topic.
+type replayLimit struct {
+	// size 是保留的事件数量上限，小于等于 0 时不限制。
+	// size is the maximum number of events kept, no limit when it is less than or equal to 0.
+	size int
+
+	// maxAge 是事件保留的最长时间，小于等于 0 时不限制。
+	// maxAge is the maximum time an event is kept, no limit when it is less than or equal to 0.
+	maxAge time.Duration
+
+	// sticky 表示主题是否是粘性主题，新注册的处理函数会自动收到最新的事件。
+	// sticky indicates whether the topic is sticky, newly registered handling functions receive the latest event automatically.
+	sticky bool
+}
+
+// replayRecord 是一个结构体，它记录一个保留在历史中的事件。
+// replayRecord is a structure that records an event kept in the history.
+type replayRecord struct {
+	// seq 是事件进入历史的顺序，用于合并多个主题的历史。
+	// seq is the order in which the event entered the history, used to merge the histories of several topics.
+	seq uint64
+
+	// at 是事件进入历史的时间，延迟事件是到期的时间。
+	// at is the time the event entered the history, it is the due time for delayed events.
+	at time.Time
+
+	// dropped 表示事件是否在进入历史之后发出失败，这样的事件不会被重放。
+	// dropped indicates whether the event failed to be emitted after entering the history, such events are not replayed.
+	dropped bool
+
+	// topic、id、msg、timestamp、scheduledAt 和 headers 是事件发出时的主题、数据和元数据。
+	// topic, id, msg, timestamp, scheduledAt and headers are the topic, data and metadata of the event when it was emitted.
+	topic       string
+	id          string
+	msg         any
+	timestamp   time.Time
+	scheduledAt time.Time
+	headers     map[string]string
+}
+
+// replayBuffer 是一个结构体，它按顺序保存一个主题上最近的事件。
+// replayBuffer is a structure that keeps the recent events of a topic in order.
+type replayBuffer struct {
+	// limit 是保留事件的限制。
+	// limit is the limits of keeping events.
+	limit replayLimit
+
+	// records 是按进入历史的顺序排列的事件。
+	// records is the events in the order they entered the history.
+	records *internal.Ring[*replayRecord]
+}
+
+// newReplayBuffer 是一个函数，它返回一个新的 replayBuffer 实例。
+// newReplayBuffer is a function that returns a new instance of replayBuffer.
+func newReplayBuffer(limit replayLimit) *replayBuffer {
+	return &replayBuffer{limit: limit, records: internal.NewRing[*replayRecord](16)}
+}
+
+// push 是 replayBuffer 的一个方法，它加入一个事件，并移除超出限制的事件。
+// push is a method of replayBuffer that adds an event and removes the events beyond the limits.
+func (b *replayBuffer) push(r *replayRecord, now time.Time) {
+	b.records.Push(r)
+	b.trim(now)
+}
+
+// trim 是 replayBuffer 的一个方法，它从最早的事件开始移除超出数量上限或者保留时间的事件。
+// trim is a method of replayBuffer that removes the events beyond the maximum number or the maximum age, starting from the earliest one.
+func (b *replayBuffer) trim(now time.Time) {
+	for b.limit.size > 0 && b.records.Len() > b.limit.size {
+		b.records.PopFront()
+	}
+	for b.limit.maxAge > 0 && b.records.Len() > 0 && now.Sub(b.records.At(0).at) > b.limit.maxAge {
+		b.records.PopFront()
+	}
+}
+
+// replayJob 是一个结构体，它记录一个在重放结束之前到达、等待提交的事件。
+// replayJob is a structure that records an event arriving before the replay ends and waiting to be submitted.
+type replayJob struct {
+	// emission 是事件的分发状态。
+	// emission is the dispatch state of the event.
+	emission *emission
+
+	// index 是处理函数在分发顺序中的位置。
+	// index is the position of the handling function in dispatch order.
+	index int
+}
+
+// replayGate 是一个结构体，它保证使用 WithReplay 注册的处理函数先收到历史事件，再收到新的事件。
+// 重放结束之前到达的新事件会按顺序排队，重放结束后再提交。
+// replayGate is a structure that ensures a handling function registered with WithReplay receives the history events before new events.
+// New events arriving before the replay ends are queued in order and submitted after the replay ends.
+type replayGate struct {
+	// lock 用于保护 queue 和 open 的并发访问。
+	// lock is used to protect concurrent access to queue and open.
+	lock sync.Mutex
+
+	// records 是注册时需要重放的历史事件。
+	// records is the history events to replay at registration.
+	records []*replayRecord
+
+	// queue 是重放结束之前到达的新事件。
+	// queue is the new events arriving before the replay ends.
+	queue []replayJob
+
+	// open 表示重放是否已经结束，结束后新事件直接提交。
+	// open indicates whether the replay has ended, new events are submitted directly after it ends.
+	open bool
+}
+
+// hold 是 replayGate 的一个方法，如果重放还没有结束，它把新事件加入队列并返回 true。
+// hold is a method of replayGate that adds the new event to the queue and returns true if the replay has not ended.
+func (g *replayGate) hold(e *emission, i int) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.open {
+		return false
+	}
+	g.queue = append(g.queue, replayJob{emission: e, index: i})
+	return true
+}
+
+// record 是 EventEmitter 的一个方法，如果主题设置了 WithReplayBuffer，它把事件加入主题的历史，调用方需要持有读锁。
+// 在读锁内加入历史，使注册处理函数时取得的历史与之后分发给它的事件既不重复也不遗漏。
+// record is a method of EventEmitter that adds the event to the history of the topic if WithReplayBuffer is set for the topic, the caller needs to hold the read lock.
+// Adding to the history within the read lock keeps the history taken when registering a handling function and the events dispatched to it afterwards from overlapping or missing each other.
+func (ee *EventEmitter) record(e *emission) {
+	limit, ok := ee.config.replay[e.topic]
+	if !ok {
+		return
+	}
+
+	ee.historyLock.Lock()
+	defer ee.historyLock.Unlock()
+
+	// 获取主题的历史，如果还没有，创建一个新的历史。
+	// Get the history of the topic, and create a new one if it does not exist yet.
+	buf, ok := ee.history[e.topic]
+	if !ok {
+		buf = newReplayBuffer(limit)
+		ee.history[e.topic] = buf
+	}
+
+	// 加入事件，并记录在分发状态中，使发出失败时可以标记它。
+	// Add the event, and record it in the dispatch state so that it can be marked when the emission fails.
+	ee.historySeq++
+	now := ee.config.clock.Now()
+	e.record = &replayRecord{
+		seq:         ee.historySeq,
+		at:          now,
+		topic:       e.topic,
+		id:          e.id,
+		msg:         e.msg,
+		timestamp:   e.timestamp,
+		scheduledAt: e.scheduledAt,
+		headers:     e.headers,
+	}
+	buf.push(e.record, now)
+}
+
+// recordDue 是 EventEmitter 的一个方法，它在延迟事件到期时把它加入主题的历史。
+// 保留历史的主题上，事件分发给到期时注册的处理函数，使发出之后、到期之前注册的处理函数不会错过它。
+// recordDue is a method of EventEmitter that adds the delayed event to the history of the topic when it is due.
+// On topics keeping a history, the event is dispatched to the handling functions registered when it is due, so that handling functions registered after the emission and before the due time do not miss it.
+func (ee *EventEmitter) recordDue(e *emission) {
+	if _, ok := ee.config.replay[e.topic]; !ok {
+		return
+	}
+
+	ee.lock.RLock()
+	defer ee.lock.RUnlock()
+
+	// 加入历史，并重新获取各级主题上的处理函数。需要等待结果的事件不会延迟，因此不需要重新分配结果的存储空间。
+	// Add to the history, and get the handling functions on each topic level again. Events whose results need to be waited for are not delayed, so the storage of results does not need to be reallocated.
+	ee.record(e)
+	if e.future == nil {
+		e.levels = ee.resolveLevels(e.topic)
+	}
+}
+
+// drop 是 EventEmitter 的一个方法，它标记已经进入历史但发出失败的事件，使它不会被重放。
+// drop is a method of EventEmitter that marks the event that has entered the history but failed to be emitted, so that it is not replayed.
+func (ee *EventEmitter) drop(e *emission) {
+	if e.record == nil {
+		return
+	}
+	ee.historyLock.Lock()
+	e.record.dropped = true
+	ee.historyLock.Unlock()
+}
+
+// historyOf 是 EventEmitter 的一个方法，它按进入历史的顺序返回与主题模式匹配的所有主题上保留的事件，调用方需要持有写锁。
+// historyOf is a method of EventEmitter that returns the events kept on all topics matching the topic pattern in the order they entered the history, the caller needs to hold the write lock.
+func (ee *EventEmitter) historyOf(pattern string) []*replayRecord {
+	ee.historyLock.Lock()
+	defer ee.historyLock.Unlock()
+
+	// 收集所有匹配的主题上尚未超出保留时间的事件。
+	// Collect the events within the maximum age on all matching topics.
+	var records []*replayRecord
+	now := ee.config.clock.Now()
+	for topic, buf := range ee.history {
+		if topic != pattern && !internal.MatchPattern(pattern, topic, ee.config.separator) {
+			continue
+		}
+		buf.trim(now)
+		for i := 0; i < buf.records.Len(); i++ {
+			if r := buf.records.At(i); !r.dropped {
+				records = append(records, r)
+			}
+		}
+	}
+
+	// 按进入历史的顺序排序。
+	// Sort by the order of entering the history.
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+
+	// 返回历史事件。
+	// Return the history events.
+	return records
+}
+
+// replay 是 EventEmitter 的一个方法，它按顺序把历史事件提交给使用 WithReplay 注册的处理函数，然后提交重放期间排队的新事件。
+// 历史事件只分发给这一个处理函数，不会冒泡，也不会写入预写日志。
+// replay is a method of EventEmitter that submits the history events in order to the handling function registered with WithReplay, and then submits the new events queued during the replay.
+// History events are dispatched only to this handling function, they do not bubble and are not written into the write-ahead log.
+func (ee *EventEmitter) replay(sub *subscription) {
+	g := sub.gate
+
+	// 按顺序重放历史事件，提交失败的事件会被丢弃。
+	// Replay the history events in order, events that fail to be submitted are dropped.
+	for _, r := range g.records {
+		e := &emission{
+			emitter:     ee,
+			topic:       r.topic,
+			msg:         r.msg,
+			id:          r.id,
+			timestamp:   r.timestamp,
+			scheduledAt: r.scheduledAt,
+			headers:     r.headers,
+			replayed:    true,
+		}
+		e.bind(context.Background(), [][]*subscription{{sub}})
+		_ = e.dispatch()
+	}
+	g.records = nil
+
+	// 提交重放期间排队的新事件，直到队列为空，然后结束重放。
+	// Submit the new events queued during the replay until the queue is empty, and then end the replay.
+	for {
+		g.lock.Lock()
+		jobs := g.queue
+		g.queue = nil
+		if len(jobs) == 0 {
+			g.open = true
+			g.lock.Unlock()
+			return
+		}
+		g.lock.Unlock()
+
+		for _, job := range jobs {
+			if err := ee.submit(sub, job.emission, job.index); err != nil {
+				// 提交失败时，这个处理函数以错误结束。
+				// When the submission fails, this handling function ends with the error.
+				job.emission.incomplete.Store(true)
+				job.emission.doneFunc(job.index)(nil, err)
+			}
+			ee.inflight.Done()
+		}
+	}
+}
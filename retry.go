@@ -0,0 +1,184 @@
+package events
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultRetryMaxAttempts 是默认的最大执行次数，包括第一次执行。
+	// DefaultRetryMaxAttempts is the default maximum number of attempts, including the first attempt.
+	DefaultRetryMaxAttempts = 3
+
+	// DefaultRetryInitialBackoff 是默认的第一次重试之前的等待时间。
+	// DefaultRetryInitialBackoff is the default wait time before the first retry.
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+
+	// DefaultRetryMaxBackoff 是默认的两次执行之间的最长等待时间。
+	// DefaultRetryMaxBackoff is the default maximum wait time between two attempts.
+	DefaultRetryMaxBackoff = 10 * time.Second
+
+	// DefaultRetryMultiplier 是默认的每次重试后等待时间的增长倍数。
+	// DefaultRetryMultiplier is the default growth factor of the wait time after each retry.
+	DefaultRetryMultiplier = 2.0
+
+	// DefaultRetryJitter 是默认的随机抖动比例，等待时间会随机缩短最多这个比例。
+	// DefaultRetryJitter is the default random jitter ratio, the wait time is randomly shortened by at most this ratio.
+	DefaultRetryJitter = 0.2
+)
+
+// RetryPolicy 是一个结构体，它描述处理函数失败后如何重试：最大执行次数、指数退避、随机抖动以及哪些错误可以重试。
+// RetryPolicy is a structure that describes how a handling function is retried after a failure: the maximum number of attempts, exponential backoff, random jitter, and which errors are retryable.
+type RetryPolicy struct {
+	// maxAttempts 是最大执行次数，包括第一次执行。
+	// maxAttempts is the maximum number of attempts, including the first attempt.
+	maxAttempts int
+
+	// initialBackoff 是第一次重试之前的等待时间。
+	// initialBackoff is the wait time before the first retry.
+	initialBackoff time.Duration
+
+	// maxBackoff 是两次执行之间的最长等待时间。
+	// maxBackoff is the maximum wait time between two attempts.
+	maxBackoff time.Duration
+
+	// multiplier 是每次重试后等待时间的增长倍数。
+	// multiplier is the growth factor of the wait time after each retry.
+	multiplier float64
+
+	// jitter 是随机抖动比例，取值范围为 [0, 1]。
+	// jitter is the random jitter ratio, in the range [0, 1].
+	jitter float64
+
+	// retryable 判断一个错误是否可以重试，为 nil 时所有处理失败都可以重试。
+	// retryable determines whether an error is retryable, all handling failures are retryable when it is nil.
+	retryable func(err error) bool
+}
+
+// NewRetryPolicy 是一个函数，用于创建并返回一个使用默认值的 RetryPolicy 结构体的指针。
+// NewRetryPolicy is a function that creates and returns a pointer to a new RetryPolicy structure with default values.
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		maxAttempts:    DefaultRetryMaxAttempts,
+		initialBackoff: DefaultRetryInitialBackoff,
+		maxBackoff:     DefaultRetryMaxBackoff,
+		multiplier:     DefaultRetryMultiplier,
+		jitter:         DefaultRetryJitter,
+	}
+}
+
+// DefaultRetryPolicy 是一个函数，用于创建一个默认的 RetryPolicy。
+// DefaultRetryPolicy is a function that creates a default RetryPolicy.
+func DefaultRetryPolicy() *RetryPolicy {
+	return NewRetryPolicy()
+}
+
+// WithMaxAttempts 是一个方法，用于设置最大执行次数，包括第一次执行。
+// WithMaxAttempts is a method used to set the maximum number of attempts, including the first attempt.
+func (p *RetryPolicy) WithMaxAttempts(attempts int) *RetryPolicy {
+	p.maxAttempts = attempts
+	return p
+}
+
+// WithBackoff 是一个方法，用于设置第一次重试之前的等待时间和两次执行之间的最长等待时间。
+// WithBackoff is a method used to set the wait time before the first retry and the maximum wait time between two attempts.
+func (p *RetryPolicy) WithBackoff(initial, max time.Duration) *RetryPolicy {
+	p.initialBackoff = initial
+	p.maxBackoff = max
+	return p
+}
+
+// WithMultiplier 是一个方法，用于设置每次重试后等待时间的增长倍数。
+// WithMultiplier is a method used to set the growth factor of the wait time after each retry.
+func (p *RetryPolicy) WithMultiplier(multiplier float64) *RetryPolicy {
+	p.multiplier = multiplier
+	return p
+}
+
+// WithJitter 是一个方法，用于设置随机抖动比例，等待时间会随机缩短最多这个比例。
+// WithJitter is a method used to set the random jitter ratio, the wait time is randomly shortened by at most this ratio.
+func (p *RetryPolicy) WithJitter(jitter float64) *RetryPolicy {
+	p.jitter = jitter
+	return p
+}
+
+// WithRetryable 是一个方法，用于设置判断错误是否可以重试的函数。
+// WithRetryable is a method used to set the function that determines whether an error is retryable.
+func (p *RetryPolicy) WithRetryable(fn func(err error) bool) *RetryPolicy {
+	p.retryable = fn
+	return p
+}
+
+// isRetryPolicyValid 是一个函数，用于检查重试策略是否有效，将无效的值修正为默认值。
+// isRetryPolicyValid is a function that checks whether the retry policy is valid, and corrects invalid values to the default values.
+func isRetryPolicyValid(p *RetryPolicy) *RetryPolicy {
+	// 如果重试策略为 nil，使用默认的重试策略。
+	// If the retry policy is nil, use the default retry policy.
+	if p == nil {
+		return DefaultRetryPolicy()
+	}
+
+	// 复制重试策略，避免注册之后的修改影响已注册的处理函数。
+	// Copy the retry policy to prevent modifications after registration from affecting registered handling functions.
+	c := *p
+
+	// 修正无效的值。
+	// Correct invalid values.
+	if c.maxAttempts < 1 {
+		c.maxAttempts = DefaultRetryMaxAttempts
+	}
+	if c.initialBackoff < 0 {
+		c.initialBackoff = DefaultRetryInitialBackoff
+	}
+	if c.maxBackoff < c.initialBackoff {
+		c.maxBackoff = c.initialBackoff
+	}
+	if c.multiplier < 1 {
+		c.multiplier = DefaultRetryMultiplier
+	}
+	if c.jitter < 0 || c.jitter > 1 {
+		c.jitter = DefaultRetryJitter
+	}
+
+	// 返回修正后的重试策略。
+	// Return the corrected retry policy.
+	return &c
+}
+
+// shouldRetry 是 RetryPolicy 的一个方法，它判断第 attempt 次执行返回错误之后是否需要重试。
+// 停止传播、只执行一次、上下文结束以及 EventEmitter 关闭都不会重试。
+// shouldRetry is a method of RetryPolicy that determines whether a retry is needed after the attempt-th execution returned an error.
+// Stopping propagation, run-once, context done, and EventEmitter closed are never retried.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	// 如果已经达到最大执行次数，或者错误不表示处理失败，不再重试。
+	// If the maximum number of attempts has been reached, or the error does not indicate a handling failure, do not retry.
+	if attempt >= p.maxAttempts || !isHandlingFailure(err) {
+		return false
+	}
+
+	// 使用错误分类函数判断是否可以重试。
+	// Use the error classifier to determine whether it is retryable.
+	return p.retryable == nil || p.retryable(err)
+}
+
+// backoff 是 RetryPolicy 的一个方法，它返回第 attempt 次执行失败之后、下一次执行之前的等待时间。
+// backoff is a method of RetryPolicy that returns the wait time after the attempt-th execution failed and before the next execution.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	// 按指数增长计算等待时间，并限制在最长等待时间内。
+	// Compute the wait time with exponential growth and cap it at the maximum wait time.
+	d := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if d > float64(p.maxBackoff) {
+		d = float64(p.maxBackoff)
+	}
+
+	// 随机缩短等待时间，避免大量失败的事件同时重试。
+	// Randomly shorten the wait time to prevent many failed events from retrying at the same time.
+	if p.jitter > 0 {
+		d -= d * p.jitter * rand.Float64()
+	}
+
+	// 返回等待时间。
+	// Return the wait time.
+	return time.Duration(d)
+}
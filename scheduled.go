@@ -0,0 +1,59 @@
+package events
+
+// pendingDelayed 是 EventEmitter 的一个方法，它返回所有尚未到期的延迟事件和描述它们的 Envelope，按到期时间排序。
+// pendingDelayed is a method of EventEmitter that returns all delayed events not yet due and the Envelopes describing them, sorted by due time.
+func (ee *EventEmitter) pendingDelayed() ([]*delayedEvent, []*Envelope) {
+	ee.scheduleLock.Lock()
+	defer ee.scheduleLock.Unlock()
+
+	// 收集尚未到期的延迟事件，并按到期时间排序。
+	// Collect the delayed events not yet due, and sort them by due time.
+	pending := make([]*delayedEvent, 0, len(ee.scheduled))
+	for d := range ee.scheduled {
+		pending = append(pending, d)
+	}
+	sortDelayed(pending)
+
+	// 在锁内生成 Envelope，使它与重新安排之后的到期时间一致。
+	// Build the Envelopes within the lock, so that they agree with the due times after rescheduling.
+	envelopes := make([]*Envelope, len(pending))
+	for i, d := range pending {
+		envelopes[i] = d.envelope()
+	}
+	return pending, envelopes
+}
+
+// Scheduled 是 EventEmitter 的一个方法，它返回所有尚未到期的延迟事件，按到期时间排序。
+// 每个 Envelope 包含事件的 ID、主题、数据、头部、发出时间和到期时间 ScheduledAt。Envelope 和头部是当时状态的副本，修改它们不会影响事件；
+// 数据 Payload 没有被复制，它就是之后交给处理函数的同一个值，因此不应该修改它。
+// Scheduled is a method of EventEmitter that returns all delayed events not yet due, sorted by due time.
+// Each Envelope carries the ID, topic, payload, headers, emit time and due time ScheduledAt of the event. The Envelope and its headers are copies of the state at that moment and changing them does not affect the events;
+// the Payload is not copied, it is the same value later handed to the handling functions, so it should not be modified.
+func (ee *EventEmitter) Scheduled() []*Envelope {
+	_, envelopes := ee.pendingDelayed()
+	return envelopes
+}
+
+// CancelScheduled 是 EventEmitter 的一个方法，它取消所有 filter 返回 true 的尚未到期的延迟事件，并返回取消的事件数量。filter 为 nil 时取消所有延迟事件。
+// filter 在锁外按到期时间顺序调用，调用期间到期的事件不会被取消。被取消的事件与 ScheduledEvent.Cancel 一样在预写日志中标记为完成。
+// CancelScheduled is a method of EventEmitter that cancels all delayed events not yet due for which filter returns true, and returns the number of cancelled events. All delayed events are cancelled when filter is nil.
+// filter is called outside the lock in due order, events that become due during the call are not cancelled. Cancelled events are marked as completed in the write-ahead log, the same as ScheduledEvent.Cancel.
+func (ee *EventEmitter) CancelScheduled(filter func(env *Envelope) bool) int {
+	pending, envelopes := ee.pendingDelayed()
+
+	// 取消匹配的延迟事件，只统计确实被取消的事件。
+	// Cancel the matching delayed events, counting only the events actually cancelled.
+	count := 0
+	for i, d := range pending {
+		if filter != nil && !filter(envelopes[i]) {
+			continue
+		}
+		if d.Cancel() {
+			count++
+		}
+	}
+
+	// 返回取消的事件数量。
+	// Return the number of cancelled events.
+	return count
+}
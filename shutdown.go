@@ -0,0 +1,74 @@
+package events
+
+import "context"
+
+// ShutdownPolicy 是一个类型，它决定 Shutdown 如何处理尚未到期的延迟事件。
+// ShutdownPolicy is a type that decides how Shutdown handles the delayed events that are not yet due.
+type ShutdownPolicy int
+
+const (
+	// ShutdownRunPending 表示立即分发所有尚未到期的延迟事件，并等待它们执行完毕，这是默认的策略。
+	// ShutdownRunPending means dispatching all delayed events that are not yet due immediately and waiting for them to finish, which is the default policy.
+	ShutdownRunPending ShutdownPolicy = iota
+
+	// ShutdownDiscardPending 表示丢弃所有尚未到期的延迟事件。
+	// ShutdownDiscardPending means discarding all delayed events that are not yet due.
+	ShutdownDiscardPending
+
+	// ShutdownReturnPending 表示不执行尚未到期的延迟事件，而是由 Shutdown 返回给调用方，例如用于持久化。
+	// ShutdownReturnPending means not executing the delayed events that are not yet due, but returning them from Shutdown to the caller, for example for persistence.
+	ShutdownReturnPending
+)
+
+// Shutdown 是 EventEmitter 的一个方法，它优雅地关闭 EventEmitter。
+// 它首先停止接受新的事件，之后发出事件会返回 ErrEmitterClosed；然后按配置的 ShutdownPolicy 处理尚未到期的延迟事件；
+// 接着等待所有正在执行的处理函数完成或者 ctx 结束；最后调用 Stop。
+// 使用 ShutdownReturnPending 策略时，返回按到期时间排序的延迟事件的 Envelope，否则返回 nil。如果 ctx 先结束，返回 ctx 的错误。
+// 配置了预写日志时，被丢弃或者返回的延迟事件与 Stop 一样仍然留在日志中，重启后由 Recover 重新发出。
+// Shutdown is a method of EventEmitter that gracefully shuts down the EventEmitter.
+// It first stops accepting new events, emitting events afterwards returns ErrEmitterClosed; then it handles the delayed events that are not yet due according to the configured ShutdownPolicy;
+// then it waits for all running handling functions to complete or ctx to be done; finally it calls Stop.
+// With the ShutdownReturnPending policy, it returns the Envelopes of the delayed events sorted by due time, otherwise it returns nil. If ctx is done first, the error of ctx is returned.
+// When a write-ahead log is configured, the discarded or returned delayed events stay in the log like with Stop, and are emitted again by Recover after a restart.
+func (ee *EventEmitter) Shutdown(ctx context.Context) ([]*Envelope, error) {
+	// 关闭 EventEmitter，并取走所有尚未到期的延迟事件。
+	// Close the EventEmitter and take all delayed events that are not yet due.
+	pending := ee.close()
+
+	// 取消所有周期发出的计划。
+	// Cancel all recurring schedules.
+	ee.cancelRecurring()
+
+	// 按策略处理尚未到期的延迟事件。
+	// Handle the delayed events that are not yet due according to the policy.
+	var returned []*Envelope
+	for _, d := range pending {
+		switch ee.config.shutdownPolicy {
+		case ShutdownRunPending:
+			// 立即分发延迟事件，提交失败的事件会被丢弃。
+			// Dispatch the delayed event immediately, events that fail to be submitted are dropped.
+			ee.recordDue(d.emission)
+			_ = d.emission.dispatch()
+		case ShutdownReturnPending:
+			// 记录延迟事件的 Envelope，并结束它的分发。与 Stop 一样，它不会在预写日志中标记为完成。
+			// Record the Envelope of the delayed event and end its dispatch. Like Stop, it is not marked as completed in the write-ahead log.
+			returned = append(returned, d.envelope())
+			d.emission.incomplete.Store(true)
+			d.emission.complete()
+		default:
+			// 丢弃延迟事件，并结束它的分发。与 Stop 一样，它不会在预写日志中标记为完成。
+			// Discard the delayed event and end its dispatch. Like Stop, it is not marked as completed in the write-ahead log.
+			d.emission.incomplete.Store(true)
+			d.emission.complete()
+		}
+	}
+
+	// 等待所有正在执行的处理函数完成，然后停止 EventEmitter。
+	// Wait for all running handling functions to complete, and then stop the EventEmitter.
+	err := ee.inflight.Wait(ctx)
+	ee.Stop()
+
+	// 返回交还给调用方的延迟事件和错误。
+	// Return the delayed events handed back to the caller and the error.
+	return returned, err
+}
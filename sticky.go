@@ -0,0 +1,71 @@
+package events
+
+import (
+	"sort"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// latest 是 replayBuffer 的一个方法，它返回最新的没有发出失败的事件，没有时返回 nil，调用方需要持有 historyLock。
+// latest is a method of replayBuffer that returns the latest event that did not fail to be emitted, and nil if there is none, the caller needs to hold historyLock.
+func (b *replayBuffer) latest() *replayRecord {
+	for i := b.records.Len() - 1; i >= 0; i-- {
+		if r := b.records.At(i); !r.dropped {
+			return r
+		}
+	}
+	return nil
+}
+
+// latestOf 是 EventEmitter 的一个方法，它按进入历史的顺序返回与主题模式匹配的所有粘性主题上最新的事件，调用方需要持有写锁。
+// latestOf is a method of EventEmitter that returns the latest events of all sticky topics matching the topic pattern in the order they entered the history, the caller needs to hold the write lock.
+func (ee *EventEmitter) latestOf(pattern string) []*replayRecord {
+	ee.historyLock.Lock()
+	defer ee.historyLock.Unlock()
+
+	// 收集所有匹配的粘性主题上最新的事件。
+	// Collect the latest events of all matching sticky topics.
+	var records []*replayRecord
+	now := ee.config.clock.Now()
+	for topic, buf := range ee.history {
+		if !buf.limit.sticky || (topic != pattern && !internal.MatchPattern(pattern, topic, ee.config.separator)) {
+			continue
+		}
+		buf.trim(now)
+		if r := buf.latest(); r != nil {
+			records = append(records, r)
+		}
+	}
+
+	// 按进入历史的顺序排序。
+	// Sort by the order of entering the history.
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+
+	// 返回最新的事件。
+	// Return the latest events.
+	return records
+}
+
+// Latest 是 EventEmitter 的一个方法，它返回主题上保留的最新事件的数据。主题需要通过 Config.WithStickyTopic 或 Config.WithReplayBuffer 保留事件，
+// 延迟事件在到期之后才会成为最新的事件。如果主题上没有保留的事件，第二个返回值为 false。
+// Latest is a method of EventEmitter that returns the data of the latest event kept on the topic. The topic needs to keep events through Config.WithStickyTopic or Config.WithReplayBuffer,
+// and delayed events only become the latest event after they are due. If no event is kept on the topic, the second return value is false.
+func (ee *EventEmitter) Latest(topic string) (any, bool) {
+	ee.historyLock.Lock()
+	defer ee.historyLock.Unlock()
+
+	// 获取主题的历史，并移除超出保留时间的事件。
+	// Get the history of the topic, and remove the events beyond the maximum age.
+	buf, ok := ee.history[topic]
+	if !ok {
+		return nil, false
+	}
+	buf.trim(ee.config.clock.Now())
+
+	// 返回最新的事件的数据。
+	// Return the data of the latest event.
+	if r := buf.latest(); r != nil {
+		return r.msg, true
+	}
+	return nil, false
+}
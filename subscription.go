@@ -0,0 +1,78 @@
+package events
+
+import "sync/atomic"
+
+// subscription 是一个结构体，它实现了 Subscription 接口，记录了一次消息处理函数的注册。
+// subscription is a structure that implements the Subscription interface and records one registration of a message handling function.
+type subscription struct {
+	// id 是这次注册的唯一标识。
+	// id is the unique identifier of this registration.
+	id uint64
+
+	// topic 是这次注册的主题。
+	// topic is the topic of this registration.
+	topic string
+
+	// emitter 是这次注册所属的 EventEmitter。
+	// emitter is the EventEmitter this registration belongs to.
+	emitter *EventEmitter
+
+	// fns 是这次注册的消息处理函数。
+	// fns is the message handling functions of this registration.
+	fns *handleFuncs
+
+	// active 表示这次注册是否仍然有效。
+	// active indicates whether this registration is still active.
+	active atomic.Bool
+
+	// gate 保证使用 WithReplay 注册的处理函数先收到历史事件，没有使用 WithReplay 时为 nil。
+	// gate ensures the handling function registered with WithReplay receives the history events first, it is nil when WithReplay is not used.
+	gate *replayGate
+}
+
+// newSubscription 是一个函数，它返回一个新的 subscription 实例。
+// newSubscription is a function that returns a new instance of subscription.
+func newSubscription(ee *EventEmitter, id uint64, topic string, fns *handleFuncs) *subscription {
+	// 创建一个新的 subscription 实例。
+	// Create a new instance of subscription.
+	sub := &subscription{
+		id:      id,
+		topic:   topic,
+		emitter: ee,
+		fns:     fns,
+	}
+
+	// 新的注册默认是有效的。
+	// A new registration is active by default.
+	sub.active.Store(true)
+
+	// 返回 subscription 实例。
+	// Return the instance of subscription.
+	return sub
+}
+
+// Unsubscribe 是 subscription 的一个方法，它只移除这次注册的消息处理函数。
+// 已经提交到 pipeline 中的事件仍然会被处理。
+// Unsubscribe is a method of subscription that removes only the message handling function of this registration.
+// Events that have already been submitted to the pipeline are still processed.
+func (s *subscription) Unsubscribe() {
+	s.emitter.unsubscribe(s)
+}
+
+// Topic 是 subscription 的一个方法，它返回这次注册的主题。
+// Topic is a method of subscription that returns the topic of this registration.
+func (s *subscription) Topic() string {
+	return s.topic
+}
+
+// ID 是 subscription 的一个方法，它返回这次注册的唯一标识。
+// ID is a method of subscription that returns the unique identifier of this registration.
+func (s *subscription) ID() uint64 {
+	return s.id
+}
+
+// Active 是 subscription 的一个方法，它返回这次注册是否仍然有效。
+// Active is a method of subscription that returns whether this registration is still active.
+func (s *subscription) Active() bool {
+	return s.active.Load()
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_Bubbling is a test function for testing that events bubble up to parent topics level by level
+func TestEventEmitter_Bubbling(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with bubbling enabled and "/" as the separator
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithTopicSeparator("/").WithBubbling())
+
+	// Create a channel to receive the topics in the order they are handled
+	topics := make(chan string, testMaxRounds)
+
+	// Create a handler factory that records the registered topic and checks the message
+	newHandleFunc := func(topic string) events.MessageHandleFunc {
+		return func(msg any) (any, error) {
+			assert.Equal(t, testMessage, msg.(string))
+			topics <- topic
+			return msg, nil
+		}
+	}
+
+	// Register handlers on the child topic and its parents
+	for _, topic := range []string{"user", "user/profile", "user/profile/updated"} {
+		_, err := ee.SubscribeWithTopic(topic, newHandleFunc(topic))
+		assert.NoError(t, err)
+	}
+
+	// Emit the test message on the child topic
+	err := ee.EmitWithTopic("user/profile/updated", testMessage)
+	assert.NoError(t, err)
+
+	// Assert that the event bubbles from the child topic to the root topic
+	assert.Equal(t, "user/profile/updated", <-topics)
+	assert.Equal(t, "user/profile", <-topics)
+	assert.Equal(t, "user", <-topics)
+
+	// Emitting on a topic without handlers still bubbles up to the parents
+	err = ee.EmitWithTopic("user/settings", testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", <-topics)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_StopPropagation is a test function for testing that a handler can stop the event from bubbling up
+func TestEventEmitter_StopPropagation(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with bubbling enabled and "/" as the separator
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithTopicSeparator("/").WithBubbling())
+
+	// Create a channel to receive the topics in the order they are handled
+	topics := make(chan string, testMaxRounds)
+
+	// Register handlers, the middle one stops the propagation
+	_, err := ee.SubscribeWithTopic("user", func(msg any) (any, error) { topics <- "user"; return msg, nil })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("user/profile", func(msg any) (any, error) {
+		topics <- "user/profile"
+		return nil, events.ErrorStopPropagation
+	})
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("user/profile/updated", func(msg any) (any, error) { topics <- "user/profile/updated"; return msg, nil })
+	assert.NoError(t, err)
+
+	// Emit the test message on the child topic
+	err = ee.EmitWithTopic("user/profile/updated", testMessage)
+	assert.NoError(t, err)
+
+	// Assert that the event stops at the middle topic
+	assert.Equal(t, "user/profile/updated", <-topics)
+	assert.Equal(t, "user/profile", <-topics)
+
+	// Sleep for a while to make sure the root topic is not handled
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, topics, 0)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_TopicValidation is a test function for testing the validation of topic names and patterns
+func TestEventEmitter_TopicValidation(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with bubbling enabled and "/" as the separator
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithTopicSeparator("/").WithBubbling())
+
+	// Create a new handler with the testing.T
+	handler := &handler{t: t}
+
+	// Patterns with empty levels are rejected
+	for _, pattern := range []string{"", "user//profile", "/user", "user/"} {
+		_, err := ee.SubscribeWithTopic(pattern, handler.testTopicMsgHandleFunc)
+		assert.Equal(t, events.ErrorTopicPatternInvalid, err, pattern)
+	}
+
+	// Register a handler on a wildcard pattern
+	_, err := ee.SubscribeWithTopic("user/#", handler.testTopicMsgHandleFunc)
+	assert.NoError(t, err)
+
+	// Topic names with empty levels or wildcards are rejected
+	for _, topic := range []string{"", "user//profile", "user/+", "user/#"} {
+		assert.Equal(t, events.ErrorTopicInvalid, ee.EmitWithTopic(topic, testMessage), topic)
+	}
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_TopicNamesDefault is a test function for testing that any topic name is accepted without bubbling and wildcard patterns
+func TestEventEmitter_TopicNamesDefault(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the default configuration
+	ee := events.NewEventEmitter(pl)
+
+	// Create a channel to receive the topics in the order they are handled
+	topics := make(chan string, testMaxRounds)
+
+	// Topic names that the hierarchy would reject can be registered and emitted on
+	names := []string{"", "a..b", ".a", "a."}
+	for _, topic := range names {
+		topic := topic
+		ee.RegisterWithTopic(topic, func(msg any) (any, error) { topics <- topic; return msg, nil })
+	}
+	for _, topic := range names {
+		assert.NoError(t, ee.EmitWithTopic(topic, testMessage), topic)
+		assert.Equal(t, topic, <-topics)
+	}
+
+	// Topics with wildcard levels are not rejected as long as no wildcard pattern is registered
+	assert.Equal(t, events.ErrorTopicNotExists, ee.EmitWithTopic("a.+", testMessage))
+
+	// Once a wildcard pattern is registered, the topic names are validated
+	_, err := ee.SubscribeWithTopic("orders.#", func(msg any) (any, error) { return msg, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, events.ErrorTopicInvalid, ee.EmitWithTopic("a..b", testMessage))
+	assert.NoError(t, ee.EmitWithTopic("orders.eu", testMessage))
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_BubblingRejected is a test function for testing that a failed submission to a parent topic is reported
+func TestEventEmitter_BubblingRejected(t *testing.T) {
+
+	// Create a new synchronous pipeline and an event emitter with bubbling and in-memory metrics
+	pl := pipeline.NewSyncPipeline()
+	metrics := events.NewMemoryMetrics()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithTopicSeparator("/").WithBubbling().WithMetricsRecorder(metrics))
+
+	// The handler on the child topic stops the pipeline, so the parent topics cannot be submitted to
+	parents := 0
+	_, err := ee.SubscribeWithTopic("user/profile", func(msg any) (any, error) { pl.Stop(); return msg, nil })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("user", func(msg any) (any, error) { parents++; return msg, nil })
+	assert.NoError(t, err)
+
+	// The emission is accepted, the parent is skipped and the failure is recorded as a rejection
+	assert.NoError(t, ee.EmitWithTopic("user/profile", testMessage))
+	assert.Equal(t, 0, parents)
+	stats := metrics.Stats()["user/profile"]
+	assert.Equal(t, uint64(1), stats.Emitted)
+	assert.Equal(t, uint64(1), stats.Rejected)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/clock"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// testClockStart is the start time of the fake clocks used in tests
+var testClockStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestFakeClock_AfterFunc is a test function for testing that callbacks fire in due order when the fake clock advances
+func TestFakeClock_AfterFunc(t *testing.T) {
+
+	// Create a new fake clock
+	fc := clock.NewFakeClock(testClockStart)
+
+	// Set callbacks out of order, one of them setting another callback
+	var fired []int
+	var at []time.Time
+	record := func(i int) func() {
+		return func() { fired = append(fired, i); at = append(at, fc.Now()) }
+	}
+	fc.AfterFunc(3*time.Second, record(3))
+	fc.AfterFunc(time.Second, func() {
+		record(1)()
+		fc.AfterFunc(time.Second, record(2))
+	})
+	stopped := fc.AfterFunc(2*time.Second, record(0))
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+	assert.Equal(t, 2, fc.Len())
+
+	// Callbacks run at their due times, including the one set while advancing
+	fc.Advance(3 * time.Second)
+	assert.Equal(t, []int{1, 2, 3}, fired)
+	assert.Equal(t, []time.Time{testClockStart.Add(time.Second), testClockStart.Add(2 * time.Second), testClockStart.Add(3 * time.Second)}, at)
+	assert.Equal(t, 0, fc.Len())
+
+}
+
+// TestFakeClock_NewTimer is a test function for testing the channel timers of the fake clock
+func TestFakeClock_NewTimer(t *testing.T) {
+
+	// Create a new fake clock and a timer
+	fc := clock.NewFakeClock(testClockStart)
+	timer := fc.NewTimer(time.Minute)
+
+	// The timer does not fire before it is due
+	fc.Advance(59 * time.Second)
+	select {
+	case <-timer.C():
+		assert.Fail(t, "timer fired early")
+	default:
+	}
+
+	// Reset moves the due time relative to the current virtual time
+	assert.True(t, timer.Reset(time.Minute))
+	fc.Advance(time.Minute)
+	assert.Equal(t, testClockStart.Add(119*time.Second), <-timer.C())
+	assert.False(t, timer.Stop())
+
+}
+
+// TestClock_EventEmitter is a test function for testing that envelopes carry the time of the injected clock
+func TestClock_EventEmitter(t *testing.T) {
+
+	// Create a new event emitter sharing the clock of a synchronous pipeline
+	pl := pipeline.NewSyncPipelineWithClock(clock.NewFakeClock(testClockStart))
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(pl.Clock()))
+	defer ee.Stop()
+
+	// Register a handler that records the envelopes
+	var envelopes []*events.Envelope
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) { envelopes = append(envelopes, env); return nil, nil })
+	assert.NoError(t, err)
+
+	// Emit a delayed event and advance the clock past its due time
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, time.Hour))
+	pl.Advance(time.Hour)
+
+	// The envelope timestamps come from the virtual clock
+	assert.Len(t, envelopes, 1)
+	assert.Equal(t, testClockStart, envelopes[0].Timestamp)
+	assert.Equal(t, testClockStart.Add(time.Hour), envelopes[0].ScheduledAt)
+
+}
+
+// TestClock_Pipeline is a test function for testing the worker-pool pipeline with a fake clock
+func TestClock_Pipeline(t *testing.T) {
+
+	// Create a new built-in pipeline and event emitter sharing a fake clock
+	fc := clock.NewFakeClock(testClockStart)
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(2).WithClock(fc))
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(fc))
+	defer ee.Stop()
+
+	// Register a handler that forwards the envelopes
+	received := make(chan *events.Envelope, 1)
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) { received <- env; return nil, nil })
+	assert.NoError(t, err)
+
+	// A delayed event a day away is not executed until the clock advances
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, 24*time.Hour))
+	select {
+	case <-received:
+		assert.Fail(t, "delayed event executed early")
+	default:
+	}
+
+	// Advancing the clock executes it without real waiting
+	fc.Advance(24 * time.Hour)
+	select {
+	case env := <-received:
+		assert.Equal(t, testClockStart.Add(24*time.Hour), env.ScheduledAt)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "delayed event not executed")
+	}
+
+}
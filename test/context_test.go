@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantKey is the context key type used for request-scoped values in tests
+type tenantKey struct{}
+
+// TestEventEmitter_EmitWithContext is a test function for testing that context values reach context-aware handlers
+func TestEventEmitter_EmitWithContext(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a context-aware handler that reports the tenant from the context
+	tenants := make(chan any, 1)
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		tenants <- ctx.Value(tenantKey{})
+		return msg, nil
+	})
+	assert.NoError(t, err)
+
+	// Emit with a request-scoped value
+	ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+	assert.NoError(t, ee.EmitWithContext(ctx, testTopic, testMessage))
+	assert.Equal(t, "tenant-a", <-tenants)
+
+	// Plain emits deliver a context without the value
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	assert.Nil(t, <-tenants)
+
+	// A context that is already done is rejected at emit
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, ee.EmitWithContext(cancelled, testTopic, testMessage))
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EmitAndWaitContextCancel is a test function for testing that handlers observe the cancellation of the emitting request
+func TestEventEmitter_EmitAndWaitContextCancel(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that waits for its context to be cancelled
+	started := make(chan struct{})
+	observed := make(chan error, 1)
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		close(started)
+		<-ctx.Done()
+		observed <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	// Cancel the request once the handler is running
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	// The caller and the handler both see the cancellation
+	result, err := ee.EmitAndWait(ctx, testTopic, testMessage)
+	assert.Nil(t, result)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, <-observed)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_StopCancelsContext is a test function for testing that handlers observe the cancellation of Stop
+func TestEventEmitter_StopCancelsContext(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register handlers that wait for their context to be cancelled
+	started := make(chan struct{}, 2)
+	observed := make(chan error, 2)
+	handler := func(ctx context.Context, msg any) (any, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			observed <- ctx.Err()
+		case <-time.After(10 * time.Second):
+			observed <- nil
+		}
+		return nil, nil
+	}
+	_, err := ee.RegisterContextWithTopic(testTopic, handler)
+	assert.NoError(t, err)
+	_, err = ee.RegisterContextWithTopic("topic2", handler)
+	assert.NoError(t, err)
+
+	// Emit one event without a caller context and one with a cancellable caller context
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, ee.EmitWithContext(ctx, "topic2", testMessage))
+	<-started
+	<-started
+
+	// Stopping the emitter cancels both handler contexts
+	go ee.Stop()
+	assert.Equal(t, context.Canceled, <-observed)
+	assert.Equal(t, context.Canceled, <-observed)
+
+}
+
+// TestTopic_RegisterContext is a test function for testing the context-aware methods of typed topics
+func TestTopic_RegisterContext(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a typed topic
+	topic, err := events.NewTopic[orderCreated, string](ee, "orders.created")
+	assert.NoError(t, err)
+
+	// Register a typed context-aware handler
+	tenants := make(chan any, 1)
+	_, err = topic.RegisterContext(func(ctx context.Context, msg orderCreated) (string, error) {
+		tenants <- ctx.Value(tenantKey{})
+		return msg.ID, nil
+	})
+	assert.NoError(t, err)
+
+	// Emit with a request-scoped value
+	ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-b")
+	assert.NoError(t, topic.EmitWithContext(ctx, orderCreated{ID: "o-1"}))
+	assert.Equal(t, "tenant-b", <-tenants)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
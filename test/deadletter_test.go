@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// errTransient is a handler error used for testing failures
+var errTransient = errors.New("transient failure")
+
+// deadLetterSink is a dead-letter sink that forwards dead letters to a channel
+type deadLetterSink struct {
+	letters chan *events.DeadLetter
+}
+
+// HandleDeadLetter forwards the dead letter to the channel
+func (s *deadLetterSink) HandleDeadLetter(dl *events.DeadLetter) {
+	s.letters <- dl
+}
+
+// TestEventEmitter_DeadLetterSink is a test function for testing that failed events are handed to the dead-letter sink
+func TestEventEmitter_DeadLetterSink(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with a dead-letter sink
+	sink := &deadLetterSink{letters: make(chan *events.DeadLetter, 4)}
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithDeadLetterSink(sink))
+
+	// Register a failing handler and a handler that stops propagation
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, errTransient })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, events.ErrorStopPropagation })
+	assert.NoError(t, err)
+
+	// Only the failure reaches the sink
+	_, err = ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Equal(t, errTransient, err)
+	assert.Len(t, sink.letters, 1)
+	dl := <-sink.letters
+	assert.Equal(t, errTransient, dl.Err)
+	assert.Equal(t, 1, dl.Attempts)
+	assert.Equal(t, testTopic, dl.Envelope.Topic)
+	assert.Equal(t, testMessage, dl.Envelope.Payload)
+	assert.NotEmpty(t, dl.Envelope.ID)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_DeadLetterStop is a test function for testing that a handler interrupted by Stop is not a dead letter
+func TestEventEmitter_DeadLetterStop(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with a dead-letter sink and the dead-letter topic enabled
+	sink := &deadLetterSink{letters: make(chan *events.DeadLetter, 4)}
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithDeadLetterSink(sink).WithDeadLetterTopic())
+
+	// Register a handler that returns the error of its context once it is cancelled
+	started := make(chan struct{})
+	returned := make(chan error, 1)
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		close(started)
+		<-ctx.Done()
+		returned <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	// Stop the event emitter while the handler is running
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	<-started
+	ee.Stop()
+	assert.Equal(t, context.Canceled, <-returned)
+
+	// The interrupted execution does not reach the sink
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, sink.letters, 0)
+
+}
+
+// TestEventEmitter_DeadLetterTopic is a test function for testing that failed events are re-emitted on the dead-letter topic and can be requeued
+func TestEventEmitter_DeadLetterTopic(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the dead-letter topic enabled
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithDeadLetterTopic())
+
+	// Register a handler that fails on the first attempt only
+	var calls atomic.Int64
+	done := make(chan any, 1)
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		if calls.Add(1) == 1 {
+			return nil, errTransient
+		}
+		done <- msg
+		return msg, nil
+	})
+	assert.NoError(t, err)
+
+	// Register a dead-letter handler that also fails, which must not loop
+	letters := make(chan *events.DeadLetter, 4)
+	_, err = ee.SubscribeWithTopic(events.DeadLetterTopic, func(msg any) (any, error) {
+		letters <- msg.(*events.DeadLetter)
+		return nil, errTransient
+	})
+	assert.NoError(t, err)
+
+	// The failed event arrives on the dead-letter topic with its headers
+	ctx := events.ContextWithHeaders(context.Background(), map[string]string{"user": "u-1"})
+	assert.NoError(t, ee.EmitWithContext(ctx, testTopic, testMessage))
+	dl := <-letters
+	assert.Equal(t, errTransient, dl.Err)
+	assert.Equal(t, testTopic, dl.Envelope.Topic)
+	assert.Equal(t, "u-1", dl.Envelope.Header("user"))
+
+	// Requeueing emits the event on its original topic again
+	assert.NoError(t, ee.Requeue(dl))
+	assert.Equal(t, testMessage, <-done)
+	assert.Len(t, letters, 0)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
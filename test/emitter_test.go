@@ -620,3 +620,107 @@ func TestEventEmitter_ResetOnce(t *testing.T) {
 	ee.Stop()
 
 }
+
+// TestEventEmitter_RegisterWithTopicFanOut is a test function for testing that every handler registered on a topic receives the event
+func TestEventEmitter_RegisterWithTopicFanOut(t *testing.T) {
+
+	// Create a new configuration
+	c := k.NewConfig()
+
+	// Create a new fake delaying queue
+	queue := k.NewFakeDelayingQueue(wkq.NewQueue(nil))
+
+	// Create a new pipeline with the queue and configuration
+	pl := k.NewPipeline(queue, c)
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a wait group to wait for all handlers
+	wg := sync.WaitGroup{}
+
+	// Count the number of handlers that received the message
+	var lock sync.Mutex
+	received := make(map[string]int)
+
+	// Create a handler factory that records the handler name
+	newHandleFunc := func(name string) events.MessageHandleFunc {
+		return func(msg any) (any, error) {
+			defer wg.Done()
+			lock.Lock()
+			received[name]++
+			lock.Unlock()
+			assert.Equal(t, testMessage, msg.(string))
+			return msg, nil
+		}
+	}
+
+	// Register three independent handlers on the test topic
+	ee.RegisterWithTopic(testTopic, newHandleFunc("audit"))
+	ee.RegisterWithTopic(testTopic, newHandleFunc("cache"))
+	ee.RegisterWithTopic(testTopic, newHandleFunc("metrics"))
+
+	// Check that all handlers are registered in order
+	fns, err := ee.GetMessageHandleFuncs(testTopic)
+	assert.NoError(t, err)
+	assert.Len(t, fns, 3)
+
+	// Emit the test message with the test topic for testMaxRounds times
+	wg.Add(3 * testMaxRounds)
+	for i := 0; i < testMaxRounds; i++ {
+		err = ee.EmitWithTopic(testTopic, testMessage)
+		assert.NoError(t, err)
+	}
+
+	// Wait for all handlers to finish
+	wg.Wait()
+
+	// Assert that every handler received every message
+	assert.Equal(t, map[string]int{"audit": testMaxRounds, "cache": testMaxRounds, "metrics": testMaxRounds}, received)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_RegisterWithTopicReplace is a test function for testing the replace mode of RegisterWithTopic
+func TestEventEmitter_RegisterWithTopicReplace(t *testing.T) {
+
+	// Create a new configuration
+	c := k.NewConfig()
+
+	// Create a new fake delaying queue
+	queue := k.NewFakeDelayingQueue(wkq.NewQueue(nil))
+
+	// Create a new pipeline with the queue and configuration
+	pl := k.NewPipeline(queue, c)
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a channel to receive the name of the handler that was executed
+	names := make(chan string, 2)
+
+	// Register two handlers, the second one replaces the first one
+	ee.RegisterWithTopic(testTopic, func(msg any) (any, error) { names <- "first"; return msg, nil })
+	ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { names <- "second"; return msg, nil }, events.WithReplace())
+
+	// Check that only one handler is registered
+	fns, err := ee.GetMessageHandleFuncs(testTopic)
+	assert.NoError(t, err)
+	assert.Len(t, fns, 1)
+
+	// Emit the test message with the test topic
+	err = ee.EmitWithTopic(testTopic, testMessage)
+	assert.NoError(t, err)
+
+	// Assert that only the replacing handler was executed
+	assert.Equal(t, "second", <-names)
+
+	// Stop the event emitter
+	ee.Stop()
+
+	// Assert that no other handler was executed
+	assert.Len(t, names, 0)
+
+}
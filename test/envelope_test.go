@@ -0,0 +1,97 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_RegisterEnvelope is a test function for testing the metadata of the envelope received by handlers
+func TestEventEmitter_RegisterEnvelope(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register two envelope handlers on the same topic
+	envelopes := make(chan *events.Envelope, 2)
+	handler := func(env *events.Envelope) (any, error) {
+		envelopes <- env
+		return env.ID, nil
+	}
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, handler)
+	assert.NoError(t, err)
+	_, err = ee.RegisterEnvelopeWithTopic(testTopic, handler)
+	assert.NoError(t, err)
+
+	// Emit with headers carried by the context
+	before := time.Now()
+	ctx := events.ContextWithHeaders(context.Background(), map[string]string{"tenant": "t-1"})
+	ctx = events.ContextWithHeaders(ctx, map[string]string{"trace": "abc"})
+	id, err := ee.EmitAndWait(ctx, testTopic, testMessage)
+	assert.NoError(t, err)
+
+	// Both handlers see the same metadata and their own copy of the headers
+	first, second := <-envelopes, <-envelopes
+	for _, env := range []*events.Envelope{first, second} {
+		assert.Equal(t, id, env.ID)
+		assert.Equal(t, testTopic, env.Topic)
+		assert.Equal(t, testMessage, env.Payload)
+		assert.Equal(t, 1, env.Attempt)
+		assert.False(t, env.Timestamp.Before(before))
+		assert.Equal(t, env.Timestamp, env.ScheduledAt)
+		assert.Equal(t, map[string]string{"tenant": "t-1", "trace": "abc"}, env.Headers)
+		assert.Equal(t, "t-1", env.Header("tenant"))
+	}
+	first.Headers["tenant"] = "changed"
+	assert.Equal(t, "t-1", second.Header("tenant"))
+
+	// Every emission gets a new identifier
+	next, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, next)
+	<-envelopes
+	<-envelopes
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EnvelopeScheduledAt is a test function for testing the scheduled time of delayed events
+func TestEventEmitter_EnvelopeScheduledAt(t *testing.T) {
+
+	// Create a new pipeline with a real delaying queue
+	pl := k.NewPipeline(wkq.NewDelayingQueue(nil), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register an envelope handler
+	envelopes := make(chan *events.Envelope, 1)
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) {
+		envelopes <- env
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	// Emit after a delay
+	delay := 100 * time.Millisecond
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, delay))
+
+	// The scheduled time is the emit time plus the delay
+	env := <-envelopes
+	assert.Equal(t, env.Timestamp.Add(delay), env.ScheduledAt)
+	assert.False(t, time.Now().Before(env.ScheduledAt))
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_EmitAsync is a test function for testing that many events can be emitted and joined with futures
+func TestEventEmitter_EmitAsync(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig().WithWorkerNumber(4))
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that squares the input
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return msg.(int) * msg.(int), nil })
+	assert.NoError(t, err)
+
+	// Fire many events first
+	futures := make([]events.Future, testMaxRounds)
+	for i := 0; i < testMaxRounds; i++ {
+		futures[i] = ee.EmitAsync(testTopic, i)
+	}
+
+	// Then join on their outcomes
+	for i, f := range futures {
+		<-f.Done()
+		result, err := f.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, i*i, result)
+	}
+
+	// A failed emission returns a completed future with the error
+	f := ee.EmitAsync("topic.unknown", testMessage)
+	<-f.Done()
+	result, err := f.Result()
+	assert.Nil(t, result)
+	assert.Equal(t, events.ErrorTopicNotExists, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EmitAsyncCancel is a test function for testing the Cancel method of Future
+func TestEventEmitter_EmitAsyncCancel(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a slow handler
+	release := make(chan struct{})
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { <-release; return msg, nil })
+	assert.NoError(t, err)
+
+	// Cancel the future before the handler finishes
+	f := ee.EmitAsync(testTopic, testMessage)
+	f.Cancel()
+
+	// The future is done and reports the cancellation
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("future is not done after cancel")
+	}
+	result, err := f.Result()
+	assert.Nil(t, result)
+	assert.Equal(t, events.ErrorFutureCancelled, err)
+
+	// Cancelling a completed future does nothing
+	close(release)
+	done := ee.EmitAsync(testTopic, testMessage)
+	result, err = done.Result()
+	assert.NoError(t, err)
+	done.Cancel()
+	result, err = done.Result()
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, result)
+
+	// A typed topic returns futures as well
+	topic, err := events.NewTopic[orderCreated, int](ee, "orders.created")
+	assert.NoError(t, err)
+	_, err = topic.Register(func(msg orderCreated) (int, error) { return msg.Amount, nil })
+	assert.NoError(t, err)
+	result, err = topic.EmitAsync(orderCreated{Amount: 1}).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -4,16 +4,38 @@ go 1.19
 
 replace github.com/shengyanli1982/events => ../
 
+replace github.com/shengyanli1982/events/contrib/prometheus => ../contrib/prometheus
+
+replace github.com/shengyanli1982/events/contrib/otel => ../contrib/otel
+
 require (
+	github.com/prometheus/client_golang v1.17.0
 	github.com/shengyanli1982/events v0.0.0-00010101000000-000000000000
+	github.com/shengyanli1982/events/contrib/otel v0.0.0-00010101000000-000000000000
+	github.com/shengyanli1982/events/contrib/prometheus v0.0.0-00010101000000-000000000000
 	github.com/shengyanli1982/karta v0.2.4
 	github.com/shengyanli1982/workqueue/v2 v2.2.4
 	github.com/stretchr/testify v1.8.4
+	go.opentelemetry.io/otel v1.16.0
+	go.opentelemetry.io/otel/sdk v1.16.0
+	go.opentelemetry.io/otel/trace v1.16.0
 )
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.4 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.4.1-0.20230718164431-9a2bf3000d16 // indirect
+	github.com/prometheus/common v0.44.0 // indirect
+	github.com/prometheus/procfs v0.11.1 // indirect
+	go.opentelemetry.io/otel/metric v1.16.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/time v0.5.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
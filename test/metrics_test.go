@@ -0,0 +1,122 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/clock"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics_MemoryMetrics is a test function for testing the per-topic counters of the in-memory metrics recorder
+func TestMetrics_MemoryMetrics(t *testing.T) {
+
+	// Create a new event emitter recording metrics in memory
+	metrics := events.NewMemoryMetrics()
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithMetricsRecorder(metrics))
+	defer ee.Stop()
+
+	// Register two handlers on the first topic, a failing handler and a panicking handler behind Recovery
+	ok := func(msg any) (any, error) { return msg, nil }
+	_, err := ee.SubscribeWithTopic(testTopic, ok)
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic(testTopic, ok)
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("failing", func(msg any) (any, error) { return nil, errTransient })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("panicking", func(msg any) (any, error) { panic("boom") }, events.WithMiddleware(events.Recovery()))
+	assert.NoError(t, err)
+
+	// Emit events on every topic, and on a topic without handlers
+	for i := 0; i < testMaxRounds; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+	}
+	assert.NoError(t, ee.EmitWithTopic("failing", testMessage))
+	assert.NoError(t, ee.EmitWithTopic("panicking", testMessage))
+	assert.ErrorIs(t, ee.EmitWithTopic("missing", testMessage), events.ErrorTopicNotExists)
+
+	// Verify the counters of each topic
+	stats := metrics.Stats()
+	assert.Equal(t, uint64(testMaxRounds), stats[testTopic].Emitted)
+	assert.Equal(t, uint64(testMaxRounds*2), stats[testTopic].Submitted)
+	assert.Equal(t, uint64(testMaxRounds*2), stats[testTopic].Succeeded)
+	assert.Equal(t, uint64(testMaxRounds*2), stats[testTopic].HandlerDuration.Count)
+	assert.Equal(t, uint64(testMaxRounds*2), stats[testTopic].QueueWait.Count)
+	assert.Equal(t, uint64(1), stats["failing"].Failed)
+	assert.Equal(t, uint64(1), stats["panicking"].Panicked)
+	assert.Equal(t, uint64(1), stats["missing"].Rejected)
+	assert.Equal(t, uint64(0), stats["missing"].Emitted)
+
+}
+
+// TestMetrics_Latency is a test function for testing the queue wait and handler duration histograms
+func TestMetrics_Latency(t *testing.T) {
+
+	// Create a new event emitter whose handler advances the shared fake clock
+	metrics := events.NewMemoryMetrics(time.Second, time.Minute)
+	fc := clock.NewFakeClock(testClockStart)
+	pl := pipeline.NewSyncPipelineWithClock(fc)
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(fc).WithMetricsRecorder(metrics))
+	defer ee.Stop()
+
+	// Register a handler that takes 30 virtual seconds
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { fc.Set(fc.Now().Add(30 * time.Second)); return nil, nil })
+	assert.NoError(t, err)
+
+	// Emit one immediate and one delayed event
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, time.Hour))
+	pl.Advance(time.Hour)
+
+	// Both handlers took 30 seconds, and the delayed event did not wait in the queue after it was due
+	stats := metrics.Stats()[testTopic]
+	assert.Equal(t, uint64(2), stats.HandlerDuration.Count)
+	assert.Equal(t, time.Minute, stats.HandlerDuration.Sum)
+	assert.Equal(t, 30*time.Second, stats.HandlerDuration.Mean())
+	assert.Equal(t, []events.LatencyBucket{{UpperBound: time.Second, Count: 0}, {UpperBound: time.Minute, Count: 2}}, stats.HandlerDuration.Buckets)
+	assert.Equal(t, time.Duration(0), stats.QueueWait.Max)
+
+	// Snapshots do not change when more events are recorded
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	assert.Equal(t, uint64(2), stats.HandlerDuration.Count)
+	assert.Equal(t, uint64(2), stats.HandlerDuration.Buckets[1].Count)
+
+}
+
+// TestMetrics_Panicked is a test function for testing that unrecovered panics are recorded
+func TestMetrics_Panicked(t *testing.T) {
+
+	// Create a new event emitter on the built-in pipeline, which recovers panics itself
+	metrics := events.NewMemoryMetrics()
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithMetricsRecorder(metrics))
+	defer ee.Stop()
+
+	// Register a handler that panics without Recovery
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { panic(errors.New("boom")) })
+	assert.NoError(t, err)
+
+	// Wait for the handler to finish
+	_, _ = ee.EmitAndWait(context.Background(), testTopic, testMessage)
+
+	// The execution is recorded as panicked
+	stats := metrics.Stats()[testTopic]
+	assert.Equal(t, uint64(1), stats.Panicked)
+	assert.Equal(t, uint64(0), stats.Failed)
+
+}
+
+// TestMetrics_OutcomeOf is a test function for testing the classification of handler errors shared by the metrics and tracing
+func TestMetrics_OutcomeOf(t *testing.T) {
+	assert.Equal(t, events.OutcomeSucceeded, events.OutcomeOf(nil))
+	assert.Equal(t, events.OutcomeSucceeded, events.OutcomeOf(events.ErrorStopPropagation))
+	assert.Equal(t, events.OutcomeSucceeded, events.OutcomeOf(fmt.Errorf("wrapped: %w", events.ErrorTopicExecutedOnce)))
+	assert.Equal(t, events.OutcomeFailed, events.OutcomeOf(errTransient))
+	assert.Equal(t, events.OutcomePanicked, events.OutcomeOf(fmt.Errorf("%w: boom", events.ErrorHandlerPanicked)))
+}
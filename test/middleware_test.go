@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordMiddleware returns a middleware that appends its name and the topic of the envelope to the records
+func recordMiddleware(name string, lock *sync.Mutex, records *[]string) events.Middleware {
+	return func(next events.MessageHandleFunc) events.MessageHandleFunc {
+		return func(msg any) (any, error) {
+			lock.Lock()
+			*records = append(*records, name+":"+msg.(*events.Envelope).Topic)
+			lock.Unlock()
+			return next(msg)
+		}
+	}
+}
+
+// TestEventEmitter_UseComposeOnce is a test function for testing that the middleware chain is composed once and again only after Use
+func TestEventEmitter_UseComposeOnce(t *testing.T) {
+
+	// Create a new event emitter with a middleware counting how often it is composed
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+	var composed atomic.Int64
+	counting := func(next events.MessageHandleFunc) events.MessageHandleFunc {
+		composed.Add(1)
+		return next
+	}
+	ee.Use(counting)
+
+	// The chain is composed at registration and reused for every event
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return msg, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), composed.Load())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	}
+	assert.Equal(t, int64(1), composed.Load())
+
+	// Adding a middleware composes the chain again once, on the next event
+	ee.Use(func(next events.MessageHandleFunc) events.MessageHandleFunc { return next })
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	}
+	assert.Equal(t, int64(2), composed.Load())
+
+}
+
+// TestEventEmitter_Use is a test function for testing the order of emitter-level and per-subscription middlewares
+func TestEventEmitter_Use(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler with its own middleware
+	var lock sync.Mutex
+	var records []string
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		lock.Lock()
+		records = append(records, "handler")
+		lock.Unlock()
+		return msg, nil
+	}, events.WithMiddleware(recordMiddleware("sub", &lock, &records)))
+	assert.NoError(t, err)
+
+	// Add emitter-level middlewares after the registration
+	ee.Use(recordMiddleware("first", &lock, &records), recordMiddleware("second", &lock, &records))
+
+	// Emitter-level middlewares run first, in order, then the per-subscription ones
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, result)
+	assert.Equal(t, []string{"first:" + testTopic, "second:" + testTopic, "sub:" + testTopic, "handler"}, records)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_MiddlewareContext is a test function for testing that middlewares can pass context values and short-circuit handlers
+func TestEventEmitter_MiddlewareContext(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Add an auth middleware that rejects anonymous messages and stores the tenant in the context
+	errUnauthorized := errors.New("unauthorized")
+	ee.Use(func(next events.MessageHandleFunc) events.MessageHandleFunc {
+		return func(msg any) (any, error) {
+			env := msg.(*events.Envelope)
+			if env.Payload == "anonymous" {
+				return nil, errUnauthorized
+			}
+			return next(env.WithContext(context.WithValue(env.Context(), tenantKey{}, "tenant-c")))
+		}
+	})
+
+	// Register a context-aware handler that returns the tenant
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		return ctx.Value(tenantKey{}), nil
+	})
+	assert.NoError(t, err)
+
+	// The handler sees the value set by the middleware
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-c", result)
+
+	// The middleware short-circuits rejected messages
+	result, err = ee.EmitAndWait(context.Background(), testTopic, "anonymous")
+	assert.Nil(t, result)
+	assert.Equal(t, errUnauthorized, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_Recovery is a test function for testing the Recovery middleware
+func TestEventEmitter_Recovery(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that panics, recovered by a per-subscription middleware
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		panic("boom")
+	}, events.WithMiddleware(events.Recovery()))
+	assert.NoError(t, err)
+
+	// The panic is returned as an error
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, events.ErrorHandlerPanicked))
+	assert.Contains(t, err.Error(), "boom")
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	eotel "github.com/shengyanli1982/events/contrib/otel"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracedEmitter creates an event emitter on the built-in pipeline that propagates traces with the given configuration
+func newTracedEmitter(conf *eotel.Config) *events.EventEmitter {
+	ee := events.NewEventEmitterWithConfig(pipeline.NewPipeline(nil), events.NewConfig().WithHeaderInjector(eotel.NewHeaderInjector(conf)))
+	ee.Use(eotel.NewMiddleware(conf))
+	return ee
+}
+
+// TestOtel_ChildSpan is a test function for testing that handler spans are children of the emitting span
+func TestOtel_ChildSpan(t *testing.T) {
+
+	// Create a tracer provider exporting to memory
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ee := newTracedEmitter(eotel.NewConfig().WithTracerProvider(tp))
+	defer ee.Stop()
+
+	// Register a handler that returns the span context it observes, and a failing handler
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		return trace.SpanContextFromContext(ctx), nil
+	})
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("failing", func(msg any) (any, error) { return nil, errTransient })
+	assert.NoError(t, err)
+
+	// Emit within a parent span
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	result, err := ee.EmitAndWait(ctx, testTopic, testMessage)
+	assert.NoError(t, err)
+	_, err = ee.EmitAndWait(ctx, "failing", testMessage)
+	assert.ErrorIs(t, err, errTransient)
+	parent.End()
+
+	// The handler observed a span of the same trace
+	observed := result.(trace.SpanContext)
+	assert.Equal(t, parent.SpanContext().TraceID(), observed.TraceID())
+
+	// Both handler spans are children of the emitting span, and the failing one records the error
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	byName := make(map[string]tracetest.SpanStub)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	handled := byName[testTopic+" process"]
+	assert.Equal(t, trace.SpanKindConsumer, handled.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), handled.Parent.SpanID())
+	assert.Equal(t, observed.SpanID(), handled.SpanContext.SpanID())
+	failed := byName["failing process"]
+	assert.Equal(t, parent.SpanContext().SpanID(), failed.Parent.SpanID())
+	assert.Equal(t, codes.Error, failed.Status.Code)
+
+}
+
+// TestOtel_Headers is a test function for testing that the span context is injected into the envelope headers
+func TestOtel_Headers(t *testing.T) {
+
+	// Create a tracer provider exporting to memory, with linked handler spans
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ee := newTracedEmitter(eotel.NewConfig().WithTracerProvider(tp).WithLinks())
+	defer ee.Stop()
+
+	// Register a handler that returns the envelope headers
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) { return env.Header("traceparent"), nil })
+	assert.NoError(t, err)
+
+	// Emit within a parent span
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	result, err := ee.EmitAndWait(ctx, testTopic, testMessage)
+	assert.NoError(t, err)
+	parent.End()
+
+	// The traceparent header carries the emitting span
+	assert.Contains(t, result, parent.SpanContext().TraceID().String())
+	assert.Contains(t, result, parent.SpanContext().SpanID().String())
+
+	// The handler span is a new root linked to the emitting span
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	handled := spans[0]
+	assert.False(t, handled.Parent.IsValid())
+	assert.Len(t, handled.Links, 1)
+	assert.Equal(t, parent.SpanContext().SpanID(), handled.Links[0].SpanContext.SpanID())
+
+}
@@ -0,0 +1,230 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordCallback is a pipeline callback that records the errors seen after each job
+type recordCallback struct {
+	lock sync.Mutex
+	errs []error
+}
+
+// OnBefore does nothing
+func (c *recordCallback) OnBefore(msg any) {}
+
+// OnAfter records the error of the job
+func (c *recordCallback) OnAfter(msg, result any, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// TestPipeline_EventEmitter is a test function for testing the built-in pipeline behind an event emitter
+func TestPipeline_EventEmitter(t *testing.T) {
+
+	// Create a new built-in pipeline
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(4))
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that doubles the input
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return msg.(int) * 2, nil })
+	assert.NoError(t, err)
+
+	// Immediate events are executed
+	result, err := ee.EmitAndWait(context.Background(), testTopic, 21)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+
+	// Many events are executed concurrently
+	futures := make([]events.Future, testMaxRounds)
+	for i := range futures {
+		futures[i] = ee.EmitAsync(testTopic, i)
+	}
+	for i, f := range futures {
+		result, err := f.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, i*2, result)
+	}
+
+	// Shut down the event emitter, which stops the pipeline
+	_, err = ee.Shutdown(context.Background())
+	assert.NoError(t, err)
+
+}
+
+// TestPipeline_SubmitAfterWithFunc is a test function for testing that delayed jobs run in due order
+func TestPipeline_SubmitAfterWithFunc(t *testing.T) {
+
+	// Create a new built-in pipeline with a single worker
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	defer pl.Stop()
+
+	// Submit delayed jobs out of order
+	order := make(chan int, 3)
+	record := func(msg any) (any, error) { order <- msg.(int); return nil, nil }
+	start := time.Now()
+	assert.NoError(t, pl.SubmitAfterWithFunc(record, 3, 150*time.Millisecond))
+	assert.NoError(t, pl.SubmitAfterWithFunc(record, 1, 50*time.Millisecond))
+	assert.NoError(t, pl.SubmitAfterWithFunc(record, 2, 100*time.Millisecond))
+
+	// They run in due order, not before they are due
+	assert.Equal(t, 1, <-order)
+	assert.Equal(t, 2, <-order)
+	assert.Equal(t, 3, <-order)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+}
+
+// TestPipeline_Stop is a test function for testing that a stopped pipeline rejects jobs and runs the delayed jobs it has accepted
+func TestPipeline_Stop(t *testing.T) {
+
+	// Create a new built-in pipeline with a callback
+	cb := &recordCallback{}
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1).WithCallback(cb))
+
+	// A panicking job does not kill the worker and its error reaches the callback
+	done := make(chan struct{})
+	assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) { panic("boom") }, nil))
+	assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) { close(done); return nil, nil }, nil))
+	<-done
+
+	// Delayed jobs not yet due run immediately on stop
+	ran := make(chan struct{}, 1)
+	assert.NoError(t, pl.SubmitAfterWithFunc(func(msg any) (any, error) { ran <- struct{}{}; return nil, nil }, nil, time.Hour))
+	pl.Stop()
+	assert.Len(t, ran, 1)
+
+	// The callback saw the panic
+	cb.lock.Lock()
+	assert.True(t, errors.Is(cb.errs[0], pipeline.ErrorHandleFuncPanicked))
+	cb.lock.Unlock()
+
+	// Submitting after stop returns an error
+	assert.Equal(t, pipeline.ErrorPipelineClosed, pl.SubmitWithFunc(func(msg any) (any, error) { return nil, nil }, nil))
+	assert.Equal(t, pipeline.ErrorPipelineClosed, pl.SubmitAfterWithFunc(func(msg any) (any, error) { return nil, nil }, nil, time.Second))
+
+}
+
+// TestPipeline_StopDrain is a test function for testing that Stop runs the queued jobs before it returns
+func TestPipeline_StopDrain(t *testing.T) {
+
+	// Create a new built-in pipeline with a single worker, and block the worker
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) { close(started); <-release; return nil, nil }, nil))
+	<-started
+
+	// Queue jobs behind the blocked one, in order
+	var lock sync.Mutex
+	var order []int
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, msg.(int))
+			return nil, nil
+		}, i))
+	}
+
+	// Stop waits for the queued jobs to run
+	stopped := make(chan struct{})
+	go func() { pl.Stop(); close(stopped) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the running job finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+}
+
+// TestPipeline_StopFromJob is a test function for testing that Stop called within a job does not wait for its own worker
+func TestPipeline_StopFromJob(t *testing.T) {
+
+	// Create a new built-in pipeline with two workers
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(2))
+
+	// A job stops the pipeline without waiting for itself
+	stopped := make(chan struct{})
+	assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) { pl.Stop(); close(stopped); return nil, nil }, nil))
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop called within a job did not return")
+	}
+	assert.Equal(t, pipeline.ErrorPipelineClosed, pl.SubmitWithFunc(func(msg any) (any, error) { return nil, nil }, nil))
+
+	// Stop from outside returns once every worker has exited
+	pl.Stop()
+
+}
+
+// TestPipeline_StopFuture is a test function for testing that futures of queued events are resolved when the event emitter stops
+func TestPipeline_StopFuture(t *testing.T) {
+
+	// Create a new event emitter on a built-in pipeline with a single worker
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that blocks until its context is cancelled
+	started := make(chan struct{}, 1)
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	// The first event occupies the worker, the second one waits in the queue
+	running := ee.EmitAsync(testTopic, testMessage)
+	<-started
+	queued := ee.EmitAsync(testTopic, testMessage)
+
+	// Both futures are resolved after stopping
+	ee.Stop()
+	for _, f := range []events.Future{running, queued} {
+		select {
+		case <-f.Done():
+		case <-time.After(time.Second):
+			t.Fatal("future was not resolved after Stop")
+		}
+		_, err := f.Result()
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+
+}
+
+// TestPipeline_QueueCapacity is a test function for testing the bounded queue
+func TestPipeline_QueueCapacity(t *testing.T) {
+
+	// Create a new built-in pipeline with a single worker and a queue of one job
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1).WithQueueCapacity(1))
+	defer pl.Stop()
+
+	// Block the worker
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.NoError(t, pl.SubmitWithFunc(func(msg any) (any, error) { close(started); <-release; return nil, nil }, nil))
+	<-started
+
+	// The queue accepts one job and then reports it is full
+	noop := func(msg any) (any, error) { return nil, nil }
+	assert.NoError(t, pl.SubmitWithFunc(noop, nil))
+	assert.Equal(t, pipeline.ErrorQueueFull, pl.SubmitWithFunc(noop, nil))
+	close(release)
+
+}
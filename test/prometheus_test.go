@@ -0,0 +1,61 @@
+package test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shengyanli1982/events"
+	eprom "github.com/shengyanli1982/events/contrib/prometheus"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrometheus_Recorder is a test function for testing the prometheus metrics recorder
+func TestPrometheus_Recorder(t *testing.T) {
+
+	// Create a new event emitter recording prometheus metrics
+	recorder := eprom.NewRecorder(eprom.NewConfig().WithConstLabels(prom.Labels{"service": "test"}))
+	ee := events.NewEventEmitterWithConfig(pipeline.NewSyncPipeline(), events.NewConfig().WithMetricsRecorder(recorder))
+	defer ee.Stop()
+
+	// Register a succeeding and a failing handler
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return msg, nil })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("failing", func(msg any) (any, error) { return nil, errTransient })
+	assert.NoError(t, err)
+
+	// Emit events on both topics and on a topic without handlers
+	for i := 0; i < testMaxRounds; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+	}
+	assert.NoError(t, ee.EmitWithTopic("failing", testMessage))
+	assert.Error(t, ee.EmitWithTopic("missing", testMessage))
+
+	// The counters are labelled by topic and outcome
+	expected := `
+# HELP events_handled_total Number of handler executions by outcome.
+# TYPE events_handled_total counter
+events_handled_total{outcome="failed",service="test",topic="failing"} 1
+events_handled_total{outcome="succeeded",service="test",topic="topic1"} 10
+# HELP events_rejected_total Number of emissions that returned an error.
+# TYPE events_rejected_total counter
+events_rejected_total{service="test",topic="missing"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(recorder, strings.NewReader(expected), "events_handled_total", "events_rejected_total"))
+
+	// The handler serves all metrics in the text format
+	rec := httptest.NewRecorder()
+	recorder.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	assert.Contains(t, string(body), `events_emitted_total{service="test",topic="topic1"} 10`)
+	assert.Contains(t, string(body), `events_handler_duration_seconds_count{outcome="succeeded",service="test",topic="topic1"} 10`)
+	assert.Contains(t, string(body), `events_queue_wait_seconds_count{service="test",topic="topic1"} 10`)
+
+	// The recorder can also be registered on another registry
+	assert.NoError(t, prom.NewRegistry().Register(recorder))
+
+}
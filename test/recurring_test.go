@@ -0,0 +1,167 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/clock"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecurringEmitter is a helper function that creates an event emitter sharing the virtual clock of a synchronous pipeline, with a handler recording the received envelopes
+func newRecurringEmitter(t *testing.T) (*pipeline.SyncPipeline, *events.EventEmitter, *[]*events.Envelope) {
+	pl := pipeline.NewSyncPipelineWithClock(clock.NewFakeClock(testClockStart))
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(pl.Clock()))
+	received := &[]*events.Envelope{}
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) {
+		*received = append(*received, env)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	return pl, ee, received
+}
+
+// TestRecurring_EmitEvery is a test function for testing pausing, resuming and cancelling a fixed-interval schedule
+func TestRecurring_EmitEvery(t *testing.T) {
+
+	// Create a new event emitter and emit every minute
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	s, err := ee.EmitEvery(testTopic, "tick", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, testTopic, s.Topic())
+	assert.Equal(t, testClockStart.Add(time.Minute), s.Next())
+
+	// An event is emitted at each interval
+	pl.Advance(3 * time.Minute)
+	assert.Len(t, *received, 3)
+	assert.Equal(t, "tick", (*received)[0].Payload)
+	assert.Equal(t, testClockStart.Add(3*time.Minute), (*received)[2].Timestamp)
+
+	// Nothing is emitted while paused
+	s.Pause()
+	assert.True(t, s.Paused())
+	assert.True(t, s.Next().IsZero())
+	pl.Advance(150 * time.Second)
+	assert.Len(t, *received, 3)
+
+	// Resuming counts the interval from now on
+	s.Resume()
+	assert.False(t, s.Paused())
+	assert.Equal(t, pl.Now().Add(time.Minute), s.Next())
+	pl.Advance(time.Minute)
+	assert.Len(t, *received, 4)
+
+	// Nothing is emitted after cancelling, and the schedule cannot be resumed
+	s.Cancel()
+	s.Resume()
+	assert.False(t, s.Active())
+	pl.Advance(time.Hour)
+	assert.Len(t, *received, 4)
+
+}
+
+// TestRecurring_EmitCron is a test function for testing cron schedules in a time zone
+func TestRecurring_EmitCron(t *testing.T) {
+
+	// Create a new event emitter and emit at 09:00 Shanghai time on weekdays, starting on Monday 2024-01-01 00:00 UTC
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	count := 0
+	s, err := ee.EmitCron(testTopic, func() any { count++; return count }, "CRON_TZ=Asia/Shanghai 0 9 * * MON-FRI")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), s.Next().UTC())
+
+	// One event per weekday, with a new payload each time
+	pl.Advance(7 * 24 * time.Hour)
+	assert.Len(t, *received, 5)
+	assert.Equal(t, 5, (*received)[4].Payload)
+	assert.Equal(t, time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC), (*received)[4].Timestamp.UTC())
+	assert.Equal(t, time.Date(2024, 1, 8, 1, 0, 0, 0, time.UTC), s.Next().UTC())
+
+}
+
+// TestRecurring_CronExpressions is a test function for testing the due times of various cron expressions
+func TestRecurring_CronExpressions(t *testing.T) {
+
+	// Create a new event emitter
+	_, ee, _ := newRecurringEmitter(t)
+	defer ee.Stop()
+	msg := func() any { return nil }
+
+	// The first due time of each expression after Monday 2024-01-01 00:00 UTC
+	for spec, next := range map[string]time.Time{
+		"CRON_TZ=UTC */5 * * * *":      time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC),
+		"CRON_TZ=UTC 30 2-4/2 * * *":   time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC),
+		"CRON_TZ=UTC 0 0 29 2 *":       time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"CRON_TZ=UTC 0 0 13 * FRI":     time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"CRON_TZ=UTC 0 12 * JUN,dec 0": time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC),
+		"TZ=UTC @monthly":              time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		"TZ=UTC @weekly":               time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+	} {
+		s, err := ee.EmitCron(testTopic, msg, spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, next, s.Next().UTC(), spec)
+		s.Cancel()
+	}
+
+	// Invalid schedules are rejected
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@often", "CRON_TZ=Nowhere/City * * * * *"} {
+		_, err := ee.EmitCron(testTopic, msg, spec)
+		assert.ErrorIs(t, err, events.ErrorScheduleInvalid, spec)
+	}
+	_, err := ee.EmitCron(testTopic, nil, "* * * * *")
+	assert.ErrorIs(t, err, events.ErrorScheduleInvalid)
+	_, err = ee.EmitEvery(testTopic, nil, 0)
+	assert.ErrorIs(t, err, events.ErrorScheduleInvalid)
+	_, err = ee.SubscribeWithTopic("orders.#", func(msg any) (any, error) { return nil, nil })
+	assert.NoError(t, err)
+	_, err = ee.EmitEvery("", nil, time.Second)
+	assert.ErrorIs(t, err, events.ErrorTopicInvalid)
+
+}
+
+// TestRecurring_Stop is a test function for testing that schedules are cancelled when the event emitter stops
+func TestRecurring_Stop(t *testing.T) {
+
+	// Create a new event emitter with a schedule
+	pl, ee, received := newRecurringEmitter(t)
+	s, err := ee.EmitEvery(testTopic, "tick", time.Minute)
+	assert.NoError(t, err)
+	pl.Advance(time.Minute)
+	assert.Len(t, *received, 1)
+
+	// Stopping the event emitter cancels the schedule, and no new schedule can be created
+	ee.Stop()
+	assert.False(t, s.Active())
+	_, err = ee.EmitEvery(testTopic, "tick", time.Minute)
+	assert.ErrorIs(t, err, events.ErrEmitterClosed)
+
+}
+
+// TestRecurring_Pipeline is a test function for testing a schedule on the built-in pipeline with real time
+func TestRecurring_Pipeline(t *testing.T) {
+
+	// Create a new event emitter with the built-in pipeline
+	ee := events.NewEventEmitter(pipeline.NewPipeline(nil))
+	defer ee.Stop()
+	ticks := make(chan any, testMaxRounds)
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { ticks <- msg; return nil, nil })
+	assert.NoError(t, err)
+
+	// Events are emitted repeatedly until the schedule is cancelled
+	s, err := ee.EmitEvery(testTopic, "tick", 10*time.Millisecond)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-ticks:
+			assert.Equal(t, "tick", msg)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for a recurring event")
+		}
+	}
+	s.Cancel()
+
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReplay_LateSubscriber is a test function for testing that a late handler receives the kept events before new ones
+func TestReplay_LateSubscriber(t *testing.T) {
+
+	// Create a new event emitter that keeps the last 3 events of the test topic
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithReplayBuffer(testTopic, 3, 0))
+	defer ee.Stop()
+
+	// Events are kept even before any handler is registered
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+	}
+
+	// Topics without a replay buffer still need a handler
+	assert.ErrorIs(t, ee.EmitWithTopic("other", 0), events.ErrorTopicNotExists)
+
+	// A handler registered with WithReplay receives the kept events in order
+	var replayed, live []any
+	var ids []string
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) {
+		replayed = append(replayed, env.Payload)
+		ids = append(ids, env.ID)
+		return nil, nil
+	}, events.WithReplay())
+	assert.NoError(t, err)
+	assert.Equal(t, []any{2, 3, 4}, replayed)
+
+	// A handler registered without WithReplay only receives new events
+	_, err = ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) {
+		live = append(live, env.Payload)
+		ids = append(ids, env.ID)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, live)
+
+	// New events reach both handlers with the same ID, and are kept for the next late handler
+	assert.NoError(t, ee.EmitWithTopic(testTopic, 5))
+	assert.Equal(t, []any{2, 3, 4, 5}, replayed)
+	assert.Equal(t, []any{5}, live)
+	assert.Equal(t, ids[3], ids[4])
+
+	var late []any
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { late = append(late, msg); return nil, nil }, events.WithReplay())
+	assert.NoError(t, err)
+	assert.Equal(t, []any{3, 4, 5}, late)
+
+}
+
+// TestReplay_MaxAge is a test function for testing that events older than the maximum age are not replayed
+func TestReplay_MaxAge(t *testing.T) {
+
+	// Create a new event emitter that keeps the events of the last minute, on the clock of the pipeline
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(pl.Clock()).WithReplayBuffer(testTopic, 0, time.Minute))
+	defer ee.Stop()
+
+	// Emit an old event, an event due later and a recent event
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "old"))
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "delayed", 90*time.Second))
+	pl.Advance(time.Minute + time.Second)
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "recent"))
+
+	// Only the recent event is replayed, the delayed event is kept once it is due
+	var received []any
+	record := func(msg any) (any, error) { received = append(received, msg); return nil, nil }
+	_, err := ee.SubscribeWithTopic(testTopic, record, events.WithReplay())
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"recent"}, received)
+
+	pl.Advance(30 * time.Second)
+	assert.Equal(t, []any{"recent", "delayed"}, received)
+
+}
+
+// TestReplay_Pattern is a test function for testing that a wildcard handler receives the kept events of all matching topics in order
+func TestReplay_Pattern(t *testing.T) {
+
+	// Create a new event emitter that keeps the events of two topics
+	pl := pipeline.NewSyncPipeline()
+	conf := events.NewConfig().WithReplayBuffer("orders.created", 10, 0).WithReplayBuffer("orders.paid", 10, 0)
+	ee := events.NewEventEmitterWithConfig(pl, conf)
+	defer ee.Stop()
+
+	// Emit events on both topics alternately
+	assert.NoError(t, ee.EmitWithTopic("orders.created", 1))
+	assert.NoError(t, ee.EmitWithTopic("orders.paid", 2))
+	assert.NoError(t, ee.EmitWithTopic("orders.created", 3))
+
+	// The wildcard handler receives the events in emission order
+	var topics []string
+	var received []any
+	_, err := ee.RegisterEnvelopeWithTopic("orders.#", func(env *events.Envelope) (any, error) {
+		topics = append(topics, env.Topic)
+		received = append(received, env.Payload)
+		return nil, nil
+	}, events.WithReplay())
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1, 2, 3}, received)
+	assert.Equal(t, []string{"orders.created", "orders.paid", "orders.created"}, topics)
+
+}
+
+// TestReplay_EmitDuringReplay is a test function for testing that events emitted while replaying are delivered after the kept events
+func TestReplay_EmitDuringReplay(t *testing.T) {
+
+	// Create a new event emitter that keeps the events of the test topic
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithReplayBuffer(testTopic, 10, 0))
+	defer ee.Stop()
+	assert.NoError(t, ee.EmitWithTopic(testTopic, 1))
+	assert.NoError(t, ee.EmitWithTopic(testTopic, 2))
+
+	// The handler emits a new event when it receives the first replayed event
+	var received []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		received = append(received, msg)
+		if msg == 1 {
+			assert.NoError(t, ee.EmitWithTopic(testTopic, 3))
+		}
+		return nil, nil
+	}, events.WithReplay())
+	assert.NoError(t, err)
+
+	// The new event is delivered after the replay
+	assert.Equal(t, []any{1, 2, 3}, received)
+
+}
+
+// TestReplay_Concurrent is a test function for testing that a handler registered while events are emitted receives every event exactly once and in order
+func TestReplay_Concurrent(t *testing.T) {
+
+	// Create a new event emitter with a single worker, keeping all events of the test topic
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithReplayBuffer(testTopic, testMaxRounds*10, 0))
+	defer ee.Stop()
+
+	// Emit events from another goroutine
+	total := testMaxRounds * 10
+	half := make(chan struct{})
+	go func() {
+		for i := 0; i < total; i++ {
+			if i == total/2 {
+				close(half)
+			}
+			assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+		}
+	}()
+
+	// Register a handler with WithReplay while the events are emitted
+	var lock sync.Mutex
+	var received []int
+	<-half
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		lock.Lock()
+		received = append(received, msg.(int))
+		lock.Unlock()
+		return nil, nil
+	}, events.WithReplay())
+	assert.NoError(t, err)
+
+	// The handler receives every event once, in emission order
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) >= total
+	}, 5*time.Second, 10*time.Millisecond)
+	expected := make([]int, total)
+	for i := range expected {
+		expected[i] = i
+	}
+	lock.Lock()
+	assert.Equal(t, expected, received)
+	lock.Unlock()
+
+}
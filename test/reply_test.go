@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_EmitAndWait is a test function for testing that EmitAndWait returns the result of the handler
+func TestEventEmitter_EmitAndWait(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a command handler that doubles the input, and a second handler that fails
+	errAudit := errors.New("audit failed")
+	_, err := ee.SubscribeWithTopic("commands.double", func(msg any) (any, error) { return msg.(int) * 2, nil })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("commands.double", func(msg any) (any, error) { return nil, errAudit })
+	assert.NoError(t, err)
+
+	// The result of the first handler and the first error are returned
+	result, err := ee.EmitAndWait(context.Background(), "commands.double", 21)
+	assert.Equal(t, 42, result)
+	assert.Equal(t, errAudit, err)
+
+	// Emitting to a topic without handlers returns an error immediately
+	result, err = ee.EmitAndWait(context.Background(), "commands.unknown", 21)
+	assert.Nil(t, result)
+	assert.Equal(t, events.ErrorTopicNotExists, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EmitAndWaitTimeout is a test function for testing that EmitAndWait returns when the context expires
+func TestEventEmitter_EmitAndWaitTimeout(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a slow handler
+	release := make(chan struct{})
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { <-release; return msg, nil })
+	assert.NoError(t, err)
+
+	// Wait with a short timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := ee.EmitAndWait(ctx, testTopic, testMessage)
+	assert.Nil(t, result)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Release the handler and stop the event emitter
+	close(release)
+	ee.Stop()
+
+}
+
+// TestTopic_EmitAndWait is a test function for testing the typed EmitAndWait method of Topic
+func TestTopic_EmitAndWait(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Bind the topic to the orderCreated type with an int result
+	topic, err := events.NewTopic[orderCreated, int](ee, "orders.created")
+	assert.NoError(t, err)
+	_, err = topic.Register(func(msg orderCreated) (int, error) { return msg.Amount + 1, nil })
+	assert.NoError(t, err)
+
+	// The typed result is returned
+	amount, err := topic.EmitAndWait(context.Background(), orderCreated{ID: "1", Amount: 41})
+	assert.NoError(t, err)
+	assert.Equal(t, 42, amount)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EmitAndWaitPanic is a test function for testing that a panicking handler is reported as an error instead of a success
+func TestEventEmitter_EmitAndWaitPanic(t *testing.T) {
+
+	// Create a new event emitter with a dead-letter sink on the built-in pipeline, which recovers panics itself
+	pl := pipeline.NewPipeline(pipeline.NewConfig().WithWorkerNumber(1))
+	sink := &deadLetterSink{letters: make(chan *events.DeadLetter, 1)}
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithDeadLetterSink(sink))
+	defer ee.Stop()
+
+	// Register a handler that panics without Recovery
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { panic("boom") })
+	assert.NoError(t, err)
+
+	// EmitAndWait returns the panic as an error
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, events.ErrorHandlerPanicked))
+	assert.Contains(t, err.Error(), "boom")
+
+	// The panicked event is handed to the dead-letter sink
+	dl := <-sink.letters
+	assert.True(t, errors.Is(dl.Err, events.ErrorHandlerPanicked))
+	assert.Equal(t, testMessage, dl.Envelope.Payload)
+
+}
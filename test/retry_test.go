@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_WithRetry is a test function for testing that failed handlers are retried with the attempt number exposed
+func TestEventEmitter_WithRetry(t *testing.T) {
+
+	// Create a new pipeline with a delaying queue
+	pl := k.NewPipeline(wkq.NewDelayingQueue(nil), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that fails twice before succeeding
+	var lock sync.Mutex
+	var attempts []int
+	var times []time.Time
+	policy := events.NewRetryPolicy().WithMaxAttempts(3).WithBackoff(50*time.Millisecond, time.Second).WithJitter(0)
+	_, err := ee.RegisterEnvelopeWithTopic(testTopic, func(env *events.Envelope) (any, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts = append(attempts, env.Attempt)
+		times = append(times, time.Now())
+		if env.Attempt < 3 {
+			return nil, errTransient
+		}
+		return env.Payload, nil
+	}, events.WithRetry(policy))
+	assert.NoError(t, err)
+
+	// The caller sees the result of the successful attempt
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, result)
+
+	// Every attempt is numbered and the backoff grows exponentially
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), 50*time.Millisecond)
+	assert.GreaterOrEqual(t, times[2].Sub(times[1]), 100*time.Millisecond)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_WithRetryExhausted is a test function for testing that events are dead-lettered after the last attempt
+func TestEventEmitter_WithRetryExhausted(t *testing.T) {
+
+	// Create a new pipeline with a delaying queue
+	pl := k.NewPipeline(wkq.NewDelayingQueue(nil), k.NewConfig())
+
+	// Create a new event emitter with a dead-letter sink
+	sink := &deadLetterSink{letters: make(chan *events.DeadLetter, 4)}
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithDeadLetterSink(sink))
+
+	// Register a handler that always fails
+	policy := events.NewRetryPolicy().WithMaxAttempts(2).WithBackoff(10*time.Millisecond, 10*time.Millisecond)
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, errTransient }, events.WithRetry(policy))
+	assert.NoError(t, err)
+
+	// The last error is returned and only the final failure is dead-lettered
+	_, err = ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Equal(t, errTransient, err)
+	assert.Len(t, sink.letters, 1)
+	dl := <-sink.letters
+	assert.Equal(t, 2, dl.Attempts)
+	assert.Equal(t, errTransient, dl.Err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_WithRetryClassifier is a test function for testing that non-retryable errors are not retried
+func TestEventEmitter_WithRetryClassifier(t *testing.T) {
+
+	// Create a new pipeline with a delaying queue
+	pl := k.NewPipeline(wkq.NewDelayingQueue(nil), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler with a classifier that only retries transient failures
+	errPermanent := errors.New("permanent failure")
+	var lock sync.Mutex
+	calls := 0
+	policy := events.NewRetryPolicy().WithBackoff(10*time.Millisecond, 10*time.Millisecond).WithRetryable(func(err error) bool {
+		return errors.Is(err, errTransient)
+	})
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		return nil, errPermanent
+	}, events.WithRetry(policy))
+	assert.NoError(t, err)
+
+	// The permanent failure is attempted only once
+	_, err = ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 1, calls)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -0,0 +1,210 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/shengyanli1982/events/wal"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestScheduledEvent_Cancel is a test function for testing cancelling a delayed event before it is due
+func TestScheduledEvent_Cancel(t *testing.T) {
+
+	// Create a new event emitter and emit two delayed events
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	cancelled, err := ee.ScheduleAfterWithTopic(testTopic, "cancelled", time.Hour)
+	assert.NoError(t, err)
+	kept, err := ee.ScheduleAfterWithTopic(testTopic, "kept", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, testTopic, cancelled.Topic())
+	assert.NotEqual(t, cancelled.ID(), kept.ID())
+
+	// Only the first cancel succeeds, and the cancelled event is never dispatched
+	assert.True(t, cancelled.Cancel())
+	assert.False(t, cancelled.Cancel())
+	assert.ErrorIs(t, cancelled.Reschedule(time.Minute), events.ErrorEventNotScheduled)
+	pl.Advance(time.Hour)
+	assert.Len(t, *received, 1)
+	assert.Equal(t, "kept", (*received)[0].Payload)
+	assert.Equal(t, kept.ID(), (*received)[0].ID)
+
+	// An event that is already due can no longer be cancelled
+	assert.False(t, kept.Cancel())
+
+}
+
+// TestScheduledEvent_Reschedule is a test function for testing moving the due time of a delayed event
+func TestScheduledEvent_Reschedule(t *testing.T) {
+
+	// Create a new event emitter and emit two delayed events
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	later, err := ee.ScheduleAfterWithTopic(testTopic, "later", time.Hour)
+	assert.NoError(t, err)
+	sooner, err := ee.ScheduleAfterWithTopic(testTopic, "sooner", 2*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, testClockStart.Add(time.Hour), later.When())
+
+	// Postpone the first event and bring the second one forward
+	assert.NoError(t, later.Reschedule(3*time.Hour))
+	assert.NoError(t, sooner.Reschedule(30*time.Minute))
+	assert.Equal(t, testClockStart.Add(3*time.Hour), later.When())
+	assert.Equal(t, testClockStart.Add(30*time.Minute), sooner.When())
+
+	// The old due times no longer fire, the new ones do
+	pl.Advance(30 * time.Minute)
+	assert.Len(t, *received, 1)
+	assert.Equal(t, "sooner", (*received)[0].Payload)
+	assert.Equal(t, testClockStart.Add(30*time.Minute), (*received)[0].ScheduledAt)
+	pl.Advance(90 * time.Minute)
+	assert.Len(t, *received, 1)
+	pl.Advance(time.Hour)
+	assert.Len(t, *received, 2)
+	assert.Equal(t, "later", (*received)[1].Payload)
+
+	// A non-positive delay makes the event due immediately
+	now, err := ee.ScheduleAfterWithTopic(testTopic, "now", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, now.Reschedule(0))
+	assert.Len(t, *received, 3)
+	assert.ErrorIs(t, now.Reschedule(time.Hour), events.ErrorEventNotScheduled)
+
+}
+
+// TestScheduledEvent_EmitAt is a test function for testing emitting events at an absolute time
+func TestScheduledEvent_EmitAt(t *testing.T) {
+
+	// Create a new event emitter and a typed topic
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	topic, err := events.NewTopic[string, any](ee, testTopic)
+	assert.NoError(t, err)
+
+	// An event at a future time waits until that time
+	at := testClockStart.Add(time.Hour)
+	s, err := topic.EmitAt("at", at)
+	assert.NoError(t, err)
+	assert.Equal(t, at, s.When())
+	pl.Advance(59 * time.Minute)
+	assert.Empty(t, *received)
+	pl.Advance(time.Minute)
+	assert.Len(t, *received, 1)
+	assert.Equal(t, at, (*received)[0].ScheduledAt)
+
+	// An event at a past time is emitted immediately, and its handle is already due
+	s, err = ee.EmitAt(testTopic, "past", testClockStart)
+	assert.NoError(t, err)
+	assert.Len(t, *received, 2)
+	assert.False(t, s.Cancel())
+
+	// Closed emitters reject the event
+	ee.Stop()
+	_, err = ee.EmitAt(testTopic, "closed", pl.Now().Add(time.Hour))
+	assert.Equal(t, events.ErrEmitterClosed, err)
+
+}
+
+// TestScheduledEvent_WAL is a test function for testing that cancelling and rescheduling are written into the write-ahead log
+func TestScheduledEvent_WAL(t *testing.T) {
+
+	// Open a new log and create an event emitter writing into it
+	dir := t.TempDir()
+	log, err := wal.Open(dir, nil)
+	assert.NoError(t, err)
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithClock(pl.Clock()).WithWAL(log))
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, nil })
+	assert.NoError(t, err)
+
+	// Cancel one delayed event and reschedule another
+	cancelled, err := ee.ScheduleAfterWithTopic(testTopic, "cancelled", time.Hour)
+	assert.NoError(t, err)
+	rescheduled, err := ee.ScheduleAfterWithTopic(testTopic, "rescheduled", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, log.Pending())
+	assert.True(t, cancelled.Cancel())
+	assert.NoError(t, rescheduled.Reschedule(2*time.Hour))
+	assert.Equal(t, 1, log.Pending())
+
+	// After a restart only the rescheduled event is recovered, with its new due time
+	ee.Stop()
+	assert.NoError(t, log.Close())
+	log, err = wal.Open(dir, nil)
+	assert.NoError(t, err)
+	defer log.Close()
+	records := log.TakeRecovered()
+	assert.Len(t, records, 1)
+	assert.Equal(t, rescheduled.ID(), records[0].ID)
+	assert.True(t, rescheduled.When().Equal(records[0].ScheduledAt))
+
+}
+
+// testReminder is a payload type for testing filtering scheduled events
+type testReminder struct {
+	User string
+	Text string
+}
+
+// TestScheduledEvent_List is a test function for testing listing and bulk cancelling pending delayed events
+func TestScheduledEvent_List(t *testing.T) {
+
+	// Create a new event emitter and emit delayed reminders for two users out of order
+	pl, ee, received := newRecurringEmitter(t)
+	defer ee.Stop()
+	assert.Empty(t, ee.Scheduled())
+	late, err := ee.ScheduleAfterWithTopic(testTopic, testReminder{User: "alice", Text: "late"}, 3*time.Hour)
+	assert.NoError(t, err)
+	_, err = ee.ScheduleAfterWithTopic(testTopic, testReminder{User: "bob", Text: "early"}, time.Hour)
+	assert.NoError(t, err)
+	_, err = ee.ScheduleAfterWithTopic(testTopic, testReminder{User: "alice", Text: "middle"}, 2*time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testReminder{User: "carol", Text: "now"}))
+
+	// Only the pending delayed events are listed, in due order
+	scheduled := ee.Scheduled()
+	assert.Len(t, scheduled, 3)
+	assert.Equal(t, "early", scheduled[0].Payload.(testReminder).Text)
+	assert.Equal(t, "middle", scheduled[1].Payload.(testReminder).Text)
+	assert.Equal(t, late.ID(), scheduled[2].ID)
+	assert.Equal(t, testTopic, scheduled[2].Topic)
+	assert.Equal(t, testClockStart.Add(3*time.Hour), scheduled[2].ScheduledAt)
+
+	// The listed envelopes are copies, changing them does not affect the events
+	scheduled[2].Topic = "changed"
+	scheduled[2].ScheduledAt = testClockStart
+	assert.Equal(t, testTopic, ee.Scheduled()[2].Topic)
+	assert.Equal(t, testClockStart.Add(3*time.Hour), ee.Scheduled()[2].ScheduledAt)
+
+	// Rescheduling is reflected in the list
+	assert.NoError(t, late.Reschedule(30*time.Minute))
+	assert.Equal(t, late.ID(), ee.Scheduled()[0].ID)
+
+	// Cancel all reminders for one user
+	count := ee.CancelScheduled(func(env *events.Envelope) bool { return env.Payload.(testReminder).User == "alice" })
+	assert.Equal(t, 2, count)
+	assert.False(t, late.Cancel())
+	scheduled = ee.Scheduled()
+	assert.Len(t, scheduled, 1)
+	assert.Equal(t, "bob", scheduled[0].Payload.(testReminder).User)
+
+	// Only the remaining event is dispatched
+	pl.Advance(3 * time.Hour)
+	assert.Len(t, *received, 2)
+	assert.Equal(t, "early", (*received)[1].Payload.(testReminder).Text)
+	assert.Empty(t, ee.Scheduled())
+
+	// A nil filter cancels everything
+	_, err = ee.ScheduleAfterWithTopic(testTopic, testReminder{User: "bob"}, time.Hour)
+	assert.NoError(t, err)
+	_, err = ee.ScheduleAfterWithTopic(testTopic, testReminder{User: "carol"}, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ee.CancelScheduled(nil))
+	assert.Empty(t, ee.Scheduled())
+	pl.Advance(time.Hour)
+	assert.Len(t, *received, 2)
+
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_ShutdownWaitsInFlight is a test function for testing that Shutdown waits for running handlers and rejects new emits
+func TestEventEmitter_ShutdownWaitsInFlight(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a slow handler
+	started := make(chan struct{})
+	var finished atomic.Bool
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		return msg, nil
+	})
+	assert.NoError(t, err)
+
+	// Shut down while the handler is running
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	<-started
+	pending, err := ee.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, pending)
+	assert.True(t, finished.Load())
+
+	// New emits are rejected
+	assert.Equal(t, events.ErrEmitterClosed, ee.EmitWithTopic(testTopic, testMessage))
+	assert.Equal(t, events.ErrEmitterClosed, ee.EmitAfterWithTopic(testTopic, testMessage, time.Second))
+	_, err = ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.Equal(t, events.ErrEmitterClosed, err)
+
+}
+
+// TestEventEmitter_ShutdownTimeout is a test function for testing that Shutdown returns when its context is done
+func TestEventEmitter_ShutdownTimeout(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Register a handler that runs until its context is cancelled
+	started := make(chan struct{})
+	_, err := ee.RegisterContextWithTopic(testTopic, func(ctx context.Context, msg any) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	// Shutdown gives up when its context expires and stops the emitter, which cancels the handler
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = ee.Shutdown(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+}
+
+// TestEventEmitter_ShutdownPolicy is a test function for testing the policies for pending delayed events
+func TestEventEmitter_ShutdownPolicy(t *testing.T) {
+
+	// newEmitter creates an event emitter with the policy and a counting handler
+	newEmitter := func(policy events.ShutdownPolicy, count *atomic.Int64) *events.EventEmitter {
+		pl := k.NewPipeline(wkq.NewDelayingQueue(nil), k.NewConfig())
+		ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithShutdownPolicy(policy))
+		_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+			count.Add(1)
+			return msg, nil
+		})
+		assert.NoError(t, err)
+		return ee
+	}
+
+	t.Run("run", func(t *testing.T) {
+		// Pending delayed events run at shutdown
+		var count atomic.Int64
+		ee := newEmitter(events.ShutdownRunPending, &count)
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, time.Hour))
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, time.Hour))
+		pending, err := ee.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Nil(t, pending)
+		assert.Equal(t, int64(2), count.Load())
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		// Pending delayed events are dropped at shutdown
+		var count atomic.Int64
+		ee := newEmitter(events.ShutdownDiscardPending, &count)
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, testMessage, time.Hour))
+		pending, err := ee.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Nil(t, pending)
+		assert.Equal(t, int64(0), count.Load())
+	})
+
+	t.Run("return", func(t *testing.T) {
+		// Pending delayed events are handed back in due order
+		var count atomic.Int64
+		ee := newEmitter(events.ShutdownReturnPending, &count)
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "later", 2*time.Hour))
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "sooner", time.Hour))
+		pending, err := ee.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count.Load())
+		if assert.Len(t, pending, 2) {
+			assert.Equal(t, "sooner", pending[0].Payload)
+			assert.Equal(t, "later", pending[1].Payload)
+			assert.Equal(t, testTopic, pending[0].Topic)
+			assert.NotEmpty(t, pending[0].ID)
+			assert.Equal(t, pending[0].Timestamp.Add(time.Hour), pending[0].ScheduledAt)
+		}
+	})
+
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSticky_Latest is a test function for testing that a sticky topic keeps its latest value and delivers it to new handlers
+func TestSticky_Latest(t *testing.T) {
+
+	// Create a new event emitter with a sticky topic
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithStickyTopic(testTopic))
+	defer ee.Stop()
+
+	// Nothing is kept before the first emission
+	_, ok := ee.Latest(testTopic)
+	assert.False(t, ok)
+
+	// The latest value is kept without any handler
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "v1"))
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "v2"))
+	latest, ok := ee.Latest(testTopic)
+	assert.True(t, ok)
+	assert.Equal(t, "v2", latest)
+
+	// A new handler receives the latest value immediately, then the new values
+	var first, second []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { first = append(first, msg); return nil, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"v2"}, first)
+
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "v3"))
+	assert.Equal(t, []any{"v2", "v3"}, first)
+	latest, _ = ee.Latest(testTopic)
+	assert.Equal(t, "v3", latest)
+
+	// Another handler only receives the current value
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { second = append(second, msg); return nil, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"v3"}, second)
+
+	// Topics that are not sticky keep nothing
+	_, ok = ee.Latest("other")
+	assert.False(t, ok)
+
+}
+
+// TestSticky_Pattern is a test function for testing that a wildcard handler receives the latest value of every matching sticky topic
+func TestSticky_Pattern(t *testing.T) {
+
+	// Create a new event emitter with two sticky topics
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithStickyTopic("status.db", "status.cache"))
+	defer ee.Stop()
+
+	assert.NoError(t, ee.EmitWithTopic("status.cache", "down"))
+	assert.NoError(t, ee.EmitWithTopic("status.db", "up"))
+	assert.NoError(t, ee.EmitWithTopic("status.cache", "up"))
+
+	// The wildcard handler receives one value per topic, in emission order
+	var received []string
+	_, err := ee.RegisterEnvelopeWithTopic("status.*", func(env *events.Envelope) (any, error) {
+		received = append(received, env.Topic+"="+env.Payload.(string))
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"status.db=up", "status.cache=up"}, received)
+
+}
+
+// TestSticky_ReplayBuffer is a test function for testing a sticky topic that also keeps a replay buffer
+func TestSticky_ReplayBuffer(t *testing.T) {
+
+	// Create a new event emitter with a sticky topic keeping the last 3 events
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithStickyTopic(testTopic).WithReplayBuffer(testTopic, 3, 0))
+	defer ee.Stop()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+	}
+
+	// A plain handler receives the latest event, a handler with WithReplay receives all kept events
+	var plain, replayed []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { plain = append(plain, msg); return nil, nil })
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { replayed = append(replayed, msg); return nil, nil }, events.WithReplay())
+	assert.NoError(t, err)
+	assert.Equal(t, []any{3}, plain)
+	assert.Equal(t, []any{1, 2, 3}, replayed)
+
+}
+
+// TestSticky_Topic is a test function for testing the typed Latest accessor
+func TestSticky_Topic(t *testing.T) {
+
+	// Create a new event emitter with a sticky typed topic
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithStickyTopic(testTopic))
+	defer ee.Stop()
+	topic, err := events.NewTopic[orderCreated, any](ee, testTopic)
+	assert.NoError(t, err)
+
+	_, ok := topic.Latest()
+	assert.False(t, ok)
+
+	assert.NoError(t, topic.Emit(orderCreated{ID: "a", Amount: 1}))
+	latest, ok := topic.Latest()
+	assert.True(t, ok)
+	assert.Equal(t, orderCreated{ID: "a", Amount: 1}, latest)
+
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSubscription_Unsubscribe is a test function for testing that Unsubscribe removes only its own handler
+func TestSubscription_Unsubscribe(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a channel to receive the name of the executed handler
+	names := make(chan string, testMaxRounds)
+
+	// Register two handlers on the test topic
+	audit, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { names <- "audit"; return msg, nil })
+	assert.NoError(t, err)
+	cache, err := ee.SubscribeOnceWithTopic(testTopic, func(msg any) (any, error) { names <- "cache"; return msg, nil })
+	assert.NoError(t, err)
+
+	// Assert the subscription metadata
+	assert.Equal(t, testTopic, audit.Topic())
+	assert.Equal(t, testTopic, cache.Topic())
+	assert.NotEqual(t, audit.ID(), cache.ID())
+	assert.True(t, audit.Active())
+	assert.True(t, cache.Active())
+
+	// Unsubscribe the cache handler only
+	cache.Unsubscribe()
+	assert.False(t, cache.Active())
+	assert.True(t, audit.Active())
+
+	// Unsubscribing twice is a no-op
+	cache.Unsubscribe()
+
+	// Emit the test message with the test topic
+	err = ee.EmitWithTopic(testTopic, testMessage)
+	assert.NoError(t, err)
+
+	// Assert that only the audit handler was executed
+	assert.Equal(t, "audit", <-names)
+
+	// Unsubscribe the last handler, the topic no longer exists
+	audit.Unsubscribe()
+	err = ee.EmitWithTopic(testTopic, testMessage)
+	assert.Equal(t, events.ErrorTopicNotExists, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+	// Assert that no other handler was executed
+	assert.Len(t, names, 0)
+
+}
+
+// TestSubscription_UnregisterWithTopic is a test function for testing that UnregisterWithTopic and replace mode deactivate subscriptions
+func TestSubscription_UnregisterWithTopic(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a new handler with the testing.T
+	handler := &handler{t: t}
+
+	// Register a handler and replace it with another one
+	first, err := ee.SubscribeWithTopic(testTopic, handler.testTopicMsgHandleFunc)
+	assert.NoError(t, err)
+	second, err := ee.SubscribeWithTopic(testTopic, handler.testTopicMsgHandleFunc, events.WithReplace())
+	assert.NoError(t, err)
+
+	// Assert that the replaced subscription is inactive
+	assert.False(t, first.Active())
+	assert.True(t, second.Active())
+
+	// Unregister the topic and assert that the subscription is inactive
+	ee.UnregisterWithTopic(testTopic)
+	assert.False(t, second.Active())
+
+	// Registering a nil handler returns an error
+	sub, err := ee.SubscribeWithTopic(testTopic, nil)
+	assert.Nil(t, sub)
+	assert.Equal(t, events.ErrorHandleFuncIsNil, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestSubscription_Register is a test function for testing that the Register methods keep their signatures next to the Subscribe methods
+func TestSubscription_Register(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+
+	// The Register methods can still be used as plain function values
+	var register func(string, events.MessageHandleFunc) = ee.RegisterWithTopic
+	var registerOnce func(string, events.MessageHandleFunc) = ee.RegisterOnceWithTopic
+	names := make(chan string, testMaxRounds)
+	register(testTopic, func(msg any) (any, error) { names <- "register"; return msg, nil })
+	registerOnce(testTopic, func(msg any) (any, error) { names <- "once"; return msg, nil })
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	assert.Equal(t, "register", <-names)
+	assert.Equal(t, "once", <-names)
+
+	// A nil function is ignored by Register and reported by Subscribe
+	ee.Register(nil)
+	_, err := ee.Subscribe(nil)
+	assert.Equal(t, events.ErrorHandleFuncIsNil, err)
+	_, err = ee.SubscribeOnce(nil)
+	assert.Equal(t, events.ErrorHandleFuncIsNil, err)
+
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSyncPipeline_Emit is a test function for testing that immediate events are executed inline
+func TestSyncPipeline_Emit(t *testing.T) {
+
+	// Create a new event emitter with a synchronous pipeline
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+
+	// Register a handler that records the messages
+	var received []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { received = append(received, msg); return msg, nil })
+	assert.NoError(t, err)
+
+	// The handler has run when Emit returns
+	for i := 0; i < testMaxRounds; i++ {
+		assert.NoError(t, ee.EmitWithTopic(testTopic, i))
+		assert.Len(t, received, i+1)
+	}
+
+	// Request/reply works without waiting
+	result, err := ee.EmitAndWait(context.Background(), testTopic, testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, testMessage, result)
+
+}
+
+// TestSyncPipeline_Advance is a test function for testing that delayed events run when the virtual clock passes their due time
+func TestSyncPipeline_Advance(t *testing.T) {
+
+	// Create a new event emitter with a synchronous pipeline
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+
+	// Register a handler that records the messages
+	var received []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { received = append(received, msg); return nil, nil })
+	assert.NoError(t, err)
+
+	// Emit delayed events out of order
+	start := pl.Now()
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, 3, 3*time.Hour))
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, 1, time.Hour))
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, 2, 2*time.Hour))
+	assert.Equal(t, 3, pl.Len())
+
+	// Nothing runs before the first due time
+	pl.Advance(time.Hour - time.Nanosecond)
+	assert.Empty(t, received)
+
+	// Events run in due order as the clock advances
+	pl.Advance(time.Nanosecond)
+	assert.Equal(t, []any{1}, received)
+	pl.Advance(2 * time.Hour)
+	assert.Equal(t, []any{1, 2, 3}, received)
+	assert.Equal(t, 0, pl.Len())
+	assert.Equal(t, start.Add(3*time.Hour), pl.Now())
+
+}
+
+// TestSyncPipeline_Panic is a test function for testing that panics in handlers are recovered and reach the callback
+func TestSyncPipeline_Panic(t *testing.T) {
+
+	// Create a new event emitter with a synchronous pipeline using a callback
+	cb := &recordCallback{}
+	pl := pipeline.NewSyncPipelineWithConfig(pipeline.NewConfig().WithCallback(cb))
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+
+	// Register a handler that panics on the first message only
+	var received []any
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		if msg == "boom" {
+			panic("boom")
+		}
+		received = append(received, msg)
+		return msg, nil
+	})
+	assert.NoError(t, err)
+
+	// Neither an immediate nor a delayed panicking event crashes the caller
+	assert.NotPanics(t, func() { assert.NoError(t, ee.EmitWithTopic(testTopic, "boom")) })
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "boom", time.Minute))
+	assert.NotPanics(t, func() { pl.Advance(time.Minute) })
+
+	// The callback saw both panics as errors
+	panicked := 0
+	for _, err := range cb.errs {
+		if errors.Is(err, pipeline.ErrorHandleFuncPanicked) {
+			panicked++
+		}
+	}
+	assert.Equal(t, 2, panicked)
+
+	// Later events are still handled
+	assert.NoError(t, ee.EmitWithTopic(testTopic, testMessage))
+	assert.Equal(t, []any{testMessage}, received)
+	assert.NoError(t, cb.errs[len(cb.errs)-1])
+
+}
+
+// TestSyncPipeline_Retry is a test function for testing that retry backoffs are driven by the virtual clock
+func TestSyncPipeline_Retry(t *testing.T) {
+
+	// Create a new event emitter with a synchronous pipeline
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitter(pl)
+	defer ee.Stop()
+
+	// Register a handler that fails twice, retried after a fixed backoff
+	attempts := 0
+	policy := events.NewRetryPolicy().WithMaxAttempts(3).WithBackoff(time.Second, time.Second).WithJitter(0)
+	_, err := ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errTransient
+		}
+		return "ok", nil
+	}, events.WithRetry(policy))
+	assert.NoError(t, err)
+
+	// The first attempt runs inline and the retry waits for the clock
+	f := ee.EmitAsync(testTopic, testMessage)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 1, pl.Len())
+
+	// Each second of virtual time runs one more attempt
+	pl.Advance(time.Second)
+	assert.Equal(t, 2, attempts)
+	pl.Advance(time.Second)
+	assert.Equal(t, 3, attempts)
+
+	// The future is resolved with the result of the last attempt
+	result, err := f.Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result)
+
+}
+
+// TestSyncPipeline_Stop is a test function for testing that a stopped synchronous pipeline rejects jobs
+func TestSyncPipeline_Stop(t *testing.T) {
+
+	// Create a new synchronous pipeline and queue a delayed job
+	pl := pipeline.NewSyncPipeline()
+	ran := false
+	assert.NoError(t, pl.SubmitAfterWithFunc(func(msg any) (any, error) { ran = true; return nil, nil }, nil, time.Second))
+
+	// Stop discards the delayed job
+	pl.Stop()
+	pl.Advance(time.Second)
+	assert.False(t, ran)
+	assert.Equal(t, 0, pl.Len())
+
+	// New jobs are rejected
+	err := pl.SubmitWithFunc(func(msg any) (any, error) { return nil, nil }, nil)
+	assert.True(t, errors.Is(err, pipeline.ErrorPipelineClosed))
+
+}
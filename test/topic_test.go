@@ -0,0 +1,100 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// orderCreated is a payload type for testing typed topics
+type orderCreated struct {
+	ID     string
+	Amount int
+}
+
+// TestTopic_EmitAndRegister is a test function for testing the typed Emit and Register methods of Topic
+func TestTopic_EmitAndRegister(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Bind the topic to the orderCreated type
+	topic, err := events.NewTopic[orderCreated, int](ee, "orders.created")
+	assert.NoError(t, err)
+	assert.Equal(t, "orders.created", topic.Name())
+
+	// Create a channel to receive the typed payloads
+	orders := make(chan orderCreated, 1)
+
+	// Register a typed handler, no type assertion is needed
+	_, err = topic.Register(func(msg orderCreated) (int, error) {
+		orders <- msg
+		return msg.Amount, nil
+	})
+	assert.NoError(t, err)
+
+	// Emit a typed payload
+	err = topic.Emit(orderCreated{ID: "1", Amount: 42})
+	assert.NoError(t, err)
+	assert.Equal(t, orderCreated{ID: "1", Amount: 42}, <-orders)
+
+	// An untyped emit with the right type is accepted
+	err = ee.EmitWithTopic("orders.created", orderCreated{ID: "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", (<-orders).ID)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestTopic_TypeMismatch is a test function for testing that untyped emits with the wrong type are rejected
+func TestTopic_TypeMismatch(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Bind the topic to the orderCreated type and register a handler
+	topic, err := events.NewTopic[orderCreated, any](ee, "orders.created")
+	assert.NoError(t, err)
+	_, err = topic.Register(func(msg orderCreated) (any, error) { return nil, nil })
+	assert.NoError(t, err)
+
+	// An untyped emit with the wrong type returns a TypeMismatchError
+	err = ee.EmitWithTopic("orders.created", testMessage)
+	assert.True(t, errors.Is(err, events.ErrorTopicTypeMismatch))
+	var mismatch *events.TypeMismatchError
+	assert.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "orders.created", mismatch.Topic)
+	assert.Equal(t, reflect.TypeOf(orderCreated{}), mismatch.Expected)
+	assert.Equal(t, reflect.TypeOf(testMessage), mismatch.Actual)
+
+	// A nil payload cannot be assigned to a struct type
+	err = ee.EmitWithTopic("orders.created", nil)
+	assert.True(t, errors.Is(err, events.ErrorTopicTypeMismatch))
+
+	// Binding the same topic to another type fails, binding it to the same type succeeds
+	_, err = events.NewTopic[string, any](ee, "orders.created")
+	assert.True(t, errors.Is(err, events.ErrorTopicTypeMismatch))
+	_, err = events.NewTopic[orderCreated, string](ee, "orders.created")
+	assert.NoError(t, err)
+
+	// Wildcard patterns cannot be bound to a type
+	_, err = events.NewTopic[orderCreated, any](ee, "orders.#")
+	assert.Equal(t, events.ErrorTopicInvalid, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
@@ -0,0 +1,227 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shengyanli1982/events"
+	"github.com/shengyanli1982/events/pipeline"
+	"github.com/shengyanli1982/events/wal"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWAL_Reopen is a test function for testing that events not completed are recovered when the log is reopened
+func TestWAL_Reopen(t *testing.T) {
+
+	// Open a new log whose codec decodes the topic into orderCreated
+	dir := t.TempDir()
+	conf := func() *wal.Config {
+		return wal.NewConfig().WithCodec(wal.NewJSONCodec().Register(testTopic, orderCreated{}))
+	}
+	log, err := wal.Open(dir, conf())
+	assert.NoError(t, err)
+
+	// Append three events and complete the second one
+	for i, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, log.Append(&wal.Record{ID: id, Topic: testTopic, Payload: orderCreated{ID: id, Amount: i}, Headers: map[string]string{"n": id}}))
+	}
+	assert.NoError(t, log.Complete("b"))
+	assert.Equal(t, 2, log.Pending())
+	assert.NoError(t, log.Close())
+
+	// Reopen the log and take back the events not completed, in writing order
+	log, err = wal.Open(dir, conf())
+	assert.NoError(t, err)
+	defer log.Close()
+	recovered := log.TakeRecovered()
+	assert.Len(t, recovered, 2)
+	assert.Equal(t, "a", recovered[0].ID)
+	assert.Equal(t, orderCreated{ID: "a", Amount: 0}, recovered[0].Payload)
+	assert.Equal(t, "c", recovered[1].ID)
+	assert.Equal(t, orderCreated{ID: "c", Amount: 2}, recovered[1].Payload)
+	assert.Equal(t, "c", recovered[1].Headers["n"])
+	assert.Empty(t, log.TakeRecovered())
+
+}
+
+// TestWAL_TornWrite is a test function for testing that an incomplete record at the end of the log is ignored
+func TestWAL_TornWrite(t *testing.T) {
+
+	// Open a new log and append an event
+	dir := t.TempDir()
+	log, err := wal.Open(dir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, log.Append(&wal.Record{ID: "a", Topic: testTopic, Payload: testMessage}))
+	assert.NoError(t, log.Close())
+
+	// Simulate a crash in the middle of writing the next record
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, segments, 1)
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	// The complete record is recovered
+	log, err = wal.Open(dir, nil)
+	assert.NoError(t, err)
+	defer log.Close()
+	recovered := log.TakeRecovered()
+	assert.Len(t, recovered, 1)
+	assert.Equal(t, testMessage, recovered[0].Payload)
+
+}
+
+// TestWAL_Compaction is a test function for testing that segments holding only completed events are deleted
+func TestWAL_Compaction(t *testing.T) {
+
+	// Open a new log with tiny segments
+	dir := t.TempDir()
+	log, err := wal.Open(dir, wal.NewConfig().WithSegmentSize(1))
+	assert.NoError(t, err)
+	defer log.Close()
+
+	// Every append starts a new segment
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, log.Append(&wal.Record{ID: id, Topic: testTopic, Payload: testMessage}))
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, segments, 4)
+
+	// Completing a later event does not delete the older segments
+	assert.NoError(t, log.Complete("b"))
+	segments, _ = filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, segments, 4)
+
+	// Completing the oldest event deletes the completed prefix
+	assert.NoError(t, log.Complete("a"))
+	segments, _ = filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.Len(t, segments, 2)
+	assert.Equal(t, 1, log.Pending())
+
+}
+
+// TestWAL_EventEmitter is a test function for testing that events survive a restart of the event emitter
+func TestWAL_EventEmitter(t *testing.T) {
+
+	// Open a new log and create an event emitter writing into it
+	dir := t.TempDir()
+	log, err := wal.Open(dir, nil)
+	assert.NoError(t, err)
+	pl := pipeline.NewSyncPipeline()
+	ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithWAL(log))
+
+	// Register a handler that records the events, and a failing handler
+	var received []*events.Envelope
+	record := func(env *events.Envelope) (any, error) { received = append(received, env); return nil, nil }
+	_, err = ee.RegisterEnvelopeWithTopic(testTopic, record)
+	assert.NoError(t, err)
+	_, err = ee.SubscribeWithTopic("failing", func(msg any) (any, error) { return nil, errTransient })
+	assert.NoError(t, err)
+
+	// A handled event is completed, a failed and a pending delayed event are not
+	assert.NoError(t, ee.EmitWithTopic(testTopic, "handled"))
+	assert.NoError(t, ee.EmitWithTopic("failing", "failed"))
+	assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "delayed", time.Hour))
+	assert.Len(t, received, 1)
+	assert.Equal(t, 2, log.Pending())
+
+	// Stop the event emitter before the delayed event is due, as if the process exited
+	ee.Stop()
+	assert.NoError(t, log.Close())
+
+	// Restart with a new log and event emitter, the recovered events wait for their handlers
+	log, err = wal.Open(dir, nil)
+	assert.NoError(t, err)
+	defer log.Close()
+	pl = pipeline.NewSyncPipeline()
+	ee = events.NewEventEmitterWithConfig(pl, events.NewConfig().WithWAL(log))
+	defer ee.Stop()
+	received = nil
+	_, err = ee.RegisterEnvelopeWithTopic(testTopic, record)
+	assert.NoError(t, err)
+	assert.Empty(t, received)
+	assert.Equal(t, 2, log.Pending())
+
+	// The failed event runs again as soon as its topic has a handler
+	handled := 0
+	_, err = ee.SubscribeWithTopic("failing", func(msg any) (any, error) { handled++; return nil, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, 1, log.Pending())
+
+	// Nothing is left waiting for Recover
+	count, err := ee.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// The delayed event keeps its due time
+	assert.Empty(t, received)
+	pl.Advance(time.Hour)
+	assert.Len(t, received, 1)
+	assert.Equal(t, "delayed", received[0].Payload)
+	assert.Equal(t, 0, log.Pending())
+
+}
+
+// TestWAL_Recover is a test function for testing that Recover emits the recovered events still waiting for handlers
+func TestWAL_Recover(t *testing.T) {
+
+	// Open a new log with an event on a topic that has no handler after the restart
+	dir := t.TempDir()
+	log, err := wal.Open(dir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, log.Append(&wal.Record{ID: "a", Topic: "orphan", Payload: "lost", Timestamp: time.Now()}))
+	assert.NoError(t, log.Close())
+
+	// Recovered events wait for their handlers, and registering on another topic does not emit them
+	log, err = wal.Open(dir, nil)
+	assert.NoError(t, err)
+	defer log.Close()
+	ee := events.NewEventEmitterWithConfig(pipeline.NewSyncPipeline(), events.NewConfig().WithWAL(log))
+	defer ee.Stop()
+	_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, log.Pending())
+
+	// Recover stops waiting, the event fails to be emitted and stays in the log
+	count, err := ee.Recover()
+	assert.Equal(t, events.ErrorTopicNotExists, err)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 1, log.Pending())
+
+}
+
+// TestWAL_Shutdown is a test function for testing that delayed events discarded or returned by Shutdown stay in the log
+func TestWAL_Shutdown(t *testing.T) {
+	for _, policy := range []events.ShutdownPolicy{events.ShutdownDiscardPending, events.ShutdownReturnPending} {
+
+		// Open a new log and create an event emitter writing into it
+		dir := t.TempDir()
+		log, err := wal.Open(dir, nil)
+		assert.NoError(t, err)
+		pl := pipeline.NewSyncPipeline()
+		ee := events.NewEventEmitterWithConfig(pl, events.NewConfig().WithWAL(log).WithShutdownPolicy(policy))
+		_, err = ee.SubscribeWithTopic(testTopic, func(msg any) (any, error) { return nil, nil })
+		assert.NoError(t, err)
+
+		// Shut down before the delayed event is due
+		assert.NoError(t, ee.EmitAfterWithTopic(testTopic, "delayed", time.Hour))
+		_, err = ee.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, log.Pending())
+		assert.NoError(t, log.Close())
+
+		// The delayed event is recovered after reopening the log
+		log, err = wal.Open(dir, nil)
+		assert.NoError(t, err)
+		records := log.TakeRecovered()
+		assert.Len(t, records, 1)
+		assert.Equal(t, "delayed", records[0].Payload)
+		assert.NoError(t, log.Close())
+	}
+}
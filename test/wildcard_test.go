@@ -0,0 +1,105 @@
+package test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/shengyanli1982/events"
+	k "github.com/shengyanli1982/karta"
+	wkq "github.com/shengyanli1982/workqueue/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventEmitter_MatchingPatterns is a test function for testing the precedence of wildcard patterns
+func TestEventEmitter_MatchingPatterns(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a new handler with the testing.T
+	handler := &handler{t: t}
+
+	// Register handlers on several patterns
+	for _, pattern := range []string{"orders.#", "#", "orders.*.created", "orders.eu.+", "orders.+.+", "orders.eu.created", "users.#"} {
+		_, err := ee.SubscribeWithTopic(pattern, handler.testTopicMsgHandleFunc)
+		assert.NoError(t, err)
+	}
+
+	// Assert the matching patterns are sorted from the most specific to the least specific
+	assert.Equal(t, []string{"orders.eu.+", "orders.*.created", "orders.+.+", "orders.#", "#"}, ee.MatchingPatterns("orders.eu.created"))
+
+	// The multi-level wildcard also matches the parent level
+	assert.Equal(t, []string{"orders.#", "#"}, ee.MatchingPatterns("orders"))
+
+	// Patterns with a multi-level wildcard in the middle are rejected
+	_, err := ee.SubscribeWithTopic("orders.#.created", handler.testTopicMsgHandleFunc)
+	assert.Equal(t, events.ErrorTopicPatternInvalid, err)
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
+
+// TestEventEmitter_EmitWithWildcard is a test function for testing that wildcard subscriptions receive matching events
+func TestEventEmitter_EmitWithWildcard(t *testing.T) {
+
+	// Create a new pipeline with a fake delaying queue
+	pl := k.NewPipeline(k.NewFakeDelayingQueue(wkq.NewQueue(nil)), k.NewConfig())
+
+	// Create a new event emitter with the pipeline
+	ee := events.NewEventEmitter(pl)
+
+	// Create a wait group to wait for all handlers
+	wg := sync.WaitGroup{}
+
+	// Record the topics received by each pattern
+	var lock sync.Mutex
+	received := make(map[string][]string)
+
+	// Create a handler factory that records the pattern and message
+	newHandleFunc := func(pattern string) events.MessageHandleFunc {
+		return func(msg any) (any, error) {
+			defer wg.Done()
+			lock.Lock()
+			received[pattern] = append(received[pattern], msg.(string))
+			lock.Unlock()
+			return msg, nil
+		}
+	}
+
+	// Register handlers on a single-level and a multi-level pattern
+	_, err := ee.SubscribeWithTopic("orders.*.created", newHandleFunc("orders.*.created"))
+	assert.NoError(t, err)
+	sub, err := ee.SubscribeWithTopic("orders.#", newHandleFunc("orders.#"))
+	assert.NoError(t, err)
+
+	// Emit events on concrete topics, the message is the topic itself
+	wg.Add(5)
+	for _, topic := range []string{"orders.eu.created", "orders.us.created", "orders.eu.deleted"} {
+		assert.NoError(t, ee.EmitWithTopic(topic, topic))
+	}
+	wg.Wait()
+
+	// Topics that match no pattern do not exist
+	assert.Equal(t, events.ErrorTopicNotExists, ee.EmitWithTopic("users.eu.created", testMessage))
+
+	// Assert the received messages
+	for _, msgs := range received {
+		sort.Strings(msgs)
+	}
+	assert.Equal(t, []string{"orders.eu.created", "orders.us.created"}, received["orders.*.created"])
+	assert.Equal(t, []string{"orders.eu.created", "orders.eu.deleted", "orders.us.created"}, received["orders.#"])
+
+	// Unsubscribing the last handler of a pattern removes it from the matching patterns
+	sub.Unsubscribe()
+	assert.Equal(t, []string{"orders.*.created"}, ee.MatchingPatterns("orders.eu.created"))
+	assert.Equal(t, events.ErrorTopicNotExists, ee.EmitWithTopic("orders.eu.deleted", testMessage))
+
+	// Stop the event emitter
+	ee.Stop()
+
+}
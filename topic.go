@@ -0,0 +1,308 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/shengyanli1982/events/internal"
+)
+
+// ErrorTopicTypeMismatch 是一个变量，它的值为一个新的错误，表示消息的类型与主题绑定的类型不匹配。
+// ErrorTopicTypeMismatch is a variable, its value is a new error, indicating that the type of the message does not match the type bound to the topic.
+var ErrorTopicTypeMismatch = errors.New("message type does not match topic type")
+
+// TypeMismatchError 是一个结构体，它描述了消息类型与主题绑定类型不匹配的详细信息，可以使用 errors.Is 与 ErrorTopicTypeMismatch 比较。
+// TypeMismatchError is a structure that describes the details of a mismatch between the message type and the type bound to the topic, it can be compared with ErrorTopicTypeMismatch using errors.Is.
+type TypeMismatchError struct {
+	// Topic 是绑定了类型的主题。
+	// Topic is the topic bound to the type.
+	Topic string
+
+	// Expected 是主题绑定的类型。
+	// Expected is the type bound to the topic.
+	Expected reflect.Type
+
+	// Actual 是消息的实际类型，消息为 nil 时它也为 nil。
+	// Actual is the actual type of the message, it is nil when the message is nil.
+	Actual reflect.Type
+}
+
+// Error 是 TypeMismatchError 的一个方法，它返回错误的描述。
+// Error is a method of TypeMismatchError that returns the description of the error.
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("%s: topic %q expects %v, got %v", ErrorTopicTypeMismatch, e.Topic, e.Expected, e.Actual)
+}
+
+// Unwrap 是 TypeMismatchError 的一个方法，它返回 ErrorTopicTypeMismatch。
+// Unwrap is a method of TypeMismatchError that returns ErrorTopicTypeMismatch.
+func (e *TypeMismatchError) Unwrap() error {
+	return ErrorTopicTypeMismatch
+}
+
+// checkMessageType 是一个函数，它检查消息是否可以赋值给主题绑定的类型，不能赋值时返回 TypeMismatchError。
+// checkMessageType is a function that checks whether the message can be assigned to the type bound to the topic, and returns a TypeMismatchError if it cannot.
+func checkMessageType(topic string, msg any, expected reflect.Type) error {
+	actual := reflect.TypeOf(msg)
+
+	// nil 消息只能赋值给可以为 nil 的类型。
+	// A nil message can only be assigned to types that can be nil.
+	if actual == nil {
+		switch expected.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return nil
+		}
+	} else if actual.AssignableTo(expected) {
+		return nil
+	}
+
+	// 返回类型不匹配的错误。
+	// Return the type mismatch error.
+	return &TypeMismatchError{Topic: topic, Expected: expected, Actual: actual}
+}
+
+// castMessage 是一个函数，它将消息转换为类型 T，转换失败时返回 TypeMismatchError。
+// castMessage is a function that converts the message to type T, and returns a TypeMismatchError if the conversion fails.
+func castMessage[T any](topic string, msg any) (T, error) {
+	// 如果消息可以直接断言为 T，返回断言的结果。
+	// If the message can be asserted to T directly, return the result of the assertion.
+	if v, ok := msg.(T); ok {
+		return v, nil
+	}
+
+	// 否则检查消息的类型，nil 消息会被转换为 T 的零值。
+	// Otherwise check the type of the message, a nil message is converted to the zero value of T.
+	var zero T
+	return zero, checkMessageType(topic, msg, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Topic 是一个泛型结构体，它将一个主题绑定到消息类型 T 和处理结果类型 R 上。
+// 通过 Topic 发出的消息在编译期就会检查类型；通过 EventEmitter 直接发出到这个主题的消息会在运行时检查，类型不匹配时返回 TypeMismatchError。
+// Topic is a generic structure that binds a topic to the message type T and the result type R.
+// Messages emitted through Topic are type-checked at compile time; messages emitted to this topic directly through EventEmitter are checked at runtime, and a TypeMismatchError is returned if the type does not match.
+type Topic[T any, R any] struct {
+	// emitter 是主题所属的 EventEmitter。
+	// emitter is the EventEmitter the topic belongs to.
+	emitter *EventEmitter
+
+	// name 是主题的名称。
+	// name is the name of the topic.
+	name string
+}
+
+// NewTopic 是一个泛型函数，它在 EventEmitter 上将主题绑定到消息类型 T，并返回一个 Topic 实例。
+// 如果主题已经绑定到另一个类型，返回 TypeMismatchError。
+// NewTopic is a generic function that binds the topic to the message type T on the EventEmitter and returns an instance of Topic.
+// If the topic is already bound to another type, a TypeMismatchError is returned.
+func NewTopic[T any, R any](ee *EventEmitter, name string) (*Topic[T, R], error) {
+	// 将主题绑定到消息类型 T。
+	// Bind the topic to the message type T.
+	if err := ee.bindTopicType(name, reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return nil, err
+	}
+
+	// 返回 Topic 实例。
+	// Return the instance of Topic.
+	return &Topic[T, R]{emitter: ee, name: name}, nil
+}
+
+// Name 是 Topic 的一个方法，它返回主题的名称。
+// Name is a method of Topic that returns the name of the topic.
+func (t *Topic[T, R]) Name() string {
+	return t.name
+}
+
+// Register 是 Topic 的一个方法，它将一个类型安全的消息处理函数注册到主题上。
+// Register is a method of Topic that registers a type-safe message handling function to the topic.
+func (t *Topic[T, R]) Register(fn func(msg T) (R, error), opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将类型安全的消息处理函数适配为 MessageHandleFunc 并注册。
+	// Adapt the type-safe message handling function to MessageHandleFunc and register it.
+	return t.emitter.SubscribeWithTopic(t.name, t.adapt(fn), opts...)
+}
+
+// RegisterOnce 是 Topic 的一个方法，它将一个类型安全的消息处理函数注册到主题上，并确保这个函数只执行一次。
+// RegisterOnce is a method of Topic that registers a type-safe message handling function to the topic and ensures that this function is executed only once.
+func (t *Topic[T, R]) RegisterOnce(fn func(msg T) (R, error), opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将类型安全的消息处理函数适配为 MessageHandleFunc 并注册。
+	// Adapt the type-safe message handling function to MessageHandleFunc and register it.
+	return t.emitter.SubscribeOnceWithTopic(t.name, t.adapt(fn), opts...)
+}
+
+// RegisterContext 是 Topic 的一个方法，它将一个类型安全的带上下文的消息处理函数注册到主题上。
+// RegisterContext is a method of Topic that registers a type-safe context-aware message handling function to the topic.
+func (t *Topic[T, R]) RegisterContext(fn func(ctx context.Context, msg T) (R, error), opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将类型安全的消息处理函数适配为 ContextHandleFunc 并注册。
+	// Adapt the type-safe message handling function to ContextHandleFunc and register it.
+	return t.emitter.RegisterContextWithTopic(t.name, func(ctx context.Context, msg any) (any, error) {
+		// 将消息转换为类型 T。
+		// Convert the message to type T.
+		v, err := castMessage[T](t.name, msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// 调用类型安全的消息处理函数。
+		// Call the type-safe message handling function.
+		return fn(ctx, v)
+	}, opts...)
+}
+
+// RegisterEnvelope 是 Topic 的一个方法，它将一个同时接收 Envelope 和类型为 T 的消息的处理函数注册到主题上。
+// RegisterEnvelope is a method of Topic that registers a handling function receiving both the Envelope and the message of type T to the topic.
+func (t *Topic[T, R]) RegisterEnvelope(fn func(env *Envelope, msg T) (R, error), opts ...RegisterOption) (Subscription, error) {
+	// 如果消息处理函数为 nil，返回错误 ErrorHandleFuncIsNil。
+	// If the message handling function is nil, return the error ErrorHandleFuncIsNil.
+	if fn == nil {
+		return nil, ErrorHandleFuncIsNil
+	}
+
+	// 将类型安全的消息处理函数适配为 EnvelopeHandleFunc 并注册。
+	// Adapt the type-safe message handling function to EnvelopeHandleFunc and register it.
+	return t.emitter.RegisterEnvelopeWithTopic(t.name, func(env *Envelope) (any, error) {
+		// 将消息转换为类型 T。
+		// Convert the message to type T.
+		v, err := castMessage[T](t.name, env.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		// 调用类型安全的消息处理函数。
+		// Call the type-safe message handling function.
+		return fn(env, v)
+	}, opts...)
+}
+
+// adapt 是 Topic 的一个方法，它将类型安全的消息处理函数适配为 MessageHandleFunc。
+// adapt is a method of Topic that adapts a type-safe message handling function to MessageHandleFunc.
+func (t *Topic[T, R]) adapt(fn func(msg T) (R, error)) MessageHandleFunc {
+	return func(msg any) (any, error) {
+		// 将消息转换为类型 T，例如冒泡而来的其他类型的消息会在这里返回错误。
+		// Convert the message to type T, for example messages of other types bubbling up return an error here.
+		v, err := castMessage[T](t.name, msg)
+		if err != nil {
+			return nil, err
+		}
+
+		// 调用类型安全的消息处理函数。
+		// Call the type-safe message handling function.
+		return fn(v)
+	}
+}
+
+// Emit 是 Topic 的一个方法，它立即在主题上发出一个类型为 T 的消息。
+// Emit is a method of Topic that immediately emits a message of type T on the topic.
+func (t *Topic[T, R]) Emit(msg T) error {
+	return t.emitter.EmitWithTopic(t.name, msg)
+}
+
+// EmitWithContext 是 Topic 的一个方法，它使用指定的上下文立即在主题上发出一个类型为 T 的消息。
+// EmitWithContext is a method of Topic that immediately emits a message of type T on the topic with the specified context.
+func (t *Topic[T, R]) EmitWithContext(ctx context.Context, msg T) error {
+	return t.emitter.EmitWithContext(ctx, t.name, msg)
+}
+
+// EmitAfter 是 Topic 的一个方法，它在指定的延迟后在主题上发出一个类型为 T 的消息。
+// EmitAfter is a method of Topic that emits a message of type T on the topic after the specified delay.
+func (t *Topic[T, R]) EmitAfter(msg T, delay time.Duration) error {
+	return t.emitter.EmitAfterWithTopic(t.name, msg, delay)
+}
+
+// ScheduleAfter 是 Topic 的一个方法，它在指定的延迟后在主题上发出一个类型为 T 的消息，并返回 ScheduledEvent。
+// ScheduleAfter is a method of Topic that emits a message of type T on the topic after the specified delay, and returns a ScheduledEvent.
+func (t *Topic[T, R]) ScheduleAfter(msg T, delay time.Duration) (ScheduledEvent, error) {
+	return t.emitter.ScheduleAfterWithTopic(t.name, msg, delay)
+}
+
+// EmitAt 是 Topic 的一个方法，它在指定的时间在主题上发出一个类型为 T 的消息。
+// EmitAt is a method of Topic that emits a message of type T on the topic at the specified time.
+func (t *Topic[T, R]) EmitAt(msg T, at time.Time) (ScheduledEvent, error) {
+	return t.emitter.EmitAt(t.name, msg, at)
+}
+
+// EmitAndWait 是 Topic 的一个方法，它在主题上发出一个类型为 T 的消息，并等待处理函数返回类型为 R 的结果。
+// EmitAndWait is a method of Topic that emits a message of type T on the topic and waits for the handling functions to return a result of type R.
+func (t *Topic[T, R]) EmitAndWait(ctx context.Context, msg T) (R, error) {
+	// 发出消息并等待处理结果。
+	// Emit the message and wait for the handling result.
+	result, err := t.emitter.EmitAndWait(ctx, t.name, msg)
+
+	// 将处理结果转换为类型 R。
+	// Convert the handling result to type R.
+	v, castErr := castMessage[R](t.name, result)
+	if err == nil {
+		err = castErr
+	}
+
+	// 返回结果和错误。
+	// Return the result and error.
+	return v, err
+}
+
+// EmitAsync 是 Topic 的一个方法，它在主题上发出一个类型为 T 的消息，并返回一个 Future。
+// EmitAsync is a method of Topic that emits a message of type T on the topic and returns a Future.
+func (t *Topic[T, R]) EmitAsync(msg T) Future {
+	return t.emitter.EmitAsync(t.name, msg)
+}
+
+// Latest 是 Topic 的一个方法，它返回主题上保留的最新的类型为 T 的消息，参见 EventEmitter 的 Latest 方法。
+// Latest is a method of Topic that returns the latest message of type T kept on the topic, see the Latest method of EventEmitter.
+func (t *Topic[T, R]) Latest() (T, bool) {
+	// 获取最新的消息，并将它转换为类型 T。
+	// Get the latest message and convert it to type T.
+	msg, ok := t.emitter.Latest(t.name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	v, err := castMessage[T](t.name, msg)
+	return v, err == nil
+}
+
+// bindTopicType 是 EventEmitter 的一个方法，它将主题绑定到消息类型上。
+// bindTopicType is a method of EventEmitter that binds the topic to the message type.
+func (ee *EventEmitter) bindTopicType(topic string, typ reflect.Type) error {
+	// 只有有效的主题名称才能绑定类型。
+	// Only valid topic names can be bound to types.
+	if !internal.IsValidTopic(topic, ee.config.separator) {
+		return ErrorTopicInvalid
+	}
+
+	// 锁定 EventEmitter，以防止并发修改。
+	// Lock the EventEmitter to prevent concurrent modifications.
+	ee.lock.Lock()
+	defer ee.lock.Unlock()
+
+	// 如果主题已经绑定到另一个类型，返回类型不匹配的错误。
+	// If the topic is already bound to another type, return the type mismatch error.
+	if bound, ok := ee.topicTypes[topic]; ok && bound != typ {
+		return &TypeMismatchError{Topic: topic, Expected: bound, Actual: typ}
+	}
+
+	// 绑定主题的类型。
+	// Bind the type of the topic.
+	ee.topicTypes[topic] = typ
+
+	// 返回 nil，表示没有错误。
+	// Return nil to indicate that there is no error.
+	return nil
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+
+	"github.com/shengyanli1982/events/wal"
+)
+
+// appendLog 是 EventEmitter 的一个方法，它在分发之前将事件写入预写日志。没有配置预写日志时什么也不做，死信主题上的事件不会写入日志。
+// appendLog is a method of EventEmitter that writes the event into the write-ahead log before dispatching. It does nothing when no write-ahead log is configured, and events on the dead-letter topic are not written into the log.
+func (ee *EventEmitter) appendLog(e *emission) error {
+	if ee.config.wal == nil || e.topic == DeadLetterTopic {
+		return nil
+	}
+	err := ee.config.wal.Append(&wal.Record{
+		ID:          e.id,
+		Topic:       e.topic,
+		Payload:     e.msg,
+		Headers:     e.headers,
+		Timestamp:   e.timestamp,
+		ScheduledAt: e.scheduledAt,
+	})
+	if err == nil {
+		e.logged = true
+	}
+	return err
+}
+
+// relog 是 EventEmitter 的一个方法，它在延迟事件被重新安排后，用新的到期时间重新写入已经写入预写日志的事件。
+// relog is a method of EventEmitter that writes the event already in the write-ahead log again with the new due time after the delayed event is rescheduled.
+func (ee *EventEmitter) relog(e *emission) error {
+	if !e.logged {
+		return nil
+	}
+	e.logged = false
+	if err := ee.config.wal.Complete(e.id); err != nil {
+		return err
+	}
+	return ee.appendLog(e)
+}
+
+// restore 是 emission 的一个方法，它使用从预写日志中恢复的事件的元数据，日志中已经有这个事件，因此不会再次写入。
+// restore is a method of emission that uses the metadata of the event recovered from the write-ahead log, the event is already in the log so it is not written again.
+func (e *emission) restore(r *wal.Record) {
+	e.id = r.ID
+	e.timestamp = r.Timestamp
+	e.scheduledAt = r.ScheduledAt
+	e.headers = r.Headers
+	e.logged = true
+}
+
+// takeRecovered 是 EventEmitter 的一个方法，它取出从预写日志中恢复、还没有重新发出的事件，调用方需要持有写锁。
+// all 为 false 时只取出主题上已经有处理函数可以分发的事件，其余的事件继续等待。
+// takeRecovered is a method of EventEmitter that takes the events recovered from the write-ahead log and not yet emitted again, the caller needs to hold the write lock.
+// When all is false, only the events whose topics already have handling functions to dispatch to are taken, and the other events keep waiting.
+func (ee *EventEmitter) takeRecovered(all bool) []*wal.Record {
+	if len(ee.recovered) == 0 {
+		return nil
+	}
+	if all {
+		records := ee.recovered
+		ee.recovered = nil
+		return records
+	}
+	var ready, waiting []*wal.Record
+	for _, r := range ee.recovered {
+		if len(ee.resolveLevels(r.Topic)) > 0 {
+			ready = append(ready, r)
+		} else {
+			waiting = append(waiting, r)
+		}
+	}
+	ee.recovered = waiting
+	return ready
+}
+
+// recover 是 EventEmitter 的一个方法，它按写入顺序重新发出从预写日志中恢复的事件，并返回重新发出的事件数量和第一个错误。
+// 事件保留原来的 ID、时间戳和头部，尚未到期的延迟事件在原定的时间执行，已经到期的立即执行。
+// recover is a method of EventEmitter that emits again, in writing order, the events recovered from the write-ahead log, and returns the number of events emitted again and the first error.
+// Events keep their original ID, timestamp and headers, delayed events not yet due are executed at the original time, and those already due are executed immediately.
+func (ee *EventEmitter) recover(records []*wal.Record) (int, error) {
+	var firstErr error
+	count := 0
+	now := ee.config.clock.Now()
+	for _, r := range records {
+		delay := r.ScheduledAt.Sub(now)
+		if delay < 0 {
+			delay = executeImmediately
+		}
+		if _, err := ee.emitEvent(context.Background(), r.Topic, r.Payload, delay, nil, r); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		count++
+	}
+	return count, firstErr
+}
+
+// Recover 是 EventEmitter 的一个方法，它立即重新发出从预写日志中恢复、还在等待处理函数注册的事件，并返回重新发出的事件数量。
+// 恢复的事件会在它们的主题上注册了处理函数之后自动重新发出，只有需要放弃等待时才调用 Recover，例如主题上不会再注册处理函数时。
+// 重新发出失败的事件（例如主题上没有处理函数）仍然留在日志中，下次打开日志时再恢复，返回的错误是第一个失败的错误。
+// Recover is a method of EventEmitter that immediately emits again the events recovered from the write-ahead log that are still waiting for handling functions to be registered, and returns the number of events emitted again.
+// Recovered events are emitted again automatically once handling functions are registered for their topics, Recover only needs to be called to stop waiting, for example when no handling function will be registered for a topic.
+// Events that fail to be emitted again (for example the topic has no handling function) stay in the log and are recovered the next time the log is opened, and the returned error is the first failure.
+func (ee *EventEmitter) Recover() (int, error) {
+	// 取出所有还在等待的恢复事件。
+	// Take all recovered events still waiting.
+	ee.lock.Lock()
+	records := ee.takeRecovered(true)
+	ee.lock.Unlock()
+
+	// 按写入顺序重新发出这些事件。
+	// Emit these events again in writing order.
+	return ee.recover(records)
+}
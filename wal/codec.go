@@ -0,0 +1,78 @@
+package wal
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// Codec 是一个接口，它负责将事件的数据编码后写入日志，并在恢复时解码。
+// Codec is an interface responsible for encoding the data of events written into the log, and decoding it on recovery.
+type Codec interface {
+	// Marshal 编码主题上的一个事件的数据。
+	// Marshal encodes the data of an event on the topic.
+	Marshal(topic string, payload any) ([]byte, error)
+
+	// Unmarshal 解码主题上的一个事件的数据。
+	// Unmarshal decodes the data of an event on the topic.
+	Unmarshal(topic string, data []byte) (any, error)
+}
+
+// JSONCodec 是一个结构体，它使用 encoding/json 实现 Codec。
+// 使用 Register 为主题登记数据的类型后，数据会被解码为这个类型；否则解码为 encoding/json 的通用类型，例如 map[string]any 和 float64。
+// JSONCodec is a structure that implements Codec with encoding/json.
+// After the type of the data has been registered for a topic with Register, the data is decoded into this type; otherwise it is decoded into the generic types of encoding/json, such as map[string]any and float64.
+type JSONCodec struct {
+	// lock 是 sync.RWMutex 类型，用于保护 types 的并发访问。
+	// lock is of type sync.RWMutex, used to protect concurrent access to types.
+	lock sync.RWMutex
+
+	// types 是每个主题上数据的类型。
+	// types is the type of the data on each topic.
+	types map[string]reflect.Type
+}
+
+// NewJSONCodec 是一个函数，它返回一个新的 JSONCodec。
+// NewJSONCodec is a function that returns a new JSONCodec.
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{types: make(map[string]reflect.Type)}
+}
+
+// Register 是 JSONCodec 的一个方法，它登记主题上数据的类型，prototype 是这个类型的任意一个值。
+// Register is a method of JSONCodec that registers the type of the data on the topic, prototype is any value of this type.
+func (c *JSONCodec) Register(topic string, prototype any) *JSONCodec {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.types[topic] = reflect.TypeOf(prototype)
+	return c
+}
+
+// Marshal 是 JSONCodec 的一个方法，它将数据编码为 JSON。
+// Marshal is a method of JSONCodec that encodes the data as JSON.
+func (c *JSONCodec) Marshal(_ string, payload any) ([]byte, error) {
+	return json.Marshal(payload)
+}
+
+// Unmarshal 是 JSONCodec 的一个方法，它将 JSON 解码为主题上登记的类型。
+// Unmarshal is a method of JSONCodec that decodes JSON into the type registered for the topic.
+func (c *JSONCodec) Unmarshal(topic string, data []byte) (any, error) {
+	c.lock.RLock()
+	typ, ok := c.types[topic]
+	c.lock.RUnlock()
+
+	// 如果主题没有登记类型，解码为通用类型。
+	// If no type is registered for the topic, decode into the generic types.
+	if !ok || typ == nil {
+		var v any
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+
+	// 解码为登记的类型。
+	// Decode into the registered type.
+	v := reflect.New(typ)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
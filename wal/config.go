@@ -0,0 +1,88 @@
+package wal
+
+// DefaultSegmentSize 是日志分段文件的默认最大字节数。
+// DefaultSegmentSize is the default maximum number of bytes of a log segment file.
+const DefaultSegmentSize int64 = 16 << 20
+
+// Config 是一个结构体，用于配置 Log 的参数。
+// Config is a structure used to configure the parameters of Log.
+type Config struct {
+	// codec 是编码和解码事件数据的 Codec。
+	// codec is the Codec encoding and decoding the data of events.
+	codec Codec
+
+	// segmentSize 是分段文件的最大字节数，超过后写入新的分段文件。
+	// segmentSize is the maximum number of bytes of a segment file, after which a new segment file is written.
+	segmentSize int64
+
+	// sync 表示每次写入后是否调用 fsync，使记录在操作系统崩溃后仍然存在。
+	// sync indicates whether fsync is called after every write, so that records survive operating system crashes.
+	sync bool
+}
+
+// NewConfig 是一个函数，用于创建并返回一个新的 Config 结构体的指针。
+// NewConfig is a function that creates and returns a pointer to a new Config structure.
+func NewConfig() *Config {
+	return &Config{
+		// codec 默认为 JSONCodec。
+		// codec defaults to JSONCodec.
+		codec: NewJSONCodec(),
+
+		// segmentSize 默认为 DefaultSegmentSize。
+		// segmentSize defaults to DefaultSegmentSize.
+		segmentSize: DefaultSegmentSize,
+	}
+}
+
+// DefaultConfig 是一个函数，用于创建一个默认的配置。
+// DefaultConfig is a function that creates a default configuration.
+func DefaultConfig() *Config {
+	return NewConfig()
+}
+
+// WithCodec 是一个方法，用于设置编码和解码事件数据的 Codec。
+// WithCodec is a method used to set the Codec encoding and decoding the data of events.
+func (c *Config) WithCodec(codec Codec) *Config {
+	c.codec = codec
+	return c
+}
+
+// WithSegmentSize 是一个方法，用于设置分段文件的最大字节数。
+// WithSegmentSize is a method used to set the maximum number of bytes of a segment file.
+func (c *Config) WithSegmentSize(size int64) *Config {
+	c.segmentSize = size
+	return c
+}
+
+// WithSync 是一个方法，用于在每次写入后调用 fsync。默认只写入操作系统的缓存，可以在进程崩溃后恢复，但不能在操作系统崩溃后恢复。
+// WithSync is a method used to call fsync after every write. By default records are only written to the cache of the operating system, which survives process crashes but not operating system crashes.
+func (c *Config) WithSync() *Config {
+	c.sync = true
+	return c
+}
+
+// isConfigValid 是一个函数，用于检查配置是否有效，如果无效则返回一个默认的配置。
+// isConfigValid is a function that checks whether the configuration is valid, and returns a default configuration if it is not.
+func isConfigValid(conf *Config) *Config {
+	// 如果配置为 nil，创建一个默认的配置。
+	// If the configuration is nil, create a default configuration.
+	if conf == nil {
+		return DefaultConfig()
+	}
+
+	// 如果 Codec 为 nil，设置为 JSONCodec。
+	// If the Codec is nil, set it to JSONCodec.
+	if conf.codec == nil {
+		conf.codec = NewJSONCodec()
+	}
+
+	// 如果分段文件的最大字节数无效，设置为默认值。
+	// If the maximum number of bytes of a segment file is invalid, set it to the default value.
+	if conf.segmentSize <= 0 {
+		conf.segmentSize = DefaultSegmentSize
+	}
+
+	// 返回配置。
+	// Return the configuration.
+	return conf
+}
@@ -0,0 +1,481 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorLogClosed 是一个变量，它的值为一个新的错误，表示 Log 已经关闭。
+// ErrorLogClosed is a variable, its value is a new error, indicating that the Log has been closed.
+var ErrorLogClosed = errors.New("write-ahead log is closed")
+
+// ErrorRecordCorrupted 是一个变量，它的值为一个新的错误，表示日志中的记录无法解码。
+// ErrorRecordCorrupted is a variable, its value is a new error, indicating that a record in the log cannot be decoded.
+var ErrorRecordCorrupted = errors.New("write-ahead log record is corrupted")
+
+// segmentExt 是分段文件的扩展名。
+// segmentExt is the extension of segment files.
+const segmentExt = ".wal"
+
+// frameHeaderSize 是每条记录前的头部字节数：4 字节长度和 4 字节 CRC32 校验和。
+// frameHeaderSize is the number of header bytes before every record: a 4-byte length and a 4-byte CRC32 checksum.
+const frameHeaderSize = 8
+
+// 记录的操作类型。
+// The operation types of records.
+const (
+	// opAppend 表示写入一个事件。
+	// opAppend indicates that an event is written.
+	opAppend = "append"
+
+	// opComplete 表示一个事件已经处理完成。
+	// opComplete indicates that an event has been handled.
+	opComplete = "complete"
+)
+
+// Record 是一个结构体，它表示写入日志的一个事件。
+// Record is a structure that represents an event written into the log.
+type Record struct {
+	// ID 是事件的唯一标识。
+	// ID is the unique identifier of the event.
+	ID string
+
+	// Topic 是事件的主题。
+	// Topic is the topic of the event.
+	Topic string
+
+	// Payload 是事件的数据，由 Codec 编码。
+	// Payload is the data of the event, encoded by the Codec.
+	Payload any
+
+	// Headers 是事件的字符串头部。
+	// Headers is the string headers of the event.
+	Headers map[string]string
+
+	// Timestamp 是事件发出的时间。
+	// Timestamp is the time the event was emitted.
+	Timestamp time.Time
+
+	// ScheduledAt 是事件计划被处理的时间。
+	// ScheduledAt is the time the event is scheduled to be handled.
+	ScheduledAt time.Time
+}
+
+// entry 是一个结构体，它是记录在文件中的格式。
+// entry is a structure that is the format of records in files.
+type entry struct {
+	Op          string            `json:"op"`
+	ID          string            `json:"id"`
+	Topic       string            `json:"topic,omitempty"`
+	Payload     []byte            `json:"payload,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Timestamp   time.Time         `json:"ts,omitempty"`
+	ScheduledAt time.Time         `json:"at,omitempty"`
+}
+
+// segment 是一个结构体，它表示一个分段文件。
+// segment is a structure that represents a segment file.
+type segment struct {
+	// seq 是分段文件的序号。
+	// seq is the sequence number of the segment file.
+	seq uint64
+
+	// path 是分段文件的路径。
+	// path is the path of the segment file.
+	path string
+
+	// pending 是写入这个分段文件、尚未完成的事件数量。
+	// pending is the number of events written into this segment file that have not been completed.
+	pending int
+}
+
+// Log 是一个结构体，它是一个写在本地目录中的分段预写日志。
+// 事件在提交之前写入日志，处理完成后标记为完成；重新打开日志时，尚未完成的事件可以通过 TakeRecovered 取回并重新发出。
+// 只包含已完成事件的最早的分段文件会被删除。
+// Log is a structure that is a segmented write-ahead log written in a local directory.
+// Events are written into the log before they are submitted and marked as completed after they have been handled; when the log is reopened, the events not yet completed can be taken back through TakeRecovered and emitted again.
+// The oldest segment files containing only completed events are deleted.
+type Log struct {
+	// dir 是日志所在的目录。
+	// dir is the directory of the log.
+	dir string
+
+	// config 是日志的配置。
+	// config is the configuration of the log.
+	config *Config
+
+	// lock 是 sync.Mutex 类型，用于保护下面所有字段的并发访问。
+	// lock is of type sync.Mutex, used to protect concurrent access to all fields below.
+	lock sync.Mutex
+
+	// segments 是按序号排列的分段文件，最后一个是正在写入的分段文件。
+	// segments is the segment files sorted by sequence number, the last one is the segment file being written.
+	segments []*segment
+
+	// file 是正在写入的分段文件。
+	// file is the segment file being written.
+	file *os.File
+
+	// writer 是正在写入的分段文件的缓冲写入器。
+	// writer is the buffered writer of the segment file being written.
+	writer *bufio.Writer
+
+	// size 是正在写入的分段文件的字节数。
+	// size is the number of bytes of the segment file being written.
+	size int64
+
+	// owners 是每个尚未完成的事件所在的分段文件。
+	// owners is the segment file of each event not yet completed.
+	owners map[string]*segment
+
+	// recovered 是打开日志时尚未完成、还没有被取回的事件。
+	// recovered is the events not yet completed when the log was opened, which have not been taken back yet.
+	recovered []*Record
+
+	// closed 表示日志是否已经关闭。
+	// closed indicates whether the log has been closed.
+	closed bool
+}
+
+// Open 是一个函数，它打开目录中的日志，目录不存在时创建它。
+// 它读取已有的分段文件，找出尚未完成的事件，并开始写入一个新的分段文件；最后一个分段文件末尾不完整的记录会被忽略。
+// Open is a function that opens the log in the directory, creating the directory when it does not exist.
+// It reads the existing segment files, finds the events not yet completed, and starts writing a new segment file; incomplete records at the end of the last segment file are ignored.
+func Open(dir string, conf *Config) (*Log, error) {
+	// 检查配置是否有效，并创建目录。
+	// Check whether the configuration is valid, and create the directory.
+	conf = isConfigValid(conf)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	// 创建日志。
+	// Create the log.
+	l := &Log{dir: dir, config: conf, owners: make(map[string]*segment)}
+
+	// 按序号读取已有的分段文件。
+	// Read the existing segment files by sequence number.
+	segments, err := l.listSegments()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]*Record)
+	var order []string
+	for _, seg := range segments {
+		entries, err := readSegment(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			switch e.Op {
+			case opAppend:
+				// 解码事件的数据，记录事件所在的分段文件。
+				// Decode the data of the event, and record the segment file of the event.
+				payload, err := conf.codec.Unmarshal(e.Topic, e.Payload)
+				if err != nil {
+					return nil, fmt.Errorf("%w: event %s: %v", ErrorRecordCorrupted, e.ID, err)
+				}
+				pending[e.ID] = &Record{ID: e.ID, Topic: e.Topic, Payload: payload, Headers: e.Headers, Timestamp: e.Timestamp, ScheduledAt: e.ScheduledAt}
+				order = append(order, e.ID)
+				l.owners[e.ID] = seg
+				seg.pending++
+			case opComplete:
+				// 事件已经完成，从尚未完成的事件中移除。
+				// The event has been completed, remove it from the events not yet completed.
+				if owner, ok := l.owners[e.ID]; ok {
+					owner.pending--
+					delete(l.owners, e.ID)
+					delete(pending, e.ID)
+				}
+			}
+		}
+	}
+	l.segments = segments
+
+	// 按写入顺序记录尚未完成的事件。
+	// Record the events not yet completed in writing order.
+	for _, id := range order {
+		if r, ok := pending[id]; ok {
+			l.recovered = append(l.recovered, r)
+			delete(pending, id)
+		}
+	}
+
+	// 开始写入一个新的分段文件，并删除不再需要的分段文件。
+	// Start writing a new segment file, and delete the segment files no longer needed.
+	var next uint64 = 1
+	if n := len(segments); n > 0 {
+		next = segments[n-1].seq + 1
+	}
+	if err := l.openSegment(next); err != nil {
+		return nil, err
+	}
+	l.compact()
+
+	// 返回日志。
+	// Return the log.
+	return l, nil
+}
+
+// Append 是 Log 的一个方法，它将一个事件写入日志。
+// Append is a method of Log that writes an event into the log.
+func (l *Log) Append(r *Record) error {
+	// 使用 Codec 编码事件的数据。
+	// Encode the data of the event with the Codec.
+	payload, err := l.config.codec.Marshal(r.Topic, r.Payload)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 如果日志已经关闭，返回错误 ErrorLogClosed。
+	// If the log has been closed, return the error ErrorLogClosed.
+	if l.closed {
+		return ErrorLogClosed
+	}
+
+	// 写入记录，并记录事件所在的分段文件。
+	// Write the record, and record the segment file of the event.
+	if err := l.write(&entry{Op: opAppend, ID: r.ID, Topic: r.Topic, Payload: payload, Headers: r.Headers, Timestamp: r.Timestamp, ScheduledAt: r.ScheduledAt}); err != nil {
+		return err
+	}
+	seg := l.segments[len(l.segments)-1]
+	l.owners[r.ID] = seg
+	seg.pending++
+
+	// 如果分段文件已满，开始写入新的分段文件。
+	// If the segment file is full, start writing a new segment file.
+	if l.size >= l.config.segmentSize {
+		return l.rotate()
+	}
+	return nil
+}
+
+// Complete 是 Log 的一个方法，它将一个事件标记为已完成，之后重新打开日志时不会再取回它。未知的事件会被忽略。
+// Complete is a method of Log that marks an event as completed, so that it is not taken back when the log is reopened. Unknown events are ignored.
+func (l *Log) Complete(id string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 如果日志已经关闭，返回错误 ErrorLogClosed。
+	// If the log has been closed, return the error ErrorLogClosed.
+	if l.closed {
+		return ErrorLogClosed
+	}
+
+	// 如果事件不存在或者已经完成，直接返回。
+	// If the event does not exist or has been completed, return directly.
+	owner, ok := l.owners[id]
+	if !ok {
+		return nil
+	}
+
+	// 写入完成的记录，并删除不再需要的分段文件。
+	// Write the completion record, and delete the segment files no longer needed.
+	if err := l.write(&entry{Op: opComplete, ID: id}); err != nil {
+		return err
+	}
+	delete(l.owners, id)
+	owner.pending--
+	l.compact()
+	return nil
+}
+
+// TakeRecovered 是 Log 的一个方法，它返回打开日志时尚未完成的事件，按写入顺序排列。每个事件只会被返回一次。
+// TakeRecovered is a method of Log that returns the events not yet completed when the log was opened, in writing order. Each event is returned only once.
+func (l *Log) TakeRecovered() []*Record {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	records := l.recovered
+	l.recovered = nil
+	return records
+}
+
+// Pending 是 Log 的一个方法，它返回日志中尚未完成的事件数量。
+// Pending is a method of Log that returns the number of events not yet completed in the log.
+func (l *Log) Pending() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.owners)
+}
+
+// Close 是 Log 的一个方法，它将缓冲的记录写入文件并关闭日志。
+// Close is a method of Log that flushes the buffered records into the file and closes the log.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return l.closeSegment()
+}
+
+// write 是 Log 的一个方法，它将一条记录写入正在写入的分段文件，调用方需要持有锁。
+// write is a method of Log that writes a record into the segment file being written, the caller needs to hold the lock.
+func (l *Log) write(e *entry) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// 写入长度、校验和和记录本身。
+	// Write the length, the checksum and the record itself.
+	var header [frameHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(body))
+	if _, err := l.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := l.writer.Write(body); err != nil {
+		return err
+	}
+
+	// 将记录写入文件，使它在进程崩溃后仍然存在，需要时调用 fsync。
+	// Flush the record into the file so that it survives process crashes, and call fsync when needed.
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if l.config.sync {
+		if err := l.file.Sync(); err != nil {
+			return err
+		}
+	}
+	l.size += int64(frameHeaderSize + len(body))
+	return nil
+}
+
+// rotate 是 Log 的一个方法，它关闭正在写入的分段文件并开始写入新的分段文件，调用方需要持有锁。
+// rotate is a method of Log that closes the segment file being written and starts writing a new one, the caller needs to hold the lock.
+func (l *Log) rotate() error {
+	if err := l.closeSegment(); err != nil {
+		return err
+	}
+	if err := l.openSegment(l.segments[len(l.segments)-1].seq + 1); err != nil {
+		return err
+	}
+	l.compact()
+	return nil
+}
+
+// openSegment 是 Log 的一个方法，它创建并开始写入指定序号的分段文件。
+// openSegment is a method of Log that creates and starts writing the segment file with the specified sequence number.
+func (l *Log) openSegment(seq uint64) error {
+	path := filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	l.file, l.writer, l.size = file, bufio.NewWriter(file), 0
+	l.segments = append(l.segments, &segment{seq: seq, path: path})
+	return nil
+}
+
+// closeSegment 是 Log 的一个方法，它将缓冲的记录写入并关闭正在写入的分段文件。
+// closeSegment is a method of Log that flushes the buffered records and closes the segment file being written.
+func (l *Log) closeSegment() error {
+	if err := l.writer.Flush(); err != nil {
+		_ = l.file.Close()
+		return err
+	}
+	if l.config.sync {
+		if err := l.file.Sync(); err != nil {
+			_ = l.file.Close()
+			return err
+		}
+	}
+	return l.file.Close()
+}
+
+// compact 是 Log 的一个方法，它从最早的分段文件开始，删除不再包含尚未完成事件的分段文件，正在写入的分段文件除外。
+// 只删除最早的连续分段文件，因为后面的分段文件可能包含前面分段文件中事件的完成记录。
+// compact is a method of Log that deletes, starting from the oldest, the segment files no longer containing events not yet completed, except the segment file being written.
+// Only the oldest contiguous segment files are deleted, because later segment files may contain the completion records of events in earlier ones.
+func (l *Log) compact() {
+	for len(l.segments) > 1 && l.segments[0].pending <= 0 {
+		_ = os.Remove(l.segments[0].path)
+		l.segments = l.segments[1:]
+	}
+}
+
+// listSegments 是 Log 的一个方法，它返回目录中按序号排列的分段文件。
+// listSegments is a method of Log that returns the segment files in the directory sorted by sequence number.
+func (l *Log) listSegments() ([]*segment, error) {
+	names, err := filepath.Glob(filepath.Join(l.dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	var segments []*segment
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, &segment{seq: seq, path: name})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+	return segments, nil
+}
+
+// readSegment 是一个函数，它读取一个分段文件中的所有记录，遇到不完整或者校验失败的记录时停止。
+// readSegment is a function that reads all records in a segment file, stopping at an incomplete record or a record failing the checksum.
+func readSegment(path string) ([]*entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	var entries []*entry
+	for {
+		// 读取记录的头部。
+		// Read the header of the record.
+		var header [frameHeaderSize]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			break
+		}
+
+		// 读取记录本身并检查校验和。
+		// Read the record itself and check the checksum.
+		n := int64(binary.LittleEndian.Uint32(header[0:4]))
+		if n > info.Size() {
+			break
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header[4:8]) {
+			break
+		}
+
+		// 解析记录。
+		// Parse the record.
+		e := &entry{}
+		if err := json.Unmarshal(body, e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}